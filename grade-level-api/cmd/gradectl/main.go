@@ -0,0 +1,385 @@
+// Command gradectl runs operational tasks against the grading database,
+// such as bootstrapping the first admin, using the same packages as the
+// server. Commands act for the default institution unless the global
+// -institution flag names another; export and import always cover every
+// institution.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+
+	"golang.org/x/term"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"create-institution", "add an institution to host", createInstitution},
+	{"list-institutions", "list hosted institutions", listInstitutions},
+	{"create-admin", "create a verified admin account", createAdmin},
+	{"reset-password", "set a user's password", resetPassword},
+	{"list-users", "list accounts", listUsers},
+	{"open-semester", "open enrollment for a semester", setEnrollment(true)},
+	{"close-semester", "close enrollment for a semester", setEnrollment(false)},
+	{"recompute-gpa", "recompute every student's GPA and CGPA for a semester", recomputeGPA},
+	{"audit-cohort", "check a department's students against its graduation requirements", auditCohort},
+	{"export", "write a snapshot of all data as JSON", exportSnapshot},
+	{"import", "load a snapshot written by export", importSnapshot},
+}
+
+func main() {
+	// Logs go to stderr so they never mix with exported data.
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+
+	global := flag.NewFlagSet("gradectl", flag.ContinueOnError)
+	global.Usage = usage
+	slug := global.String("institution", "", "slug of the institution to act for")
+	if err := global.Parse(os.Args[1:]); err != nil {
+		os.Exit(2)
+	}
+	args := global.Args()
+	if len(args) < 1 {
+		usage()
+		os.Exit(2)
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	config.Load()
+	if err := db.Init(); err != nil {
+		fatal(err)
+	}
+	defer db.Close()
+
+	if *slug != "" {
+		inst, err := db.FindInstitutionBySlug(ctx, *slug)
+		if err != nil {
+			fatal(fmt.Errorf("%s: %w", *slug, err))
+		}
+		ctx = tenant.With(ctx, inst.ID)
+	}
+
+	if err := cmd.run(ctx, args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gradectl [-institution slug] <command> [flags]\n\ncommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(os.Stderr, "\nrun gradectl <command> -h for the flags of a command")
+}
+
+func fatal(err error) {
+	db.Close()
+	fmt.Fprintln(os.Stderr, "gradectl:", err)
+	os.Exit(1)
+}
+
+// readPassword prompts without echo on a terminal, and otherwise reads the
+// first line of stdin so scripts can pipe the password in.
+func readPassword(prompt string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprint(os.Stderr, prompt)
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !(errors.Is(err, io.EOF) && line != "") {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func createInstitution(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create-institution", flag.ContinueOnError)
+	slug := fs.String("slug", "", "short name used in the X-Institution header and as the subdomain")
+	name := fs.String("name", "", "full name")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *slug == "" || *name == "" {
+		return errors.New("-slug and -name are required")
+	}
+
+	inst, err := db.CreateInstitution(ctx, *slug, *name)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created institution %d (%s); create its first admin with gradectl -institution %s create-admin\n",
+		inst.ID, inst.Slug, inst.Slug)
+	return nil
+}
+
+func listInstitutions(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list-institutions", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	institutions, err := db.ListInstitutions(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSLUG\tNAME")
+	for _, inst := range institutions {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", inst.ID, inst.Slug, inst.Name)
+	}
+	return w.Flush()
+}
+
+func createAdmin(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	name := fs.String("name", "", "full name")
+	email := fs.String("email", "", "email address")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" || *email == "" {
+		return errors.New("-name and -email are required")
+	}
+
+	password, err := readPassword("Password: ")
+	if err != nil {
+		return err
+	}
+	user, err := service.CreateAdmin(ctx, *name, *email, password)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("created admin %d <%s>\n", user.ID, user.Email)
+	return nil
+}
+
+func resetPassword(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	email := fs.String("email", "", "email address of the account")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	password, err := readPassword("New password: ")
+	if err != nil {
+		return err
+	}
+	if err := service.SetPassword(ctx, *email, password); err != nil {
+		return err
+	}
+	fmt.Printf("password reset for %s\n", *email)
+	return nil
+}
+
+func listUsers(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list-users", flag.ContinueOnError)
+	role := fs.String("role", "", "only list users with this role: student, lecturer or admin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var users []models.User
+	var err error
+	switch db.Role(*role) {
+	case "":
+		users, err = db.GetAllUsers(ctx)
+	case db.Student, db.Lecturer, db.Admin:
+		users, err = db.GetUsersByRole(ctx, db.Role(*role))
+	default:
+		return fmt.Errorf("unknown role %q", *role)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tEMAIL\tROLE\tVERIFIED\tLOCKED UNTIL")
+	for _, u := range users {
+		verified, locked := "no", ""
+		if u.EmailVerifiedAt != nil {
+			verified = "yes"
+		}
+		if u.LockedUntil != nil {
+			locked = u.LockedUntil.UTC().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", u.ID, u.Name, u.Email, u.Role, verified, locked)
+	}
+	return w.Flush()
+}
+
+func setEnrollment(open bool) func(context.Context, []string) error {
+	return func(ctx context.Context, args []string) error {
+		name := "close-semester"
+		if open {
+			name = "open-semester"
+		}
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		id := fs.Int("id", 0, "semester ID")
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if *id <= 0 {
+			return errors.New("-id is required")
+		}
+
+		semester, err := service.SetEnrollmentOpen(ctx, *id, open)
+		if err != nil {
+			return err
+		}
+		state := "closed"
+		if semester.EnrollmentOpen {
+			state = "open"
+		}
+		fmt.Printf("enrollment for %s (%d) is %s\n", semester.Name, semester.ID, state)
+		return nil
+	}
+}
+
+func recomputeGPA(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("recompute-gpa", flag.ContinueOnError)
+	semesterID := fs.Int("semester", 0, "semester ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *semesterID <= 0 {
+		return errors.New("-semester is required")
+	}
+
+	standings, err := service.SemesterStandings(ctx, *semesterID)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STUDENT\tNAME\tUNITS\tGPA\tCGPA\tREMARK")
+	for _, s := range standings {
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.2f\t%.2f\t%s\n", s.StudentID, s.StudentName, s.Units, s.GPA, s.CGPA, s.Remark)
+	}
+	return w.Flush()
+}
+
+func auditCohort(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("audit-cohort", flag.ContinueOnError)
+	departmentID := fs.Int("department", 0, "department ID")
+	semesterID := fs.Int("semester", 0, "only audit students enrolled in this semester")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *departmentID <= 0 {
+		return errors.New("-department is required")
+	}
+
+	cohort, err := service.AuditCohort(ctx, *departmentID, *semesterID)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STUDENT\tNAME\tUNITS\tCGPA\tELIGIBLE\tOUTSTANDING")
+	for _, a := range cohort.Audits {
+		eligible := "no"
+		if a.Eligible {
+			eligible = "yes"
+		}
+		outstanding := make([]string, len(a.Outstanding))
+		for i, o := range a.Outstanding {
+			outstanding[i] = o.Description
+		}
+		fmt.Fprintf(w, "%d\t%s\t%d\t%.2f\t%s\t%s\n", a.StudentID, a.StudentName, a.Units, a.CGPA, eligible, strings.Join(outstanding, "; "))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d of %d students eligible to graduate\n", cohort.Eligible, cohort.Students)
+	return nil
+}
+
+func exportSnapshot(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := fs.String("o", "", "file to write; stdout if empty")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return db.ExportSnapshot(ctx, os.Stdout)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := db.ExportSnapshot(ctx, f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func importSnapshot(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	replace := fs.Bool("replace", false, "delete all existing data first; otherwise the database must have no users")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: gradectl import [-replace] <file|->")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return errors.New("a snapshot file is required")
+	}
+
+	var r io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	if err := db.ImportSnapshot(ctx, r, *replace); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "snapshot imported")
+	return nil
+}
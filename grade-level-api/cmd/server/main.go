@@ -2,48 +2,108 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"log/slog"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/falasefemi2/gradesystem/internal/config"
 	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/events"
 	"github.com/falasefemi2/gradesystem/internal/handler"
-	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/mail"
+	"github.com/falasefemi2/gradesystem/internal/metrics"
+	"github.com/falasefemi2/gradesystem/internal/ratelimit"
+	"github.com/falasefemi2/gradesystem/internal/server"
+	"github.com/falasefemi2/gradesystem/internal/service"
 )
 
 func main() {
-	db.Init()
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	slog.SetDefault(logger)
 
-	http.HandleFunc("/signup", handler.SignUp)
-	http.HandleFunc("/login", handler.Login)
+	if err := run(logger); err != nil {
+		logger.Error("server stopped", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
 
-	http.HandleFunc(
-		"/admin/users",
-		middleware.RoleAuth(handler.GetAllUsers, db.Admin),
-	)
+func run(logger *slog.Logger) error {
+	cfg := config.Load()
+	if err := ensureTokenSecret(cfg, logger); err != nil {
+		return err
+	}
 
-	http.HandleFunc(
-		"/semesters",
-		middleware.RoleAuth(handler.SemestersHandler, db.Admin, db.Lecturer, db.Student),
-	)
+	if err := db.Init(); err != nil {
+		return err
+	}
+	defer db.Close()
+	metrics.RegisterDBStats(db.DB.Stats)
 
-	http.HandleFunc(
-		"/semesters/",
-		middleware.RoleAuth(handler.SemesterByIDHandler, db.Admin),
-	)
+	service.SetMailer(mail.LogSender{Logger: logger})
 
-	http.HandleFunc(
-		"/courses",
-		middleware.RoleAuth(handler.CreateCourse, db.Lecturer),
+	limits := ratelimit.NewMemoryStore(time.Hour)
+	handler.SetLoginLimiters(
+		ratelimit.New(limits, "login-ip", cfg.LoginIPPerMinute, cfg.LoginIPBurst),
+		ratelimit.New(limits, "login-account", cfg.LoginAccountPerMinute, cfg.LoginAccountBurst),
 	)
 
-	http.HandleFunc(
-		"/courses",
-		middleware.RoleAuth(handler.CoursesHandler, db.Admin, db.Lecturer, db.Student),
-	)
+	srv := &http.Server{
+		Addr:         cfg.Addr,
+		Handler:      server.NewHandler(cfg, logger),
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}
+	// Shutdown waits for open requests, so end the event streams first.
+	srv.RegisterOnShutdown(events.Default().Close)
 
-	http.HandleFunc(
-		"/courses/",
-		middleware.RoleAuth(handler.CourseByIDHandler, db.Admin, db.Lecturer),
-	)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errCh := make(chan error, 1)
+	go func() {
+		logger.Info("server listening", slog.String("addr", srv.Addr))
+		errCh <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	logger.Info("shutting down, draining in-flight requests")
+	handler.MarkShuttingDown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	logger.Info("server stopped")
+	return nil
+}
 
-	http.ListenAndServe(":8080", nil)
+// ensureTokenSecret refuses to run without TOKEN_SECRET, except on SQLite
+// where a development server gets a secret of its own. Tokens it emails
+// stop verifying once it restarts.
+func ensureTokenSecret(cfg *config.Config, logger *slog.Logger) error {
+	if cfg.TokenSecret != "" {
+		return nil
+	}
+	if cfg.DBDriver != db.SQLite {
+		return errors.New("TOKEN_SECRET must be set")
+	}
+	cfg.TokenSecret = rand.Text()
+	logger.Warn("TOKEN_SECRET is not set, using a random secret for this process")
+	return nil
 }
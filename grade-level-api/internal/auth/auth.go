@@ -2,11 +2,37 @@
 package auth
 
 import (
+	"bufio"
+	_ "embed"
 	"errors"
+	"strings"
+	"unicode/utf8"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	MinPasswordLength = 10
+	// MaxPasswordLength is bcrypt's limit; longer input is silently cut off.
+	MaxPasswordLength = 72
+)
+
+var ErrBreachedPassword = errors.New("password is too common, it appears in known data breaches")
+
+//go:embed breached_passwords.txt
+var breachedList string
+
+var breached = func() map[string]bool {
+	set := map[string]bool{}
+	sc := bufio.NewScanner(strings.NewReader(breachedList))
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			set[strings.ToLower(line)] = true
+		}
+	}
+	return set
+}()
+
 func HashPassword(password string) (string, error) {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
@@ -19,9 +45,18 @@ func VerifyPassword(password, hash string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 }
 
+// ValidatePassword enforces the password policy: a length between
+// MinPasswordLength characters and MaxPasswordLength bytes, and not one of
+// the commonly breached passwords.
 func ValidatePassword(password string) error {
-	if len(password) < 6 {
-		return errors.New("password must be at least 6 characters")
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return errors.New("password must be at least 10 characters")
+	}
+	if len(password) > MaxPasswordLength {
+		return errors.New("password must be at most 72 bytes")
+	}
+	if breached[strings.ToLower(password)] {
+		return ErrBreachedPassword
 	}
 	return nil
 }
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePassword(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"too short", "abc123", true},
+		{"long enough", "correct horse battery", false},
+		{"breached", "Password123", true},
+		{"breached regardless of case", "QWERTYUIOP", true},
+		{"too long for bcrypt", strings.Repeat("a", 73), true},
+		{"multibyte counted as characters", "ñandú-ñandú", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePassword(tt.password)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTokenSignature(t *testing.T) {
+	secret := []byte("test-secret")
+	token, err := NewToken(secret, "password_reset")
+	assert.NoError(t, err)
+
+	assert.True(t, VerifyTokenSignature(secret, "password_reset", token))
+	assert.False(t, VerifyTokenSignature(secret, "verify_email", token), "wrong purpose")
+	assert.False(t, VerifyTokenSignature([]byte("other"), "password_reset", token), "wrong secret")
+	assert.False(t, VerifyTokenSignature(secret, "password_reset", token+"x"), "tampered")
+	assert.False(t, VerifyTokenSignature(secret, "password_reset", "garbage"))
+
+	assert.Len(t, HashToken(token), 64)
+	assert.NotEqual(t, HashToken(token), token)
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// NewToken returns a random token signed for purpose, for links sent by
+// email. Only HashToken of it should be stored.
+func NewToken(secret []byte, purpose string) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(b)
+	return payload + "." + sign(secret, purpose, payload), nil
+}
+
+// VerifyTokenSignature reports whether token was issued by NewToken with
+// the same secret and purpose. It does not check expiry or reuse.
+func VerifyTokenSignature(secret []byte, purpose, token string) bool {
+	payload, sig, ok := strings.Cut(token, ".")
+	if !ok || payload == "" {
+		return false
+	}
+	return hmac.Equal([]byte(sig), []byte(sign(secret, purpose, payload)))
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func sign(secret []byte, purpose, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(purpose + ":" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
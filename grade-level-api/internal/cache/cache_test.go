@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2025, 9, 1, 9, 0, 0, 0, time.UTC)
+	c := NewLRU(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	_, _ = c.Get("a")
+	c.Set("c", 3)
+
+	_, ok := c.Get("b")
+	assert.False(t, ok, "least recently used entry is evicted")
+	v, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.Len())
+
+	now = now.Add(time.Minute)
+	_, ok = c.Get("a")
+	assert.False(t, ok, "entries expire after the TTL")
+
+	c = NewLRU(10, time.Minute)
+	c.Set("course:1", 1)
+	c.Set("course:level:100", 2)
+	c.Set("semester:1", 3)
+	c.DeletePrefix("course:")
+	_, ok = c.Get("course:level:100")
+	assert.False(t, ok)
+	_, ok = c.Get("semester:1")
+	assert.True(t, ok)
+
+	c.Delete("semester:1")
+	assert.Zero(t, c.Len())
+}
@@ -1 +1,189 @@
+// Package config
 package config
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+type Config struct {
+	// MinAttendancePercent is the attendance a student needs in a course
+	// offering before they are eligible to sit its exam.
+	MinAttendancePercent float64
+	AttendanceCodeTTL    time.Duration
+	// AppealWindow is how long after a semester ends students may still
+	// appeal its grades.
+	AppealWindow time.Duration
+	// RequestTimeout bounds how long a single request may run. Event
+	// streams are exempt and instead send a comment every EventsHeartbeat
+	// to keep idle connections open.
+	RequestTimeout  time.Duration
+	EventsHeartbeat time.Duration
+	// DBQueryTimeout bounds each database call or transaction; zero
+	// leaves only the request deadline.
+	DBQueryTimeout time.Duration
+	// DBDriver is "mysql" or "sqlite". DBDSN is passed to the driver; when
+	// empty, a local default for the driver is used.
+	DBDriver string
+	DBDSN    string
+	// Users, courses and semesters are cached for up to CacheTTL, at most
+	// CacheSize entries; a size of zero disables the cache.
+	CacheSize int
+	CacheTTL  time.Duration
+	// TenantDomain is the domain institutions are subdomains of, e.g.
+	// "grades.example.com". When empty, only the X-Institution header
+	// selects an institution.
+	TenantDomain string
+
+	Addr            string
+	ReadTimeout     time.Duration
+	WriteTimeout    time.Duration
+	IdleTimeout     time.Duration
+	ShutdownTimeout time.Duration
+
+	// Login rate limits, in attempts per minute with a burst allowance,
+	// applied per client IP and per account email.
+	LoginIPPerMinute      int
+	LoginIPBurst          int
+	LoginAccountPerMinute int
+	LoginAccountBurst     int
+
+	// An account is locked for LockoutBase after LockoutThreshold
+	// consecutive failed logins, doubling with every further failure.
+	LockoutThreshold int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+
+	// TokenSecret signs email verification and password reset tokens.
+	// It has no default: the server refuses to start without one unless
+	// it runs on SQLite for development.
+	TokenSecret          string
+	EmailVerificationTTL time.Duration
+	PasswordResetTTL     time.Duration
+	// PasswordHistory is how many recent passwords may not be reused.
+	PasswordHistory int
+
+	// Students whose CGPA falls below ProbationCGPA are placed on
+	// probation, and below WithdrawalCGPA are advised to withdraw.
+	ProbationCGPA  float64
+	WithdrawalCGPA float64
+
+	// Course evaluations open EvaluationLeadTime before a semester ends
+	// and close EvaluationWindow after it. Results are only reported once
+	// an offering has EvaluationMinResponses, so no answer stands alone.
+	EvaluationLeadTime     time.Duration
+	EvaluationWindow       time.Duration
+	EvaluationMinResponses int
+}
+
+var current = defaults()
+
+func defaults() *Config {
+	return &Config{
+		MinAttendancePercent:   75,
+		AttendanceCodeTTL:      10 * time.Minute,
+		AppealWindow:           14 * 24 * time.Hour,
+		RequestTimeout:         30 * time.Second,
+		EventsHeartbeat:        15 * time.Second,
+		DBQueryTimeout:         5 * time.Second,
+		DBDriver:               "mysql",
+		CacheSize:              10000,
+		CacheTTL:               time.Minute,
+		Addr:                   ":8080",
+		ReadTimeout:            10 * time.Second,
+		WriteTimeout:           60 * time.Second,
+		IdleTimeout:            120 * time.Second,
+		ShutdownTimeout:        30 * time.Second,
+		LoginIPPerMinute:       20,
+		LoginIPBurst:           10,
+		LoginAccountPerMinute:  5,
+		LoginAccountBurst:      5,
+		LockoutThreshold:       5,
+		LockoutBase:            time.Minute,
+		LockoutMax:             24 * time.Hour,
+		EmailVerificationTTL:   48 * time.Hour,
+		PasswordResetTTL:       time.Hour,
+		PasswordHistory:        5,
+		ProbationCGPA:          1.5,
+		WithdrawalCGPA:         1.0,
+		EvaluationLeadTime:     14 * 24 * time.Hour,
+		EvaluationWindow:       14 * 24 * time.Hour,
+		EvaluationMinResponses: 3,
+	}
+}
+
+// Load reads the configuration from the environment, falling back to
+// defaults for anything unset, and makes it available through Get.
+func Load() *Config {
+	cfg := defaults()
+	cfg.MinAttendancePercent = getFloat("MIN_ATTENDANCE_PERCENT", cfg.MinAttendancePercent)
+	cfg.AttendanceCodeTTL = getDuration("ATTENDANCE_CODE_TTL", cfg.AttendanceCodeTTL)
+	cfg.AppealWindow = getDuration("APPEAL_WINDOW", cfg.AppealWindow)
+	cfg.RequestTimeout = getDuration("REQUEST_TIMEOUT", cfg.RequestTimeout)
+	cfg.EventsHeartbeat = getDuration("EVENTS_HEARTBEAT", cfg.EventsHeartbeat)
+	cfg.DBQueryTimeout = getDuration("DB_QUERY_TIMEOUT", cfg.DBQueryTimeout)
+	cfg.DBDriver = getString("DB_DRIVER", cfg.DBDriver)
+	cfg.DBDSN = getString("DB_DSN", cfg.DBDSN)
+	cfg.CacheSize = getInt("CACHE_SIZE", cfg.CacheSize)
+	cfg.CacheTTL = getDuration("CACHE_TTL", cfg.CacheTTL)
+	cfg.TenantDomain = getString("TENANT_DOMAIN", cfg.TenantDomain)
+	cfg.Addr = getString("ADDR", cfg.Addr)
+	cfg.ReadTimeout = getDuration("READ_TIMEOUT", cfg.ReadTimeout)
+	cfg.WriteTimeout = getDuration("WRITE_TIMEOUT", cfg.WriteTimeout)
+	cfg.IdleTimeout = getDuration("IDLE_TIMEOUT", cfg.IdleTimeout)
+	cfg.ShutdownTimeout = getDuration("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout)
+	cfg.LoginIPPerMinute = getInt("LOGIN_IP_PER_MINUTE", cfg.LoginIPPerMinute)
+	cfg.LoginIPBurst = getInt("LOGIN_IP_BURST", cfg.LoginIPBurst)
+	cfg.LoginAccountPerMinute = getInt("LOGIN_ACCOUNT_PER_MINUTE", cfg.LoginAccountPerMinute)
+	cfg.LoginAccountBurst = getInt("LOGIN_ACCOUNT_BURST", cfg.LoginAccountBurst)
+	cfg.LockoutThreshold = getInt("LOCKOUT_THRESHOLD", cfg.LockoutThreshold)
+	cfg.LockoutBase = getDuration("LOCKOUT_BASE", cfg.LockoutBase)
+	cfg.LockoutMax = getDuration("LOCKOUT_MAX", cfg.LockoutMax)
+	cfg.TokenSecret = getString("TOKEN_SECRET", cfg.TokenSecret)
+	cfg.EmailVerificationTTL = getDuration("EMAIL_VERIFICATION_TTL", cfg.EmailVerificationTTL)
+	cfg.PasswordResetTTL = getDuration("PASSWORD_RESET_TTL", cfg.PasswordResetTTL)
+	cfg.PasswordHistory = getInt("PASSWORD_HISTORY", cfg.PasswordHistory)
+	cfg.ProbationCGPA = getFloat("PROBATION_CGPA", cfg.ProbationCGPA)
+	cfg.WithdrawalCGPA = getFloat("WITHDRAWAL_CGPA", cfg.WithdrawalCGPA)
+	cfg.EvaluationLeadTime = getDuration("EVALUATION_LEAD_TIME", cfg.EvaluationLeadTime)
+	cfg.EvaluationWindow = getDuration("EVALUATION_WINDOW", cfg.EvaluationWindow)
+	cfg.EvaluationMinResponses = getInt("EVALUATION_MIN_RESPONSES", cfg.EvaluationMinResponses)
+	current = cfg
+	return cfg
+}
+
+func Get() *Config {
+	return current
+}
+
+func getString(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+func getInt(key string, fallback int) int {
+	v, err := strconv.Atoi(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func getFloat(key string, fallback float64) float64 {
+	v, err := strconv.ParseFloat(os.Getenv(key), 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	v, err := time.ParseDuration(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,253 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+type AppealStatus string
+
+const (
+	AppealPending     AppealStatus = "pending"
+	AppealUnderReview AppealStatus = "under_review"
+	AppealUpheld      AppealStatus = "upheld"
+	AppealRejected    AppealStatus = "rejected"
+)
+
+var (
+	ErrAppealNotFound    = errors.New("appeal not found")
+	ErrAppealInProgress  = errors.New("an appeal for this grade is already in progress")
+	ErrAppealNotPending  = errors.New("appeal is not awaiting lecturer review")
+	ErrAppealNotReviewed = errors.New("appeal is not awaiting an admin decision")
+)
+
+type AppealFilter struct {
+	StudentID  int
+	LecturerID int
+	Status     AppealStatus
+}
+
+// CreateAppeal files an appeal, checking in the same transaction that no
+// other appeal for the grade is still open.
+func CreateAppeal(ctx context.Context, gradeID, studentID int, reason string) (*models.Appeal, error) {
+	appeal := &models.Appeal{
+		GradeID:   gradeID,
+		StudentID: studentID,
+		Reason:    reason,
+		Status:    string(AppealPending),
+		CreatedAt: time.Now(),
+	}
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var open int
+		err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM appeal WHERE grade_id = ? AND institution_id = ? AND status IN (?, ?)`,
+			gradeID, institution(ctx), string(AppealPending), string(AppealUnderReview),
+		).Scan(&open)
+		if err != nil {
+			return err
+		}
+		if open > 0 {
+			return ErrAppealInProgress
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO appeal (grade_id, student_id, reason, status, created_at, institution_id) VALUES (?, ?, ?, ?, ?, ?)`,
+			gradeID, studentID, reason, string(AppealPending), appeal.CreatedAt, institution(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		appeal.ID = int(id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appeal, nil
+}
+
+const appealColumns = `a.id, a.grade_id, a.student_id, a.reason, a.status,
+	a.lecturer_id, a.lecturer_recommendation, a.lecturer_note, a.proposed_score, a.reviewed_at,
+	a.admin_id, a.admin_note, a.decided_at, a.created_at`
+
+func FindAppealByID(ctx context.Context, id int) (*models.Appeal, error) {
+	appeals, err := queryAppeals(ctx,
+		`SELECT `+appealColumns+` FROM appeal a WHERE a.id = ? AND a.institution_id = ?`, id, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(appeals) == 0 {
+		return nil, ErrAppealNotFound
+	}
+	return &appeals[0], nil
+}
+
+func ListAppeals(ctx context.Context, filter AppealFilter) ([]models.Appeal, error) {
+	query := `SELECT ` + appealColumns + ` FROM appeal a
+		JOIN grade g ON g.id = a.grade_id
+		JOIN enrollment e ON e.id = g.enrollment_id
+		JOIN course c ON c.id = e.course_id`
+
+	where := []string{"a.institution_id = ?"}
+	args := []any{institution(ctx)}
+	if filter.StudentID > 0 {
+		where = append(where, "a.student_id = ?")
+		args = append(args, filter.StudentID)
+	}
+	if filter.LecturerID > 0 {
+		where = append(where, "c.lecturer_id = ?")
+		args = append(args, filter.LecturerID)
+	}
+	if filter.Status != "" {
+		where = append(where, "a.status = ?")
+		args = append(args, string(filter.Status))
+	}
+	query += " WHERE " + strings.Join(where, " AND ")
+	query += " ORDER BY a.created_at"
+
+	return queryAppeals(ctx, query, args...)
+}
+
+func queryAppeals(ctx context.Context, query string, args ...any) ([]models.Appeal, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	appeals := []models.Appeal{}
+
+	for rows.Next() {
+		var (
+			a                                       models.Appeal
+			lecturerID, adminID                     sql.NullInt64
+			recommendation, lecturerNote, adminNote sql.NullString
+			proposedScore                           sql.NullFloat64
+			reviewedAt, decidedAt                   sql.NullTime
+		)
+		if err := rows.Scan(
+			&a.ID, &a.GradeID, &a.StudentID, &a.Reason, &a.Status,
+			&lecturerID, &recommendation, &lecturerNote, &proposedScore, &reviewedAt,
+			&adminID, &adminNote, &decidedAt, &a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		if lecturerID.Valid {
+			id := int(lecturerID.Int64)
+			a.LecturerID = &id
+		}
+		if adminID.Valid {
+			id := int(adminID.Int64)
+			a.AdminID = &id
+		}
+		if proposedScore.Valid {
+			a.ProposedScore = &proposedScore.Float64
+		}
+		if reviewedAt.Valid {
+			a.ReviewedAt = &reviewedAt.Time
+		}
+		if decidedAt.Valid {
+			a.DecidedAt = &decidedAt.Time
+		}
+		a.LecturerRecommendation = recommendation.String
+		a.LecturerNote = lecturerNote.String
+		a.AdminNote = adminNote.String
+		appeals = append(appeals, a)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return appeals, nil
+}
+
+// RecordLecturerReview stores the lecturer's recommendation on a pending
+// appeal and forwards it to an admin for a decision.
+func RecordLecturerReview(ctx context.Context, id, lecturerID int, recommendation AppealStatus, proposedScore *float64, note string) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	result, err := DB.ExecContext(ctx,
+		`UPDATE appeal
+		 SET status = ?, lecturer_id = ?, lecturer_recommendation = ?, proposed_score = ?, lecturer_note = ?, reviewed_at = ?
+		 WHERE id = ? AND institution_id = ? AND status = ?`,
+		string(AppealUnderReview), lecturerID, string(recommendation), proposedScore, note, time.Now(),
+		id, institution(ctx), string(AppealPending),
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrAppealNotPending
+	}
+	return nil
+}
+
+// DecideAppeal closes an appeal that a lecturer has reviewed. When the
+// appeal is upheld the grade is changed to newScore and the change is
+// recorded in grade_change in the same transaction.
+func DecideAppeal(ctx context.Context, id, adminID int, decision AppealStatus, newScore float64, note string) error {
+	return withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		now := time.Now()
+		result, err := tx.ExecContext(ctx,
+			`UPDATE appeal SET status = ?, admin_id = ?, admin_note = ?, decided_at = ?
+			 WHERE id = ? AND institution_id = ? AND status = ?`,
+			string(decision), adminID, note, now, id, institution(ctx), string(AppealUnderReview),
+		)
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			return ErrAppealNotReviewed
+		}
+
+		if decision == AppealUpheld {
+			var gradeID int
+			var oldScore float64
+			err := tx.QueryRowContext(ctx,
+				`SELECT g.id, g.score FROM grade g JOIN appeal a ON a.grade_id = g.id WHERE a.id = ? AND a.institution_id = ?`,
+				id, institution(ctx),
+			).Scan(&gradeID, &oldScore)
+			if err != nil {
+				return err
+			}
+
+			if _, err := tx.ExecContext(ctx,
+				`UPDATE grade SET score = ? WHERE id = ? AND institution_id = ?`, newScore, gradeID, institution(ctx),
+			); err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO grade_change (grade_id, old_score, new_score, reason, appeal_id, changed_by, changed_at, institution_id)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+				gradeID, oldScore, newScore, "appeal upheld", id, adminID, now, institution(ctx),
+			); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
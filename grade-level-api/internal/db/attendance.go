@@ -0,0 +1,207 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+type AttendanceMethod string
+
+const (
+	AttendanceManual AttendanceMethod = "manual"
+	AttendanceCode   AttendanceMethod = "code"
+)
+
+func CreateClassSession(ctx context.Context, courseID, semesterID int, topic string, heldAt time.Time, code string, codeExpiresAt *time.Time) (*models.ClassSession, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if courseID <= 0 || semesterID <= 0 {
+		return nil, errors.New("invalid course or semester ID")
+	}
+
+	var nullCode sql.NullString
+	if code != "" {
+		nullCode = sql.NullString{String: code, Valid: true}
+	}
+
+	result, err := DB.ExecContext(ctx,
+		`INSERT INTO class_session (course_id, semester_id, topic, held_at, code, code_expires_at, institution_id)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		courseID, semesterID, topic, heldAt, nullCode, codeExpiresAt, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ClassSession{
+		ID:            int(id),
+		CourseID:      courseID,
+		SemesterID:    semesterID,
+		Topic:         topic,
+		HeldAt:        heldAt,
+		Code:          code,
+		CodeExpiresAt: codeExpiresAt,
+	}, nil
+}
+
+func FindClassSessionByID(ctx context.Context, id int) (*models.ClassSession, error) {
+	return findClassSession(ctx, `WHERE id = ?`, id)
+}
+
+func FindClassSessionByCode(ctx context.Context, code string) (*models.ClassSession, error) {
+	return findClassSession(ctx, `WHERE code = ?`, code)
+}
+
+func findClassSession(ctx context.Context, where string, arg any) (*models.ClassSession, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var (
+		s         models.ClassSession
+		code      sql.NullString
+		expiresAt sql.NullTime
+	)
+	err := DB.QueryRowContext(ctx,
+		`SELECT id, course_id, semester_id, topic, held_at, code, code_expires_at
+		 FROM class_session `+where+` AND institution_id = ?`,
+		arg, institution(ctx),
+	).Scan(&s.ID, &s.CourseID, &s.SemesterID, &s.Topic, &s.HeldAt, &code, &expiresAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("class session not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.Code = code.String
+	if expiresAt.Valid {
+		s.CodeExpiresAt = &expiresAt.Time
+	}
+	return &s, nil
+}
+
+var ErrAttendanceRecorded = errors.New("attendance already recorded")
+
+func RecordAttendance(ctx context.Context, sessionID, studentID int, method AttendanceMethod) (*models.Attendance, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return recordAttendance(ctx, DB, sessionID, studentID, method)
+}
+
+// RecordAttendanceBatch marks several students present in one transaction.
+// Students already recorded for the session are skipped, so only the new
+// records are returned.
+func RecordAttendanceBatch(ctx context.Context, sessionID int, studentIDs []int, method AttendanceMethod) ([]*models.Attendance, error) {
+	records := []*models.Attendance{}
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, studentID := range studentIDs {
+			record, err := recordAttendance(ctx, tx, sessionID, studentID, method)
+			if errors.Is(err, ErrAttendanceRecorded) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			records = append(records, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func recordAttendance(ctx context.Context, q querier, sessionID, studentID int, method AttendanceMethod) (*models.Attendance, error) {
+	var exists int
+	err := q.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM attendance WHERE session_id = ? AND student_id = ? AND institution_id = ?`,
+		sessionID, studentID, institution(ctx),
+	).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, ErrAttendanceRecorded
+	}
+
+	now := time.Now()
+	result, err := q.ExecContext(ctx,
+		`INSERT INTO attendance (session_id, student_id, method, recorded_at, institution_id) VALUES (?, ?, ?, ?, ?)`,
+		sessionID, studentID, string(method), now, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Attendance{
+		ID:         int(id),
+		SessionID:  sessionID,
+		StudentID:  studentID,
+		Method:     string(method),
+		RecordedAt: now,
+	}, nil
+}
+
+func CountClassSessions(ctx context.Context, courseID, semesterID int) (int, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var total int
+	err := DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM class_session WHERE course_id = ? AND semester_id = ? AND institution_id = ?`,
+		courseID, semesterID, institution(ctx),
+	).Scan(&total)
+	return total, err
+}
+
+// CountAttendanceByStudent returns how many sessions of a course offering
+// each student attended, keyed by student ID.
+func CountAttendanceByStudent(ctx context.Context, courseID, semesterID int) (map[int]int, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT a.student_id, COUNT(*)
+		 FROM attendance a
+		 JOIN class_session s ON s.id = a.session_id
+		 WHERE s.course_id = ? AND s.semester_id = ? AND s.institution_id = ?
+		 GROUP BY a.student_id`,
+		courseID, semesterID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[int]int{}
+
+	for rows.Next() {
+		var studentID, attended int
+		if err := rows.Scan(&studentID, &attended); err != nil {
+			return nil, err
+		}
+		counts[studentID] = attended
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
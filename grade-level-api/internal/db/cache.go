@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/falasefemi2/gradesystem/internal/cache"
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+// readCache holds users, courses and semesters, which are read on nearly
+// every request and rarely written. Every write to them in this package
+// invalidates the affected keys once it has been committed, and the TTL
+// bounds how stale an entry can get when a write races a read or happens
+// on another server.
+//
+// Keys start with the institution ID, so one institution never reads
+// another's entries: "<institution>:user:<id>", "<institution>:user-email:<email>"
+// (to a user ID; emails never change), and "<institution>:course:..." and
+// "<institution>:semester:..." for every course and semester read, so one
+// prefix delete clears them all. Institutions themselves are cached under
+// "institution:<slug>".
+var readCache cache.Cache = cache.NewLRU(config.Get().CacheSize, config.Get().CacheTTL)
+
+// SetCache replaces the read cache. It must be called before the database
+// is used concurrently.
+func SetCache(c cache.Cache) {
+	readCache = c
+}
+
+// cached returns the value under the institution's key, loading and
+// storing it on a miss. Values are cloned going in and out so callers may
+// modify what they get.
+func cached[T any](ctx context.Context, key string, clone func(T) T, load func() (T, error)) (T, error) {
+	key = tenantKey(ctx, key)
+	if v, ok := readCache.Get(key); ok {
+		return clone(v.(T)), nil
+	}
+	v, err := load()
+	if err != nil {
+		return v, err
+	}
+	readCache.Set(key, clone(v))
+	return v, nil
+}
+
+func cloneUser(u *models.User) *models.User {
+	c := *u
+	return &c
+}
+
+func cloneCourse(c *models.Course) *models.Course {
+	cc := *c
+	return &cc
+}
+
+func cloneCourses(courses []*models.Course) []*models.Course {
+	if courses == nil {
+		return nil
+	}
+	out := make([]*models.Course, len(courses))
+	for i, c := range courses {
+		out[i] = cloneCourse(c)
+	}
+	return out
+}
+
+func cloneSemester(s *models.Semester) *models.Semester {
+	c := *s
+	return &c
+}
+
+func tenantKey(ctx context.Context, key string) string {
+	return fmt.Sprintf("%d:%s", institution(ctx), key)
+}
+
+func userKey(id int) string {
+	return fmt.Sprintf("user:%d", id)
+}
+
+func userEmailKey(email string) string {
+	return "user-email:" + strings.ToLower(email)
+}
+
+func forgetUser(ctx context.Context, id int) {
+	readCache.Delete(tenantKey(ctx, userKey(id)))
+}
+
+func forgetCourses(ctx context.Context) {
+	readCache.DeletePrefix(tenantKey(ctx, "course:"))
+}
+
+func forgetSemesters(ctx context.Context) {
+	readCache.DeletePrefix(tenantKey(ctx, "semester:"))
+}
+
+// cloneSemesters is slices.Clone with a type cached can infer.
+var cloneSemesters = slices.Clone[[]models.Semester]
@@ -2,13 +2,39 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 
 	"github.com/falasefemi2/gradesystem/internal/models"
 )
 
-func CreateCourse(name string, level, lecturerID int) (*models.Course, error) {
+const courseColumns = `id, name, level, units, lecturer_id, department_id`
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanCourse(row scanner) (*models.Course, error) {
+	var (
+		course       models.Course
+		departmentID sql.NullInt64
+	)
+	if err := row.Scan(&course.ID, &course.Name, &course.Level, &course.Units, &course.LecturerID, &departmentID); err != nil {
+		return nil, err
+	}
+	if departmentID.Valid {
+		id := int(departmentID.Int64)
+		course.DepartmentID = &id
+	}
+	return &course, nil
+}
+
+func CreateCourse(ctx context.Context, name string, level, units, lecturerID int) (*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if name == "" {
 		return nil, errors.New("course name  cannot be empty")
 	}
@@ -16,15 +42,20 @@ func CreateCourse(name string, level, lecturerID int) (*models.Course, error) {
 	if level <= 0 {
 		return nil, errors.New("course level must be a positive integer")
 	}
+	if units <= 0 {
+		return nil, errors.New("course units must be a positive integer")
+	}
 
-	result, err := DB.Exec(
-		`INSERT INTO course (name, level, lecturer_id) VALUES (?, ?, ?)`,
-		name, level, lecturerID,
+	result, err := DB.ExecContext(ctx,
+		`INSERT INTO course (name, level, units, lecturer_id, institution_id) VALUES (?, ?, ?, ?, ?)`,
+		name, level, units, lecturerID, institution(ctx),
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	forgetCourses(ctx)
+
 	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
@@ -34,23 +65,32 @@ func CreateCourse(name string, level, lecturerID int) (*models.Course, error) {
 		ID:         int(id),
 		Name:       name,
 		Level:      level,
+		Units:      units,
 		LecturerID: lecturerID,
 	}, nil
 }
 
-func UpdateCourse(id int, name string, level, lecturerID int) (*models.Course, error) {
+func UpdateCourse(ctx context.Context, id int, name string, level, units, lecturerID int) (*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if name == "" {
 		return nil, errors.New("course name cannot be empty")
 	}
 	if level <= 0 {
 		return nil, errors.New("course level must be a positive integer")
 	}
-	result, err := DB.Exec(
-		`UPDATE course SET name = ?, level = ?, lecturer_id = ? WHERE id = ?`, name, level, lecturerID, id,
+	if units <= 0 {
+		return nil, errors.New("course units must be a positive integer")
+	}
+	result, err := DB.ExecContext(ctx,
+		`UPDATE course SET name = ?, level = ?, units = ?, lecturer_id = ? WHERE id = ? AND institution_id = ?`,
+		name, level, units, lecturerID, id, institution(ctx),
 	)
 	if err != nil {
 		return nil, err
 	}
+	forgetCourses(ctx)
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return nil, err
@@ -58,16 +98,20 @@ func UpdateCourse(id int, name string, level, lecturerID int) (*models.Course, e
 	if rowsAffected == 0 {
 		return nil, errors.New("no course found with the given ID")
 	}
-	return &models.Course{
-		ID:         id,
-		Name:       name,
-		Level:      level,
-		LecturerID: lecturerID,
-	}, nil
+	return FindCourseByID(ctx, id)
 }
 
-func ListCourses() ([]*models.Course, error) {
-	rows, err := DB.Query(`SELECT id, name, level, lecturer_id FROM course`)
+func ListCourses(ctx context.Context) ([]*models.Course, error) {
+	return cached(ctx, "course:all", cloneCourses, func() ([]*models.Course, error) {
+		return listCourses(ctx)
+	})
+}
+
+func listCourses(ctx context.Context) ([]*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `SELECT `+courseColumns+` FROM course WHERE institution_id = ?`, institution(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -75,11 +119,11 @@ func ListCourses() ([]*models.Course, error) {
 	var courses []*models.Course
 
 	for rows.Next() {
-		var course models.Course
-		if err := rows.Scan(&course.ID, &course.Name, &course.Level, &course.LecturerID); err != nil {
+		course, err := scanCourse(rows)
+		if err != nil {
 			return nil, err
 		}
-		courses = append(courses, &course)
+		courses = append(courses, course)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
@@ -87,28 +131,94 @@ func ListCourses() ([]*models.Course, error) {
 	return courses, nil
 }
 
-func FindCourseByID(id int) (*models.Course, error) {
-	var course models.Course
-	err := DB.QueryRow(
-		`SELECT id, name, level, lecturer_id FROM course WHERE id = ?`, id,
-	).Scan(&course.ID, &course.Name, &course.Level, &course.LecturerID)
+func FindCourseByID(ctx context.Context, id int) (*models.Course, error) {
+	return cached(ctx, fmt.Sprintf("course:%d", id), cloneCourse, func() (*models.Course, error) {
+		return findCourseByID(ctx, id)
+	})
+}
+
+func findCourseByID(ctx context.Context, id int) (*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	course, err := scanCourse(DB.QueryRowContext(ctx, `SELECT `+courseColumns+` FROM course WHERE id = ? AND institution_id = ?`, id, institution(ctx)))
 	if err == sql.ErrNoRows {
 		return nil, errors.New("no course found with the given ID")
 	}
+	return course, err
+}
+
+// FindCoursesByIDs looks up several courses at once, keyed by ID.
+func FindCoursesByIDs(ctx context.Context, ids []int) (map[int]*models.Course, error) {
+	courses := map[int]*models.Course{}
+	if len(ids) == 0 {
+		return courses, nil
+	}
+	marks, args := inList(ids)
+	list, err := queryCourses(ctx, `SELECT `+courseColumns+` FROM course WHERE institution_id = ? AND id IN (`+marks+`)`,
+		append([]any{institution(ctx)}, args...)...)
 	if err != nil {
 		return nil, err
 	}
-	return &course, nil
+	for _, c := range list {
+		courses[c.ID] = c
+	}
+	return courses, nil
 }
 
-func DeleteCourse(id int) error {
+// FindCoursesByLecturerIDs returns the courses taught by several lecturers
+// at once, keyed by lecturer ID.
+func FindCoursesByLecturerIDs(ctx context.Context, lecturerIDs []int) (map[int][]*models.Course, error) {
+	courses := map[int][]*models.Course{}
+	if len(lecturerIDs) == 0 {
+		return courses, nil
+	}
+	marks, args := inList(lecturerIDs)
+	list, err := queryCourses(ctx,
+		`SELECT `+courseColumns+` FROM course WHERE institution_id = ? AND lecturer_id IN (`+marks+`) ORDER BY name`,
+		append([]any{institution(ctx)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range list {
+		courses[c.LecturerID] = append(courses[c.LecturerID], c)
+	}
+	return courses, nil
+}
+
+func queryCourses(ctx context.Context, query string, args ...any) ([]*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var courses []*models.Course
+	for rows.Next() {
+		c, err := scanCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, c)
+	}
+	return courses, rows.Err()
+}
+
+func DeleteCourse(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if id <= 0 {
 		return errors.New("invalid course ID")
 	}
-	result, err := DB.Exec(`DELETE FROM course WHERE id = ?`, id)
+	result, err := DB.ExecContext(ctx, `DELETE FROM course WHERE id = ? AND institution_id = ?`, id, institution(ctx))
 	if err != nil {
 		return err
 	}
+	forgetCourses(ctx)
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return err
@@ -119,14 +229,23 @@ func DeleteCourse(id int) error {
 	return nil
 }
 
-func FindCoursesByLecturerID(lecturerID int) ([]*models.Course, error) {
+func FindCoursesByLecturerID(ctx context.Context, lecturerID int) ([]*models.Course, error) {
+	return cached(ctx, fmt.Sprintf("course:lecturer:%d", lecturerID), cloneCourses, func() ([]*models.Course, error) {
+		return findCoursesByLecturerID(ctx, lecturerID)
+	})
+}
+
+func findCoursesByLecturerID(ctx context.Context, lecturerID int) ([]*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if lecturerID <= 0 {
 		return nil, errors.New("invalid lecturer ID")
 	}
 
-	rows, err := DB.Query(
-		`SELECT id, name, level, lecturer_id FROM course WHERE lecturer_id = ?`,
-		lecturerID,
+	rows, err := DB.QueryContext(ctx,
+		`SELECT `+courseColumns+` FROM course WHERE lecturer_id = ? AND institution_id = ?`,
+		lecturerID, institution(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -136,11 +255,11 @@ func FindCoursesByLecturerID(lecturerID int) ([]*models.Course, error) {
 	var courses []*models.Course
 
 	for rows.Next() {
-		var c models.Course
-		if err := rows.Scan(&c.ID, &c.Name, &c.Level, &c.LecturerID); err != nil {
+		c, err := scanCourse(rows)
+		if err != nil {
 			return nil, err
 		}
-		courses = append(courses, &c)
+		courses = append(courses, c)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -150,14 +269,23 @@ func FindCoursesByLecturerID(lecturerID int) ([]*models.Course, error) {
 	return courses, nil
 }
 
-func FindCoursesByLevel(level int) ([]*models.Course, error) {
+func FindCoursesByLevel(ctx context.Context, level int) ([]*models.Course, error) {
+	return cached(ctx, fmt.Sprintf("course:level:%d", level), cloneCourses, func() ([]*models.Course, error) {
+		return findCoursesByLevel(ctx, level)
+	})
+}
+
+func findCoursesByLevel(ctx context.Context, level int) ([]*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if level <= 0 {
 		return nil, errors.New("invalid course level")
 	}
 
-	rows, err := DB.Query(
-		`SELECT id, name, level, lecturer_id FROM course WHERE level = ?`,
-		level,
+	rows, err := DB.QueryContext(ctx,
+		`SELECT `+courseColumns+` FROM course WHERE level = ? AND institution_id = ?`,
+		level, institution(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -167,11 +295,11 @@ func FindCoursesByLevel(level int) ([]*models.Course, error) {
 	var courses []*models.Course
 
 	for rows.Next() {
-		var c models.Course
-		if err := rows.Scan(&c.ID, &c.Name, &c.Level, &c.LecturerID); err != nil {
+		c, err := scanCourse(rows)
+		if err != nil {
 			return nil, err
 		}
-		courses = append(courses, &c)
+		courses = append(courses, c)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -181,16 +309,25 @@ func FindCoursesByLevel(level int) ([]*models.Course, error) {
 	return courses, nil
 }
 
-func FindCoursesByLecturerAndLevel(lecturerID, level int) ([]*models.Course, error) {
+func FindCoursesByLecturerAndLevel(ctx context.Context, lecturerID, level int) ([]*models.Course, error) {
+	return cached(ctx, fmt.Sprintf("course:lecturer:%d:level:%d", lecturerID, level), cloneCourses, func() ([]*models.Course, error) {
+		return findCoursesByLecturerAndLevel(ctx, lecturerID, level)
+	})
+}
+
+func findCoursesByLecturerAndLevel(ctx context.Context, lecturerID, level int) ([]*models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if lecturerID <= 0 || level <= 0 {
 		return nil, errors.New("invalid lecturer ID or level")
 	}
 
-	rows, err := DB.Query(
-		`SELECT id, name, level, lecturer_id 
-		 FROM course 
-		 WHERE lecturer_id = ? AND level = ?`,
-		lecturerID, level,
+	rows, err := DB.QueryContext(ctx,
+		`SELECT `+courseColumns+`
+		 FROM course
+		 WHERE lecturer_id = ? AND level = ? AND institution_id = ?`,
+		lecturerID, level, institution(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -200,11 +337,11 @@ func FindCoursesByLecturerAndLevel(lecturerID, level int) ([]*models.Course, err
 	var courses []*models.Course
 
 	for rows.Next() {
-		var c models.Course
-		if err := rows.Scan(&c.ID, &c.Name, &c.Level, &c.LecturerID); err != nil {
+		c, err := scanCourse(rows)
+		if err != nil {
 			return nil, err
 		}
-		courses = append(courses, &c)
+		courses = append(courses, c)
 	}
 
 	if err := rows.Err(); err != nil {
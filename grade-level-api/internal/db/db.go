@@ -2,27 +2,129 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"strings"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/falasefemi2/gradesystem/internal/cache"
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
 )
 
 var DB *sql.DB
 
-func Init() {
-	var err error
-	//  dsn := "root:admin@tcp(localhost:3306)/gradingsystem"
-	// DB, err = sql.Open("mysql", dsn)
-	DB, err = sql.Open(
-		"mysql",
-		"root:admin@tcp(localhost:3306)/gradingsystem?parseTime=true")
+// driver is the database/sql driver DB was opened with.
+var driver string
+
+// Init opens the database chosen by the DBDriver and DBDSN settings.
+func Init() error {
+	cfg := config.Get()
+	dsn := cfg.DBDSN
+	if dsn == "" {
+		dsn = defaultDSN[cfg.DBDriver]
+	}
+	if cfg.CacheSize > 0 {
+		SetCache(cache.NewLRU(cfg.CacheSize, cfg.CacheTTL))
+	} else {
+		SetCache(cache.Nop{})
+	}
+	if err := Open(cfg.DBDriver, dsn); err != nil {
+		return err
+	}
+	slog.Info("database connected", slog.String("driver", cfg.DBDriver))
+	return nil
+}
+
+// Open connects DB using the given driver, MySQL or SQLite, and brings the
+// schema up to date.
+func Open(driverName, dsn string) error {
+	dsn, err := prepareDSN(driverName, dsn)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	conn, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	if err = conn.Ping(); err != nil {
+		conn.Close()
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	DB, driver = conn, driverName
+	// Anything cached came from whatever database was open before.
+	readCache.DeletePrefix("")
+	if err = Migrate(); err != nil {
+		return fmt.Errorf("running migrations: %w", err)
+	}
+	return nil
+}
+
+// Ping reports whether the database is reachable, for readiness checks.
+func Ping(ctx context.Context) error {
+	if DB == nil {
+		return fmt.Errorf("database not initialised")
+	}
+	return DB.PingContext(ctx)
+}
+
+func Close() error {
+	if DB == nil {
+		return nil
+	}
+	return DB.Close()
+}
+
+// queryContext bounds a single database call by the configured query
+// timeout, on top of any deadline ctx already carries.
+func queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if d := config.Get().DBQueryTimeout; d > 0 {
+		return context.WithTimeout(ctx, d)
+	}
+	return context.WithCancel(ctx)
+}
+
+// querier is satisfied by both *sql.DB and *sql.Tx, so a query helper can
+// run on its own or as part of a transaction.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// withTx runs fn in a transaction bounded by the query timeout, committing
+// if fn returns nil and rolling back otherwise.
+func withTx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	tx, err := DB.BeginTx(ctx, nil)
 	if err != nil {
-		log.Fatal("Error opening database", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(ctx, tx); err != nil {
+		return err
 	}
-	if err = DB.Ping(); err != nil {
-		log.Fatal("Error connecting to db", err)
+	return tx.Commit()
+}
+
+// inList returns the placeholders and arguments for an IN (...) clause
+// over ids.
+func inList(ids []int) (string, []any) {
+	marks := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		marks[i] = "?"
+		args[i] = id
 	}
-	fmt.Println("Database connected successfully!")
+	return strings.Join(marks, ", "), args
+}
+
+// institution returns the institution ctx acts for. Every query on a
+// table with an institution_id must filter or set it with this value.
+func institution(ctx context.Context) int {
+	return tenant.FromContext(ctx)
 }
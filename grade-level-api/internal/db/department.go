@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+var ErrDepartmentNotFound = errors.New("department not found")
+
+func CreateDepartment(ctx context.Context, name string) (*models.Department, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if name == "" {
+		return nil, errors.New("department name cannot be empty")
+	}
+
+	result, err := DB.ExecContext(ctx, `INSERT INTO department (name, institution_id) VALUES (?, ?)`, name, institution(ctx))
+	if err != nil {
+		return nil, errors.New("department already exists or database error")
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &models.Department{ID: int(id), Name: name}, nil
+}
+
+func FindDepartmentByID(ctx context.Context, id int) (*models.Department, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var d models.Department
+	err := DB.QueryRowContext(ctx,
+		`SELECT id, name FROM department WHERE id = ? AND institution_id = ?`, id, institution(ctx),
+	).Scan(&d.ID, &d.Name)
+	if err == sql.ErrNoRows {
+		return nil, ErrDepartmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func ListDepartments(ctx context.Context) ([]models.Department, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `SELECT id, name FROM department WHERE institution_id = ? ORDER BY name`, institution(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	departments := []models.Department{}
+	for rows.Next() {
+		var d models.Department
+		if err := rows.Scan(&d.ID, &d.Name); err != nil {
+			return nil, err
+		}
+		departments = append(departments, d)
+	}
+	return departments, rows.Err()
+}
+
+// AssignCourseDepartment moves a course into a department, which the caller
+// has checked exists.
+func AssignCourseDepartment(ctx context.Context, courseID, departmentID int) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	result, err := DB.ExecContext(ctx,
+		`UPDATE course SET department_id = ? WHERE id = ? AND institution_id = ?`,
+		departmentID, courseID, institution(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	forgetCourses(ctx)
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("no course found with the given ID")
+	}
+	return nil
+}
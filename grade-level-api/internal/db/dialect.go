@@ -0,0 +1,106 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// The supported drivers. Every query in this package is written to run
+// unchanged on both; what differs is handled here, once, when connecting
+// and when applying migrations.
+const (
+	MySQL  = "mysql"
+	SQLite = "sqlite"
+)
+
+var defaultDSN = map[string]string{
+	MySQL:  "root:admin@tcp(localhost:3306)/gradingsystem",
+	SQLite: "file:gradesystem.db",
+}
+
+// prepareDSN adds the connection settings the queries rely on. MySQL must
+// return DATETIME columns as time.Time, in UTC. SQLite must enforce
+// foreign keys, wait for locks rather than fail, take the write lock when
+// a transaction begins so concurrent transactions queue instead of
+// deadlocking, and store times as UTC milliseconds so they compare and
+// sort as times instead of as strings.
+func prepareDSN(driverName, dsn string) (string, error) {
+	switch driverName {
+	case MySQL:
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return "", err
+		}
+		cfg.ParseTime = true
+		cfg.Loc = time.UTC
+		return cfg.FormatDSN(), nil
+
+	case SQLite:
+		path, query, _ := strings.Cut(dsn, "?")
+		params, err := url.ParseQuery(query)
+		if err != nil {
+			return "", err
+		}
+		pragmas := strings.Join(params["_pragma"], " ")
+		for _, p := range []string{"foreign_keys(1)", "busy_timeout(5000)"} {
+			name, _, _ := strings.Cut(p, "(")
+			if !strings.Contains(pragmas, name) {
+				params.Add("_pragma", p)
+			}
+		}
+		params.Set("_txlock", "immediate")
+		params.Set("_time_integer_format", "unix_milli")
+		params.Set("_inttotime", "1")
+		return path + "?" + params.Encode(), nil
+	}
+	return "", fmt.Errorf("unsupported database driver %q, want %q or %q", driverName, MySQL, SQLite)
+}
+
+// forUpdate is appended to a SELECT whose rows must stay locked until the
+// transaction ends. SQLite has no row locks and needs none, since every
+// transaction takes the database write lock when it begins.
+func forUpdate() string {
+	if driver == SQLite {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// isDuplicateKey reports whether err is a unique constraint violation.
+func isDuplicateKey(err error) bool {
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == 1062
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE
+	}
+	return false
+}
+
+var (
+	uniqueKey = regexp.MustCompile(`UNIQUE KEY \w+ \(`)
+	textType  = regexp.MustCompile(`\b(VARCHAR\(\d+\)|CHAR\(\d+\)|TEXT)`)
+)
+
+// translateDDL rewrites the MySQL DDL of a migration for SQLite. Text
+// columns get case-insensitive collation to match MySQL's default, so
+// lookups such as by email and unique constraints behave the same. Only
+// the constructs used in the migrations are handled.
+func translateDDL(stmt string) string {
+	if driver != SQLite {
+		return stmt
+	}
+	stmt = strings.ReplaceAll(stmt, "INT AUTO_INCREMENT PRIMARY KEY", "INTEGER PRIMARY KEY AUTOINCREMENT")
+	stmt = textType.ReplaceAllString(stmt, "$1 COLLATE NOCASE")
+	return uniqueKey.ReplaceAllString(stmt, "UNIQUE (")
+}
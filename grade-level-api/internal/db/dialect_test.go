@@ -0,0 +1,48 @@
+package db_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/dbtest"
+)
+
+// These pin down behaviour where MySQL and SQLite differ out of the box.
+func TestDialectsAgree(t *testing.T) {
+	dbtest.Open(t)
+	ctx := t.Context()
+
+	user, err := db.CreateUser(ctx, db.DB, "Ada", "Ada@Example.com", "correct-horse-battery", db.Lecturer)
+	require.NoError(t, err)
+
+	t.Run("text compares ignoring case", func(t *testing.T) {
+		found, err := db.GetUserByEmail(ctx, "ada@example.com")
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, found.ID)
+
+		_, err = db.CreateUser(ctx, db.DB, "Ada", "ADA@EXAMPLE.COM", "correct-horse-battery", db.Lecturer)
+		assert.ErrorIs(t, err, db.ErrEmailTaken, "email is unique regardless of case")
+	})
+
+	t.Run("times compare as instants", func(t *testing.T) {
+		now := time.Now().Truncate(time.Second)
+		// The same instants written and queried in far-apart zones.
+		until := now.Add(time.Hour).In(time.FixedZone("HST", -10*3600))
+		require.NoError(t, db.LockUser(ctx, user.ID, until))
+
+		locked, err := db.ListLockedUsers(ctx, now.In(time.FixedZone("LINT", 14*3600)))
+		require.NoError(t, err)
+		require.Len(t, locked, 1)
+		require.NotNil(t, locked[0].LockedUntil)
+		assert.True(t, until.Equal(*locked[0].LockedUntil))
+		assert.Equal(t, time.UTC, locked[0].LockedUntil.Location())
+
+		locked, err = db.ListLockedUsers(ctx, now.Add(2*time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, locked)
+	})
+}
@@ -0,0 +1,434 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+type EnrollmentStatus string
+
+const (
+	Enrolled   EnrollmentStatus = "enrolled"
+	Waitlisted EnrollmentStatus = "waitlisted"
+)
+
+var (
+	ErrAlreadyEnrolled   = errors.New("student is already enrolled in this course")
+	ErrAlreadyWaitlisted = errors.New("student is already on the waitlist for this course")
+	ErrEnrollmentGraded  = errors.New("a graded enrollment cannot be dropped")
+)
+
+// EnrollStudent checks that enrollment for the semester is open and that
+// the student is not already enrolled, and inserts the enrollment, in a
+// single transaction. When the offering is full, or others are already
+// waiting, the student joins the end of the waitlist instead.
+func EnrollStudent(ctx context.Context, studentID, courseID, semesterID int) (*models.Enrollment, error) {
+	return enroll(ctx, studentID, courseID, semesterID, false)
+}
+
+// ForceEnroll enrolls the student regardless of the offering's capacity,
+// taking them off the waitlist if they are on it.
+func ForceEnroll(ctx context.Context, studentID, courseID, semesterID int) (*models.Enrollment, error) {
+	return enroll(ctx, studentID, courseID, semesterID, true)
+}
+
+func enroll(ctx context.Context, studentID, courseID, semesterID int, overrideCapacity bool) (*models.Enrollment, error) {
+	if studentID <= 0 || courseID <= 0 || semesterID <= 0 {
+		return nil, errors.New("invalid student, course or semester ID")
+	}
+
+	enrollment := &models.Enrollment{StudentID: studentID, CourseID: courseID, SemesterID: semesterID}
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkEnrollmentOpen(ctx, tx, semesterID); err != nil {
+			return err
+		}
+		capacity, err := lockOffering(ctx, tx, courseID, semesterID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := findEnrollment(ctx, tx, studentID, courseID, semesterID); err == nil {
+			return ErrAlreadyEnrolled
+		}
+		// Only an override takes a waiting student off the waitlist; the
+		// error otherwise rolls the delete back.
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM waitlist WHERE student_id = ? AND course_id = ? AND semester_id = ? AND institution_id = ?`,
+			studentID, courseID, semesterID, institution(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n > 0 && !overrideCapacity {
+			return ErrAlreadyWaitlisted
+		}
+
+		if capacity != nil && !overrideCapacity {
+			var enrolled, waiting int
+			err := tx.QueryRowContext(ctx,
+				`SELECT (SELECT COUNT(*) FROM enrollment WHERE course_id = ? AND semester_id = ? AND institution_id = ?),
+				        (SELECT COUNT(*) FROM waitlist WHERE course_id = ? AND semester_id = ? AND institution_id = ?)`,
+				courseID, semesterID, institution(ctx), courseID, semesterID, institution(ctx),
+			).Scan(&enrolled, &waiting)
+			if err != nil {
+				return err
+			}
+			if enrolled >= *capacity || waiting > 0 {
+				if _, err := tx.ExecContext(ctx,
+					`INSERT INTO waitlist (student_id, course_id, semester_id, created_at, institution_id) VALUES (?, ?, ?, ?, ?)`,
+					studentID, courseID, semesterID, time.Now(), institution(ctx),
+				); err != nil {
+					return err
+				}
+				enrollment.Status = string(Waitlisted)
+				enrollment.Position = waiting + 1
+				return nil
+			}
+		}
+
+		id, err := insertEnrollment(ctx, tx, studentID, courseID, semesterID)
+		if err != nil {
+			return err
+		}
+		enrollment.ID = id
+		enrollment.Status = string(Enrolled)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return enrollment, nil
+}
+
+// DropEnrollment takes a student out of an offering, or off its waitlist,
+// while enrollment for the semester is open, and promotes waiting students
+// into the seat freed. It returns the enrollments made by promotion.
+func DropEnrollment(ctx context.Context, studentID, courseID, semesterID int) ([]models.Enrollment, error) {
+	var promoted []models.Enrollment
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := checkEnrollmentOpen(ctx, tx, semesterID); err != nil {
+			return err
+		}
+		capacity, err := lockOffering(ctx, tx, courseID, semesterID)
+		if err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx,
+			`DELETE FROM waitlist WHERE student_id = ? AND course_id = ? AND semester_id = ? AND institution_id = ?`,
+			studentID, courseID, semesterID, institution(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil || n > 0 {
+			return err
+		}
+
+		enrollment, err := findEnrollment(ctx, tx, studentID, courseID, semesterID)
+		if err != nil {
+			return err
+		}
+		var graded int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM grade WHERE enrollment_id = ? AND institution_id = ?`, enrollment.ID, institution(ctx),
+		).Scan(&graded); err != nil {
+			return err
+		}
+		if graded > 0 {
+			return ErrEnrollmentGraded
+		}
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM enrollment WHERE id = ? AND institution_id = ?`, enrollment.ID, institution(ctx),
+		); err != nil {
+			return err
+		}
+
+		promoted, err = promote(ctx, tx, courseID, semesterID, capacity)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+// SetCapacity limits how many students can enroll in an offering; nil
+// lifts the limit. Waiting students are promoted into any seats this
+// opens. Lowering the capacity below the current enrollment drops no one,
+// but new students wait until enough have dropped. It returns the
+// enrollments made by promotion.
+func SetCapacity(ctx context.Context, courseID, semesterID int, capacity *int) ([]models.Enrollment, error) {
+	if capacity != nil && *capacity < 0 {
+		return nil, errors.New("capacity cannot be negative")
+	}
+
+	var promoted []models.Enrollment
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx,
+			`SELECT 1 FROM semester WHERE id = ? AND institution_id = ?`, semesterID, institution(ctx),
+		).Scan(&exists)
+		if err == sql.ErrNoRows {
+			return errors.New("semester not found")
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := lockOffering(ctx, tx, courseID, semesterID); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM offering WHERE course_id = ? AND semester_id = ? AND institution_id = ?`,
+			courseID, semesterID, institution(ctx),
+		); err != nil {
+			return err
+		}
+		if capacity != nil {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO offering (course_id, semester_id, capacity, institution_id) VALUES (?, ?, ?, ?)`,
+				courseID, semesterID, *capacity, institution(ctx),
+			); err != nil {
+				return err
+			}
+		}
+
+		promoted, err = promote(ctx, tx, courseID, semesterID, capacity)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return promoted, nil
+}
+
+func FindOffering(ctx context.Context, courseID, semesterID int) (*models.Offering, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	offering := &models.Offering{CourseID: courseID, SemesterID: semesterID}
+	err := DB.QueryRowContext(ctx,
+		`SELECT (SELECT capacity FROM offering WHERE course_id = ? AND semester_id = ? AND institution_id = ?),
+		        (SELECT COUNT(*) FROM enrollment WHERE course_id = ? AND semester_id = ? AND institution_id = ?),
+		        (SELECT COUNT(*) FROM waitlist WHERE course_id = ? AND semester_id = ? AND institution_id = ?)`,
+		courseID, semesterID, institution(ctx),
+		courseID, semesterID, institution(ctx),
+		courseID, semesterID, institution(ctx),
+	).Scan(&offering.Capacity, &offering.Enrolled, &offering.Waitlisted)
+	if err != nil {
+		return nil, err
+	}
+	return offering, nil
+}
+
+// ListWaitlist returns the students waiting for a seat in an offering, in
+// the order they will be promoted.
+func ListWaitlist(ctx context.Context, courseID, semesterID int) ([]models.WaitlistEntry, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT w.student_id, u.name, w.created_at
+		 FROM waitlist w
+		 JOIN user u ON u.id = w.student_id
+		 WHERE w.course_id = ? AND w.semester_id = ? AND w.institution_id = ?
+		 ORDER BY w.id`,
+		courseID, semesterID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.WaitlistEntry{}
+	for rows.Next() {
+		e := models.WaitlistEntry{Position: len(entries) + 1}
+		if err := rows.Scan(&e.StudentID, &e.StudentName, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func checkEnrollmentOpen(ctx context.Context, tx *sql.Tx, semesterID int) error {
+	var open bool
+	err := tx.QueryRowContext(ctx,
+		`SELECT enrollment_open FROM semester WHERE id = ? AND institution_id = ?`, semesterID, institution(ctx),
+	).Scan(&open)
+	if err == sql.ErrNoRows {
+		return errors.New("semester not found")
+	}
+	if err != nil {
+		return err
+	}
+	if !open {
+		return ErrEnrollmentClosed
+	}
+	return nil
+}
+
+// lockOffering locks the course's row so that enrollments, drops and
+// capacity changes for it run one at a time, which keeps concurrent
+// requests from overfilling an offering. It returns the offering's
+// capacity, or nil when enrollment is unlimited.
+func lockOffering(ctx context.Context, tx *sql.Tx, courseID, semesterID int) (*int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx,
+		`SELECT id FROM course WHERE id = ? AND institution_id = ?`+forUpdate(), courseID, institution(ctx),
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("no course found with the given ID")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var capacity int
+	err = tx.QueryRowContext(ctx,
+		`SELECT capacity FROM offering WHERE course_id = ? AND semester_id = ? AND institution_id = ?`,
+		courseID, semesterID, institution(ctx),
+	).Scan(&capacity)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &capacity, nil
+}
+
+// promote enrolls students from the front of the offering's waitlist while
+// it has free seats. The caller must hold the offering's lock.
+func promote(ctx context.Context, tx *sql.Tx, courseID, semesterID int, capacity *int) ([]models.Enrollment, error) {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT id, student_id FROM waitlist WHERE course_id = ? AND semester_id = ? AND institution_id = ? ORDER BY id`,
+		courseID, semesterID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	type waiting struct{ id, studentID int }
+	var queue []waiting
+	for rows.Next() {
+		var w waiting
+		if err := rows.Scan(&w.id, &w.studentID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		queue = append(queue, w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(queue) == 0 {
+		return nil, err
+	}
+
+	var enrolled int
+	if err := tx.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM enrollment WHERE course_id = ? AND semester_id = ? AND institution_id = ?`,
+		courseID, semesterID, institution(ctx),
+	).Scan(&enrolled); err != nil {
+		return nil, err
+	}
+
+	var promoted []models.Enrollment
+	for _, w := range queue {
+		if capacity != nil && enrolled >= *capacity {
+			break
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM waitlist WHERE id = ? AND institution_id = ?`, w.id, institution(ctx)); err != nil {
+			return nil, err
+		}
+		id, err := insertEnrollment(ctx, tx, w.studentID, courseID, semesterID)
+		if err != nil {
+			return nil, err
+		}
+		promoted = append(promoted, models.Enrollment{
+			ID: id, StudentID: w.studentID, CourseID: courseID, SemesterID: semesterID, Status: string(Enrolled),
+		})
+		enrolled++
+	}
+	return promoted, nil
+}
+
+func insertEnrollment(ctx context.Context, tx *sql.Tx, studentID, courseID, semesterID int) (int, error) {
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO enrollment (student_id, course_id, semester_id, institution_id) VALUES (?, ?, ?, ?)`,
+		studentID, courseID, semesterID, institution(ctx),
+	)
+	if err != nil {
+		return 0, err
+	}
+	id, err := result.LastInsertId()
+	return int(id), err
+}
+
+var ErrEnrollmentNotFound = errors.New("enrollment not found")
+
+func FindEnrollment(ctx context.Context, studentID, courseID, semesterID int) (*models.Enrollment, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	return findEnrollment(ctx, DB, studentID, courseID, semesterID)
+}
+
+func findEnrollment(ctx context.Context, q querier, studentID, courseID, semesterID int) (*models.Enrollment, error) {
+	var e models.Enrollment
+	err := q.QueryRowContext(ctx,
+		`SELECT id, student_id, course_id, semester_id
+		 FROM enrollment
+		 WHERE student_id = ? AND course_id = ? AND semester_id = ? AND institution_id = ?`,
+		studentID, courseID, semesterID, institution(ctx),
+	).Scan(&e.ID, &e.StudentID, &e.CourseID, &e.SemesterID)
+	if err == sql.ErrNoRows {
+		return nil, ErrEnrollmentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	e.Status = string(Enrolled)
+	return &e, nil
+}
+
+// ListEnrolledStudents returns the students enrolled in a course for a
+// semester, ordered by name.
+func ListEnrolledStudents(ctx context.Context, courseID, semesterID int) ([]models.User, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT u.id, u.name, u.email, u.role
+		 FROM enrollment e
+		 JOIN user u ON u.id = e.student_id
+		 WHERE e.course_id = ? AND e.semester_id = ? AND e.institution_id = ?
+		 ORDER BY u.name`,
+		courseID, semesterID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
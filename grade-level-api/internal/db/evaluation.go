@@ -0,0 +1,273 @@
+package db
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+type QuestionKind string
+
+const (
+	QuestionLikert QuestionKind = "likert"
+	QuestionText   QuestionKind = "text"
+)
+
+var (
+	ErrEvaluationSubmitted = errors.New("you have already evaluated this course")
+	ErrQuestionNotFound    = errors.New("evaluation question not found")
+)
+
+func CreateEvaluationQuestion(ctx context.Context, prompt string, kind QuestionKind, position int) (*models.EvaluationQuestion, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	if kind != QuestionLikert && kind != QuestionText {
+		return nil, errors.New("question kind must be likert or text")
+	}
+
+	result, err := DB.ExecContext(ctx,
+		`INSERT INTO evaluation_question (prompt, kind, position, active, institution_id) VALUES (?, ?, ?, ?, ?)`,
+		prompt, string(kind), position, true, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	return &models.EvaluationQuestion{
+		ID:       int(id),
+		Prompt:   prompt,
+		Kind:     string(kind),
+		Position: position,
+		Active:   true,
+	}, nil
+}
+
+// ListEvaluationQuestions returns the questions in the order they are
+// asked. Retired questions are left out unless includeRetired is set.
+func ListEvaluationQuestions(ctx context.Context, includeRetired bool) ([]models.EvaluationQuestion, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	query := `SELECT id, prompt, kind, position, active FROM evaluation_question WHERE institution_id = ?`
+	args := []any{institution(ctx)}
+	if !includeRetired {
+		query += ` AND active = ?`
+		args = append(args, true)
+	}
+	query += ` ORDER BY position, id`
+
+	rows, err := DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	questions := []models.EvaluationQuestion{}
+	for rows.Next() {
+		var q models.EvaluationQuestion
+		if err := rows.Scan(&q.ID, &q.Prompt, &q.Kind, &q.Position, &q.Active); err != nil {
+			return nil, err
+		}
+		questions = append(questions, q)
+	}
+	return questions, rows.Err()
+}
+
+// RetireEvaluationQuestion stops a question being asked. It is kept so the
+// answers already given to it still show up in results.
+func RetireEvaluationQuestion(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	result, err := DB.ExecContext(ctx,
+		`UPDATE evaluation_question SET active = ? WHERE id = ? AND institution_id = ?`, false, id, institution(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrQuestionNotFound
+	}
+	return nil
+}
+
+// SubmitEvaluation records that the student has evaluated the course
+// offering and stores their answers under a random response ID. Nothing
+// that identifies the student, nor when they answered, is stored with the
+// response.
+func SubmitEvaluation(ctx context.Context, studentID, courseID, semesterID int, answers []models.EvaluationAnswer) error {
+	responseID, err := newResponseID()
+	if err != nil {
+		return err
+	}
+
+	return withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := findEnrollment(ctx, tx, studentID, courseID, semesterID); err != nil {
+			return err
+		}
+
+		var submitted int
+		err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM evaluation_submission
+			 WHERE student_id = ? AND course_id = ? AND semester_id = ? AND institution_id = ?`,
+			studentID, courseID, semesterID, institution(ctx),
+		).Scan(&submitted)
+		if err != nil {
+			return err
+		}
+		if submitted > 0 {
+			return ErrEvaluationSubmitted
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO evaluation_submission (student_id, course_id, semester_id, institution_id) VALUES (?, ?, ?, ?)`,
+			studentID, courseID, semesterID, institution(ctx),
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO evaluation_response (id, course_id, semester_id, institution_id) VALUES (?, ?, ?, ?)`,
+			responseID, courseID, semesterID, institution(ctx),
+		); err != nil {
+			return err
+		}
+		for _, a := range answers {
+			var body sql.NullString
+			if a.Comment != "" {
+				body = sql.NullString{String: a.Comment, Valid: true}
+			}
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO evaluation_answer (response_id, question_id, rating, body, institution_id) VALUES (?, ?, ?, ?, ?)`,
+				responseID, a.QuestionID, a.Rating, body, institution(ctx),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func newResponseID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func CountEvaluationResponses(ctx context.Context, courseID, semesterID int) (int, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var n int
+	err := DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM evaluation_response WHERE course_id = ? AND semester_id = ? AND institution_id = ?`,
+		courseID, semesterID, institution(ctx),
+	).Scan(&n)
+	return n, err
+}
+
+// OfferingAnswer is a single answer given in a course offering's
+// evaluation, together with its question.
+type OfferingAnswer struct {
+	Question models.EvaluationQuestion
+	Rating   *int
+	Comment  string
+}
+
+// ListOfferingAnswers returns every answer given for the course offering,
+// grouped by question in the order the questions are asked. Within a
+// question the order follows the random response IDs.
+func ListOfferingAnswers(ctx context.Context, courseID, semesterID int) ([]OfferingAnswer, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT q.id, q.prompt, q.kind, q.position, q.active, a.rating, a.body
+		 FROM evaluation_answer a
+		 JOIN evaluation_response r ON r.id = a.response_id
+		 JOIN evaluation_question q ON q.id = a.question_id
+		 WHERE r.course_id = ? AND r.semester_id = ? AND r.institution_id = ?
+		 ORDER BY q.position, q.id, r.id`,
+		courseID, semesterID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var answers []OfferingAnswer
+	for rows.Next() {
+		var (
+			a      OfferingAnswer
+			rating sql.NullInt64
+			body   sql.NullString
+		)
+		if err := rows.Scan(&a.Question.ID, &a.Question.Prompt, &a.Question.Kind, &a.Question.Position, &a.Question.Active, &rating, &body); err != nil {
+			return nil, err
+		}
+		if rating.Valid {
+			r := int(rating.Int64)
+			a.Rating = &r
+		}
+		a.Comment = body.String
+		answers = append(answers, a)
+	}
+	return answers, rows.Err()
+}
+
+// ListLecturerRatings averages the Likert ratings each lecturer received
+// in the semester, per department of the evaluated courses. Ranks are left
+// for the caller to assign.
+func ListLecturerRatings(ctx context.Context, semesterID int) ([]models.LecturerRating, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT c.department_id, d.name, u.id, u.name,
+			COUNT(DISTINCT c.id), COUNT(DISTINCT r.id), AVG(a.rating)
+		 FROM evaluation_answer a
+		 JOIN evaluation_response r ON r.id = a.response_id
+		 JOIN course c ON c.id = r.course_id
+		 JOIN user u ON u.id = c.lecturer_id
+		 LEFT JOIN department d ON d.id = c.department_id
+		 WHERE r.semester_id = ? AND r.institution_id = ? AND a.rating IS NOT NULL
+		 GROUP BY c.department_id, d.name, u.id, u.name`,
+		semesterID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ratings := []models.LecturerRating{}
+	for rows.Next() {
+		var (
+			lr           models.LecturerRating
+			departmentID sql.NullInt64
+			department   sql.NullString
+		)
+		if err := rows.Scan(&departmentID, &department, &lr.LecturerID, &lr.LecturerName, &lr.Courses, &lr.Responses, &lr.MeanRating); err != nil {
+			return nil, err
+		}
+		if departmentID.Valid {
+			id := int(departmentID.Int64)
+			lr.DepartmentID = &id
+		}
+		lr.DepartmentName = department.String
+		ratings = append(ratings, lr)
+	}
+	return ratings, rows.Err()
+}
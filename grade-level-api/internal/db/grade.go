@@ -0,0 +1,291 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+var (
+	ErrGradeNotFound  = errors.New("grade not found")
+	ErrGradePublished = errors.New("grade has already been published")
+)
+
+// SaveGrade posts or corrects the score for an enrollment. Once a grade is
+// published it can only be changed through an appeal.
+func SaveGrade(ctx context.Context, enrollmentID int, score float64) (*models.Grade, error) {
+	if score < 0 || score > 100 {
+		return nil, errors.New("score must be between 0 and 100")
+	}
+
+	var grade *models.Grade
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		grade, err = saveGrade(ctx, tx, enrollmentID, score)
+		return err
+	})
+	return grade, err
+}
+
+// SaveStudentGrade looks up a student's enrollment in a course offering
+// and saves their score in the same transaction, so the enrollment cannot
+// be removed in between. It returns ErrEnrollmentNotFound if the student
+// is not enrolled.
+func SaveStudentGrade(ctx context.Context, studentID, courseID, semesterID int, score float64) (*models.Grade, error) {
+	if score < 0 || score > 100 {
+		return nil, errors.New("score must be between 0 and 100")
+	}
+
+	var grade *models.Grade
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		enrollment, err := findEnrollment(ctx, tx, studentID, courseID, semesterID)
+		if err != nil {
+			return err
+		}
+		grade, err = saveGrade(ctx, tx, enrollment.ID, score)
+		return err
+	})
+	return grade, err
+}
+
+func saveGrade(ctx context.Context, q querier, enrollmentID int, score float64) (*models.Grade, error) {
+	var (
+		grade       models.Grade
+		publishedAt sql.NullTime
+	)
+	err := q.QueryRowContext(ctx,
+		`SELECT id, enrollment_id, score, published_at FROM grade WHERE enrollment_id = ? AND institution_id = ?`,
+		enrollmentID, institution(ctx),
+	).Scan(&grade.ID, &grade.EnrollmentID, &grade.Score, &publishedAt)
+
+	if err == sql.ErrNoRows {
+		result, err := q.ExecContext(ctx,
+			`INSERT INTO grade (enrollment_id, score, institution_id) VALUES (?, ?, ?)`,
+			enrollmentID, score, institution(ctx),
+		)
+		if err != nil {
+			return nil, err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		return &models.Grade{ID: int(id), EnrollmentID: enrollmentID, Score: score}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if publishedAt.Valid {
+		return nil, ErrGradePublished
+	}
+
+	if _, err := q.ExecContext(ctx,
+		`UPDATE grade SET score = ? WHERE id = ? AND institution_id = ?`, score, grade.ID, institution(ctx),
+	); err != nil {
+		return nil, err
+	}
+	grade.Score = score
+	return &grade, nil
+}
+
+// PublishGrades makes every unpublished grade of a course offering visible
+// to students and returns the IDs of the students whose grades were
+// published.
+func PublishGrades(ctx context.Context, courseID, semesterID int) ([]int, error) {
+	var studentIDs []int
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx,
+			`SELECT g.id, e.student_id FROM grade g JOIN enrollment e ON e.id = g.enrollment_id
+			 WHERE g.published_at IS NULL AND e.course_id = ? AND e.semester_id = ? AND g.institution_id = ?`,
+			courseID, semesterID, institution(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		// Only the grades read here are published, so the students
+		// returned match exactly.
+		var gradeIDs []int
+		for rows.Next() {
+			var gradeID, studentID int
+			if err := rows.Scan(&gradeID, &studentID); err != nil {
+				return err
+			}
+			gradeIDs = append(gradeIDs, gradeID)
+			studentIDs = append(studentIDs, studentID)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+		if len(gradeIDs) == 0 {
+			return nil
+		}
+
+		marks, args := inList(gradeIDs)
+		_, err = tx.ExecContext(ctx,
+			`UPDATE grade SET published_at = ? WHERE institution_id = ? AND id IN (`+marks+`)`,
+			append([]any{time.Now(), institution(ctx)}, args...)...,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return studentIDs, nil
+}
+
+// GradesPublished reports whether a course offering has grades and all of
+// them have been published.
+func GradesPublished(ctx context.Context, courseID, semesterID int) (bool, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var total, unpublished int
+	err := DB.QueryRowContext(ctx,
+		`SELECT COUNT(*), COUNT(*) - COUNT(g.published_at)
+		 FROM grade g JOIN enrollment e ON e.id = g.enrollment_id
+		 WHERE e.course_id = ? AND e.semester_id = ? AND g.institution_id = ?`,
+		courseID, semesterID, institution(ctx),
+	).Scan(&total, &unpublished)
+	if err != nil {
+		return false, err
+	}
+	return total > 0 && unpublished == 0, nil
+}
+
+// studentGradeQuery is continued with further conditions; queryStudentGrades
+// supplies the institution.
+const studentGradeQuery = `SELECT g.id, e.student_id, c.id, c.name, s.id, s.name, g.score, g.published_at
+	FROM grade g
+	JOIN enrollment e ON e.id = g.enrollment_id
+	JOIN course c ON c.id = e.course_id
+	JOIN semester s ON s.id = e.semester_id
+	WHERE g.institution_id = ? `
+
+func FindStudentGrade(ctx context.Context, gradeID int) (*models.StudentGrade, error) {
+	grades, err := queryStudentGrades(ctx, studentGradeQuery+`AND g.id = ?`, gradeID)
+	if err != nil {
+		return nil, err
+	}
+	if len(grades) == 0 {
+		return nil, ErrGradeNotFound
+	}
+	return &grades[0], nil
+}
+
+func ListPublishedGradesForStudent(ctx context.Context, studentID int) ([]models.StudentGrade, error) {
+	return queryStudentGrades(ctx,
+		studentGradeQuery+`AND e.student_id = ? AND g.published_at IS NOT NULL ORDER BY s.start_date, c.name`,
+		studentID,
+	)
+}
+
+// ListPublishedGradesForStudents returns the published grades of several
+// students at once, keyed by student ID.
+func ListPublishedGradesForStudents(ctx context.Context, studentIDs []int) (map[int][]models.StudentGrade, error) {
+	byStudent := map[int][]models.StudentGrade{}
+	if len(studentIDs) == 0 {
+		return byStudent, nil
+	}
+	marks, args := inList(studentIDs)
+	grades, err := queryStudentGrades(ctx,
+		studentGradeQuery+`AND e.student_id IN (`+marks+`) AND g.published_at IS NOT NULL ORDER BY s.start_date, c.name`,
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range grades {
+		byStudent[g.StudentID] = append(byStudent[g.StudentID], g)
+	}
+	return byStudent, nil
+}
+
+func ListGradesForOffering(ctx context.Context, courseID, semesterID int) ([]models.StudentGrade, error) {
+	return queryStudentGrades(ctx,
+		studentGradeQuery+`AND e.course_id = ? AND e.semester_id = ? ORDER BY e.student_id`,
+		courseID, semesterID,
+	)
+}
+
+func queryStudentGrades(ctx context.Context, query string, args ...any) ([]models.StudentGrade, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, query, append([]any{institution(ctx)}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grades []models.StudentGrade
+
+	for rows.Next() {
+		var (
+			g           models.StudentGrade
+			publishedAt sql.NullTime
+		)
+		if err := rows.Scan(
+			&g.GradeID, &g.StudentID, &g.CourseID, &g.CourseName,
+			&g.SemesterID, &g.SemesterName, &g.Score, &publishedAt,
+		); err != nil {
+			return nil, err
+		}
+		if publishedAt.Valid {
+			g.PublishedAt = &publishedAt.Time
+		}
+		grades = append(grades, g)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return grades, nil
+}
+
+func ListGradeChanges(ctx context.Context, gradeID int) ([]models.GradeChange, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT id, grade_id, old_score, new_score, reason, appeal_id, changed_by, changed_at
+		 FROM grade_change WHERE grade_id = ? AND institution_id = ? ORDER BY changed_at`,
+		gradeID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := []models.GradeChange{}
+
+	for rows.Next() {
+		var (
+			c        models.GradeChange
+			appealID sql.NullInt64
+		)
+		if err := rows.Scan(
+			&c.ID, &c.GradeID, &c.OldScore, &c.NewScore, &c.Reason,
+			&appealID, &c.ChangedBy, &c.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		if appealID.Valid {
+			id := int(appealID.Int64)
+			c.AppealID = &id
+		}
+		changes = append(changes, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
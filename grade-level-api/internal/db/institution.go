@@ -0,0 +1,127 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"regexp"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+// Institutions are the one table not scoped to an institution: they are
+// looked up to find out which institution a request is for.
+
+var (
+	ErrInstitutionNotFound = errors.New("institution not found")
+	ErrInstitutionExists   = errors.New("an institution with this slug already exists")
+)
+
+// A slug must be usable as a DNS label, since it can name the institution
+// as a subdomain.
+var validSlug = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// defaultEvaluationQuestions are the questions a new institution's course
+// evaluations start with, the same ones the default institution was given.
+var defaultEvaluationQuestions = []struct {
+	prompt string
+	kind   QuestionKind
+}{
+	{"The course objectives were clearly explained.", QuestionLikert},
+	{"The lecturer was well prepared for classes.", QuestionLikert},
+	{"Assessments reflected what was taught.", QuestionLikert},
+	{"Overall, I would rate this course highly.", QuestionLikert},
+	{"What should the lecturer keep or change?", QuestionText},
+}
+
+// CreateInstitution adds an institution with the standard evaluation
+// questions.
+func CreateInstitution(ctx context.Context, slug, name string) (*models.Institution, error) {
+	if !validSlug.MatchString(slug) {
+		return nil, errors.New("slug must be lowercase letters, digits and hyphens")
+	}
+	if name == "" {
+		return nil, errors.New("institution name cannot be empty")
+	}
+
+	inst := &models.Institution{Slug: slug, Name: name}
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var exists int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM institution WHERE slug = ?`, slug).Scan(&exists); err != nil {
+			return err
+		}
+		if exists > 0 {
+			return ErrInstitutionExists
+		}
+		result, err := tx.ExecContext(ctx, `INSERT INTO institution (slug, name) VALUES (?, ?)`, slug, name)
+		if err != nil {
+			return err
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		inst.ID = int(id)
+
+		for i, q := range defaultEvaluationQuestions {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO evaluation_question (prompt, kind, position, active, institution_id) VALUES (?, ?, ?, ?, ?)`,
+				q.prompt, string(q.kind), i+1, true, inst.ID,
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// FindInstitutionBySlug runs on every request that names an institution,
+// so institutions are cached.
+func FindInstitutionBySlug(ctx context.Context, slug string) (*models.Institution, error) {
+	key := "institution:" + slug
+	if v, ok := readCache.Get(key); ok {
+		inst := *v.(*models.Institution)
+		return &inst, nil
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	var inst models.Institution
+	err := DB.QueryRowContext(ctx, `SELECT id, slug, name FROM institution WHERE slug = ?`, slug).
+		Scan(&inst.ID, &inst.Slug, &inst.Name)
+	if err == sql.ErrNoRows {
+		return nil, ErrInstitutionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	cachedInst := inst
+	readCache.Set(key, &cachedInst)
+	return &inst, nil
+}
+
+func ListInstitutions(ctx context.Context) ([]models.Institution, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx, `SELECT id, slug, name FROM institution ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	institutions := []models.Institution{}
+	for rows.Next() {
+		var inst models.Institution
+		if err := rows.Scan(&inst.ID, &inst.Slug, &inst.Name); err != nil {
+			return nil, err
+		}
+		institutions = append(institutions, inst)
+	}
+	return institutions, rows.Err()
+}
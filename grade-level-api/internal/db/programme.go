@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+var ErrProgrammeNotFound = errors.New("the department has no programme requirements")
+
+// SaveProgramme replaces the department's programme requirements.
+func SaveProgramme(ctx context.Context, p *models.Programme) error {
+	return withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		for _, table := range []string{"programme_core_course", "programme_elective", "programme"} {
+			if _, err := tx.ExecContext(ctx,
+				`DELETE FROM `+table+` WHERE department_id = ? AND institution_id = ?`, p.DepartmentID, institution(ctx),
+			); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO programme (department_id, min_cgpa, institution_id) VALUES (?, ?, ?)`,
+			p.DepartmentID, p.MinCGPA, institution(ctx),
+		); err != nil {
+			return err
+		}
+		for _, courseID := range p.CoreCourseIDs {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO programme_core_course (department_id, course_id, institution_id) VALUES (?, ?, ?)`,
+				p.DepartmentID, courseID, institution(ctx),
+			); err != nil {
+				return err
+			}
+		}
+		for _, e := range p.Electives {
+			if _, err := tx.ExecContext(ctx,
+				`INSERT INTO programme_elective (department_id, level, min_units, institution_id) VALUES (?, ?, ?, ?)`,
+				p.DepartmentID, e.Level, e.MinUnits, institution(ctx),
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func FindProgramme(ctx context.Context, departmentID int) (*models.Programme, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	p := &models.Programme{DepartmentID: departmentID, CoreCourseIDs: []int{}, Electives: []models.ElectiveRequirement{}}
+	err := DB.QueryRowContext(ctx,
+		`SELECT min_cgpa FROM programme WHERE department_id = ? AND institution_id = ?`, departmentID, institution(ctx),
+	).Scan(&p.MinCGPA)
+	if err == sql.ErrNoRows {
+		return nil, ErrProgrammeNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT course_id FROM programme_core_course WHERE department_id = ? AND institution_id = ? ORDER BY course_id`,
+		departmentID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		p.CoreCourseIDs = append(p.CoreCourseIDs, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = DB.QueryContext(ctx,
+		`SELECT level, min_units FROM programme_elective WHERE department_id = ? AND institution_id = ? ORDER BY level`,
+		departmentID, institution(ctx),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var e models.ElectiveRequirement
+		if err := rows.Scan(&e.Level, &e.MinUnits); err != nil {
+			return nil, err
+		}
+		p.Electives = append(p.Electives, e)
+	}
+	return p, rows.Err()
+}
+
+// AssignStudentDepartment puts a student on the department's programme.
+// The caller has checked that both exist.
+func AssignStudentDepartment(ctx context.Context, studentID, departmentID int) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx,
+		`UPDATE user SET department_id = ? WHERE id = ? AND institution_id = ? AND role = ?`,
+		departmentID, studentID, institution(ctx), string(Student),
+	)
+	forgetUser(ctx, studentID)
+	return err
+}
+
+// ListDepartmentStudents returns the students on the department's
+// programme, ordered by name. A semesterID other than zero narrows them to
+// those enrolled in that semester, such as a graduating cohort in its
+// final semester.
+func ListDepartmentStudents(ctx context.Context, departmentID, semesterID int) ([]models.User, error) {
+	filter := `AND role = ? AND department_id = ?`
+	args := []any{string(Student), departmentID}
+	if semesterID != 0 {
+		filter += ` AND id IN (SELECT student_id FROM enrollment WHERE semester_id = ?)`
+		args = append(args, semesterID)
+	}
+	return listUsers(ctx, filter+` ORDER BY name, id`, args...)
+}
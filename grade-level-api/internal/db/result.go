@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+// cohortQuery selects the students enrolled in any course of a level in a
+// semester.
+const cohortQuery = `SELECT e.student_id FROM enrollment e
+	JOIN course c ON c.id = e.course_id
+	WHERE c.level = ? AND e.semester_id = ?`
+
+// ListLevelCourses returns the courses of a level that have enrollments in
+// the semester, ordered by name.
+func ListLevelCourses(ctx context.Context, level, semesterID int) ([]models.Course, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT `+courseColumns+` FROM course
+		 WHERE level = ? AND institution_id = ? AND id IN (SELECT course_id FROM enrollment WHERE semester_id = ?)
+		 ORDER BY name`,
+		level, institution(ctx), semesterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	courses := []models.Course{}
+	for rows.Next() {
+		c, err := scanCourse(rows)
+		if err != nil {
+			return nil, err
+		}
+		courses = append(courses, *c)
+	}
+	return courses, rows.Err()
+}
+
+// ListLevelStudents returns the students enrolled in courses of a level in
+// the semester, ordered by name.
+func ListLevelStudents(ctx context.Context, level, semesterID int) ([]models.User, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT id, name, email, role FROM user WHERE institution_id = ? AND id IN (`+cohortQuery+`) ORDER BY name, id`,
+		institution(ctx), level, semesterID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Email, &u.Role); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// ListCohortResults returns every published result of the level's cohort
+// in semesters that started no later than upTo, for GPA and CGPA.
+func ListCohortResults(ctx context.Context, level, semesterID int, upTo time.Time) ([]models.CourseResult, error) {
+	return queryResults(ctx, `AND e.student_id IN (`+cohortQuery+`)`, upTo, level, semesterID)
+}
+
+// ListSemesterResults is ListCohortResults for every student enrolled in
+// the semester, whatever the level of their courses.
+func ListSemesterResults(ctx context.Context, semesterID int, upTo time.Time) ([]models.CourseResult, error) {
+	return queryResults(ctx, `AND e.student_id IN (SELECT student_id FROM enrollment WHERE semester_id = ?)`, upTo, semesterID)
+}
+
+// ListStudentResults returns every published result of the given students
+// in semesters that have started.
+func ListStudentResults(ctx context.Context, studentIDs []int) ([]models.CourseResult, error) {
+	if len(studentIDs) == 0 {
+		return nil, nil
+	}
+	marks, args := inList(studentIDs)
+	return queryResults(ctx, `AND e.student_id IN (`+marks+`)`, time.Now(), args...)
+}
+
+func queryResults(ctx context.Context, filter string, upTo time.Time, args ...any) ([]models.CourseResult, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT e.student_id, c.id, s.id, c.level, c.units, g.score
+		 FROM grade g
+		 JOIN enrollment e ON e.id = g.enrollment_id
+		 JOIN course c ON c.id = e.course_id
+		 JOIN semester s ON s.id = e.semester_id
+		 WHERE g.published_at IS NOT NULL AND g.institution_id = ? AND s.start_date <= ? `+filter,
+		append([]any{institution(ctx), upTo}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []models.CourseResult
+	for rows.Next() {
+		var r models.CourseResult
+		if err := rows.Scan(&r.StudentID, &r.CourseID, &r.SemesterID, &r.Level, &r.Units, &r.Score); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
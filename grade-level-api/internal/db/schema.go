@@ -0,0 +1,411 @@
+package db
+
+import (
+	"context"
+	"fmt"
+)
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+	// sqlite, when set, replaces statements on SQLite, for changes its
+	// ALTER TABLE cannot make. Foreign keys are not enforced while
+	// migrations run, so a table can be rebuilt under its children.
+	sqlite []string
+}
+
+// migrations are applied in order and recorded in schema_migrations, so a
+// released migration must never be edited; add a new one instead.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "base tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS user (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				email VARCHAR(255) NOT NULL UNIQUE,
+				password VARCHAR(255) NOT NULL,
+				role VARCHAR(20) NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS semester (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(50) NOT NULL,
+				start_date DATE NOT NULL,
+				end_date DATE NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS course (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				level INT NOT NULL,
+				lecturer_id INT NOT NULL,
+				FOREIGN KEY (lecturer_id) REFERENCES user(id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS enrollment (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				student_id INT NOT NULL,
+				course_id INT NOT NULL,
+				semester_id INT NOT NULL,
+				UNIQUE KEY uq_enrollment (student_id, course_id, semester_id),
+				FOREIGN KEY (student_id) REFERENCES user(id),
+				FOREIGN KEY (course_id) REFERENCES course(id) ON DELETE CASCADE,
+				FOREIGN KEY (semester_id) REFERENCES semester(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS grade (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				enrollment_id INT NOT NULL UNIQUE,
+				score DECIMAL(5,2) NOT NULL,
+				FOREIGN KEY (enrollment_id) REFERENCES enrollment(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "attendance",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS class_session (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				course_id INT NOT NULL,
+				semester_id INT NOT NULL,
+				topic VARCHAR(255) NOT NULL DEFAULT '',
+				held_at DATETIME NOT NULL,
+				code VARCHAR(12) NULL UNIQUE,
+				code_expires_at DATETIME NULL,
+				FOREIGN KEY (course_id) REFERENCES course(id) ON DELETE CASCADE,
+				FOREIGN KEY (semester_id) REFERENCES semester(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS attendance (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				session_id INT NOT NULL,
+				student_id INT NOT NULL,
+				method VARCHAR(10) NOT NULL,
+				recorded_at DATETIME NOT NULL,
+				UNIQUE KEY uq_attendance (session_id, student_id),
+				FOREIGN KEY (session_id) REFERENCES class_session(id) ON DELETE CASCADE,
+				FOREIGN KEY (student_id) REFERENCES user(id)
+			)`,
+		},
+	},
+	{
+		version: 3,
+		name:    "grade publishing and appeals",
+		statements: []string{
+			`ALTER TABLE grade ADD COLUMN published_at DATETIME NULL`,
+			`CREATE TABLE IF NOT EXISTS appeal (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				grade_id INT NOT NULL,
+				student_id INT NOT NULL,
+				reason TEXT NOT NULL,
+				status VARCHAR(20) NOT NULL,
+				lecturer_id INT NULL,
+				lecturer_recommendation VARCHAR(10) NULL,
+				lecturer_note TEXT NULL,
+				proposed_score DECIMAL(5,2) NULL,
+				reviewed_at DATETIME NULL,
+				admin_id INT NULL,
+				admin_note TEXT NULL,
+				decided_at DATETIME NULL,
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY (grade_id) REFERENCES grade(id) ON DELETE CASCADE,
+				FOREIGN KEY (student_id) REFERENCES user(id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS grade_change (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				grade_id INT NOT NULL,
+				old_score DECIMAL(5,2) NOT NULL,
+				new_score DECIMAL(5,2) NOT NULL,
+				reason VARCHAR(255) NOT NULL,
+				appeal_id INT NULL,
+				changed_by INT NOT NULL,
+				changed_at DATETIME NOT NULL,
+				FOREIGN KEY (grade_id) REFERENCES grade(id) ON DELETE CASCADE,
+				FOREIGN KEY (appeal_id) REFERENCES appeal(id),
+				FOREIGN KEY (changed_by) REFERENCES user(id)
+			)`,
+		},
+	},
+	{
+		version: 4,
+		name:    "login lockout",
+		statements: []string{
+			`ALTER TABLE user ADD COLUMN failed_login_attempts INT NOT NULL DEFAULT 0`,
+			`ALTER TABLE user ADD COLUMN locked_until DATETIME NULL`,
+		},
+	},
+	{
+		version: 5,
+		name:    "email verification and password reset",
+		statements: []string{
+			`ALTER TABLE user ADD COLUMN email_verified_at DATETIME NULL`,
+			// Accounts that existed before verification was required stay usable.
+			`UPDATE user SET email_verified_at = CURRENT_TIMESTAMP`,
+			`CREATE TABLE IF NOT EXISTS user_token (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				purpose VARCHAR(20) NOT NULL,
+				token_hash CHAR(64) NOT NULL UNIQUE,
+				expires_at DATETIME NOT NULL,
+				used_at DATETIME NULL,
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS password_history (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				password_hash VARCHAR(255) NOT NULL,
+				created_at DATETIME NOT NULL,
+				FOREIGN KEY (user_id) REFERENCES user(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version: 6,
+		name:    "course units",
+		statements: []string{
+			`ALTER TABLE course ADD COLUMN units INT NOT NULL DEFAULT 3`,
+		},
+	},
+	{
+		version: 7,
+		name:    "departments and course evaluations",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS department (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL UNIQUE
+			)`,
+			`ALTER TABLE course ADD COLUMN department_id INT NULL`,
+			`CREATE TABLE IF NOT EXISTS evaluation_question (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				prompt VARCHAR(500) NOT NULL,
+				kind VARCHAR(10) NOT NULL,
+				position INT NOT NULL DEFAULT 0,
+				active BOOLEAN NOT NULL DEFAULT TRUE
+			)`,
+			`INSERT INTO evaluation_question (prompt, kind, position) VALUES
+				('The course objectives were clearly explained.', 'likert', 1),
+				('The lecturer was well prepared for classes.', 'likert', 2),
+				('Assessments reflected what was taught.', 'likert', 3),
+				('Overall, I would rate this course highly.', 'likert', 4),
+				('What should the lecturer keep or change?', 'text', 5)`,
+			// Submissions only record that a student has evaluated an
+			// offering; the answers live in evaluation_response, which has
+			// a random ID and no student or timestamp, so the two cannot be
+			// joined back together.
+			`CREATE TABLE IF NOT EXISTS evaluation_submission (
+				student_id INT NOT NULL,
+				course_id INT NOT NULL,
+				semester_id INT NOT NULL,
+				PRIMARY KEY (student_id, course_id, semester_id),
+				FOREIGN KEY (student_id) REFERENCES user(id),
+				FOREIGN KEY (course_id) REFERENCES course(id),
+				FOREIGN KEY (semester_id) REFERENCES semester(id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS evaluation_response (
+				id CHAR(32) PRIMARY KEY,
+				course_id INT NOT NULL,
+				semester_id INT NOT NULL,
+				FOREIGN KEY (course_id) REFERENCES course(id),
+				FOREIGN KEY (semester_id) REFERENCES semester(id)
+			)`,
+			`CREATE TABLE IF NOT EXISTS evaluation_answer (
+				response_id CHAR(32) NOT NULL,
+				question_id INT NOT NULL,
+				rating INT NULL,
+				body TEXT NULL,
+				PRIMARY KEY (response_id, question_id),
+				FOREIGN KEY (response_id) REFERENCES evaluation_response(id) ON DELETE CASCADE,
+				FOREIGN KEY (question_id) REFERENCES evaluation_question(id)
+			)`,
+		},
+	},
+	{
+		version: 8,
+		name:    "semester enrollment window",
+		statements: []string{
+			`ALTER TABLE semester ADD COLUMN enrollment_open BOOLEAN NOT NULL DEFAULT TRUE`,
+		},
+	},
+	{
+		version: 9,
+		name:    "offering capacity and waitlists",
+		statements: []string{
+			// An offering without a row here has no capacity limit.
+			`CREATE TABLE IF NOT EXISTS offering (
+				course_id INT NOT NULL,
+				semester_id INT NOT NULL,
+				capacity INT NOT NULL,
+				PRIMARY KEY (course_id, semester_id),
+				FOREIGN KEY (course_id) REFERENCES course(id) ON DELETE CASCADE,
+				FOREIGN KEY (semester_id) REFERENCES semester(id) ON DELETE CASCADE
+			)`,
+			// Waiting students are served in id order.
+			`CREATE TABLE IF NOT EXISTS waitlist (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				student_id INT NOT NULL,
+				course_id INT NOT NULL,
+				semester_id INT NOT NULL,
+				created_at DATETIME NOT NULL,
+				UNIQUE KEY uq_waitlist (student_id, course_id, semester_id),
+				FOREIGN KEY (student_id) REFERENCES user(id),
+				FOREIGN KEY (course_id) REFERENCES course(id) ON DELETE CASCADE,
+				FOREIGN KEY (semester_id) REFERENCES semester(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version: 10,
+		name:    "degree programmes",
+		statements: []string{
+			`ALTER TABLE user ADD COLUMN department_id INT NULL`,
+			`CREATE TABLE IF NOT EXISTS programme (
+				department_id INT PRIMARY KEY,
+				min_cgpa DECIMAL(3,2) NOT NULL,
+				FOREIGN KEY (department_id) REFERENCES department(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS programme_core_course (
+				department_id INT NOT NULL,
+				course_id INT NOT NULL,
+				PRIMARY KEY (department_id, course_id),
+				FOREIGN KEY (department_id) REFERENCES programme(department_id) ON DELETE CASCADE,
+				FOREIGN KEY (course_id) REFERENCES course(id) ON DELETE CASCADE
+			)`,
+			`CREATE TABLE IF NOT EXISTS programme_elective (
+				department_id INT NOT NULL,
+				level INT NOT NULL,
+				min_units INT NOT NULL,
+				PRIMARY KEY (department_id, level),
+				FOREIGN KEY (department_id) REFERENCES programme(department_id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version: 11,
+		name:    "institutions",
+		statements: institutionStatements(
+			`ALTER TABLE user ADD COLUMN institution_id INT NOT NULL DEFAULT 1`,
+			`ALTER TABLE user DROP INDEX email, ADD UNIQUE KEY uq_user_email (institution_id, email)`,
+			`ALTER TABLE department ADD COLUMN institution_id INT NOT NULL DEFAULT 1`,
+			`ALTER TABLE department DROP INDEX name, ADD UNIQUE KEY uq_department_name (institution_id, name)`,
+		),
+		sqlite: institutionStatements(
+			`CREATE TABLE user_new (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				email VARCHAR(255) NOT NULL,
+				password VARCHAR(255) NOT NULL,
+				role VARCHAR(20) NOT NULL,
+				failed_login_attempts INT NOT NULL DEFAULT 0,
+				locked_until DATETIME NULL,
+				email_verified_at DATETIME NULL,
+				department_id INT NULL,
+				institution_id INT NOT NULL DEFAULT 1,
+				UNIQUE KEY uq_user_email (institution_id, email)
+			)`,
+			`INSERT INTO user_new (id, name, email, password, role, failed_login_attempts, locked_until, email_verified_at, department_id)
+				SELECT id, name, email, password, role, failed_login_attempts, locked_until, email_verified_at, department_id FROM user`,
+			`DROP TABLE user`,
+			`ALTER TABLE user_new RENAME TO user`,
+			`CREATE TABLE department_new (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				name VARCHAR(255) NOT NULL,
+				institution_id INT NOT NULL DEFAULT 1,
+				UNIQUE KEY uq_department_name (institution_id, name)
+			)`,
+			`INSERT INTO department_new (id, name) SELECT id, name FROM department`,
+			`DROP TABLE department`,
+			`ALTER TABLE department_new RENAME TO department`,
+		),
+	},
+}
+
+// institutionStatements builds migration 11, which gives every table
+// holding a school's data an institution_id, assigning what is already
+// there to the default institution. Emails and department names become
+// unique per institution, which each dialect does its own way.
+func institutionStatements(perInstitutionUnique ...string) []string {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS institution (
+			id INT AUTO_INCREMENT PRIMARY KEY,
+			slug VARCHAR(63) NOT NULL UNIQUE,
+			name VARCHAR(255) NOT NULL
+		)`,
+		`INSERT INTO institution (id, slug, name) VALUES (1, 'default', 'Default institution')`,
+	}
+	stmts = append(stmts, perInstitutionUnique...)
+	for _, table := range []string{
+		"semester", "course", "enrollment", "grade", "class_session", "attendance",
+		"appeal", "grade_change", "user_token", "password_history",
+		"evaluation_question", "evaluation_submission", "evaluation_response", "evaluation_answer",
+		"offering", "waitlist", "programme", "programme_core_course", "programme_elective",
+	} {
+		stmts = append(stmts, `ALTER TABLE `+table+` ADD COLUMN institution_id INT NOT NULL DEFAULT 1`)
+	}
+	return append(stmts,
+		`CREATE INDEX idx_semester_institution ON semester (institution_id)`,
+		`CREATE INDEX idx_course_institution ON course (institution_id)`,
+		`CREATE INDEX idx_evaluation_question_institution ON evaluation_question (institution_id)`,
+	)
+}
+
+func Migrate() error {
+	ctx := context.Background()
+	// One connection, so the pragma below covers every statement.
+	conn, err := DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if driver == SQLite {
+		if _, err := conn.ExecContext(ctx, `PRAGMA foreign_keys = OFF`); err != nil {
+			return err
+		}
+		defer conn.ExecContext(ctx, `PRAGMA foreign_keys = ON`)
+	}
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	applied := map[int]bool{}
+	rows, err := conn.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return err
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		statements := m.statements
+		if driver == SQLite && m.sqlite != nil {
+			statements = m.sqlite
+		}
+		for _, stmt := range statements {
+			if _, err := conn.ExecContext(ctx, translateDDL(stmt)); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+		}
+		if _, err := conn.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`,
+			m.version, m.name,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
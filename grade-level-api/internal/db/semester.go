@@ -2,8 +2,10 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/falasefemi2/gradesystem/internal/models"
@@ -17,7 +19,10 @@ const (
 	ThirdSemester  Semester = "thirdsemester"
 )
 
-func CreateSemester(name Semester, startDate, endDate time.Time) (*models.Semester, error) {
+func CreateSemester(ctx context.Context, name Semester, startDate, endDate time.Time) (*models.Semester, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if name == "" {
 		return nil, errors.New("semester name cannot be empty")
 	}
@@ -25,26 +30,40 @@ func CreateSemester(name Semester, startDate, endDate time.Time) (*models.Semest
 		return nil, errors.New("end date cannot be before start date")
 	}
 
-	result, err := DB.Exec("INSERT INTO semester (name, start_date, end_date) VALUES (?, ?, ?)", string(name), startDate, endDate)
+	result, err := DB.ExecContext(ctx, "INSERT INTO semester (name, start_date, end_date, institution_id) VALUES (?, ?, ?, ?)",
+		string(name), startDate, endDate, institution(ctx),
+	)
 	if err != nil {
 		return nil, err
 	}
+	forgetSemesters(ctx)
 	id, err := result.LastInsertId()
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.Semester{
-		ID:        int(id),
-		Name:      string(name),
-		StartDate: startDate,
-		EndDate:   endDate,
+		ID:             int(id),
+		Name:           string(name),
+		StartDate:      startDate,
+		EndDate:        endDate,
+		EnrollmentOpen: true,
 	}, nil
 }
 
-func ListSemesters() ([]models.Semester, error) {
-	rows, err := DB.Query(
-		`SELECT id, name, start_date, end_date FROM semester ORDER BY start_date`,
+func ListSemesters(ctx context.Context) ([]models.Semester, error) {
+	return cached(ctx, "semester:all", cloneSemesters, func() ([]models.Semester, error) {
+		return listSemesters(ctx)
+	})
+}
+
+func listSemesters(ctx context.Context) ([]models.Semester, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT id, name, start_date, end_date, enrollment_open FROM semester WHERE institution_id = ? ORDER BY start_date`,
+		institution(ctx),
 	)
 	if err != nil {
 		return nil, err
@@ -55,7 +74,7 @@ func ListSemesters() ([]models.Semester, error) {
 
 	for rows.Next() {
 		var s models.Semester
-		if err := rows.Scan(&s.ID, &s.Name, &s.StartDate, &s.EndDate); err != nil {
+		if err := rows.Scan(&s.ID, &s.Name, &s.StartDate, &s.EndDate, &s.EnrollmentOpen); err != nil {
 			return nil, err
 		}
 		semesters = append(semesters, s)
@@ -69,13 +88,22 @@ func ListSemesters() ([]models.Semester, error) {
 	return semesters, nil
 }
 
-func FindSemesterByID(id int) (*models.Semester, error) {
+func FindSemesterByID(ctx context.Context, id int) (*models.Semester, error) {
+	return cached(ctx, fmt.Sprintf("semester:%d", id), cloneSemester, func() (*models.Semester, error) {
+		return findSemesterByID(ctx, id)
+	})
+}
+
+func findSemesterByID(ctx context.Context, id int) (*models.Semester, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	var s models.Semester
 
-	err := DB.QueryRow(
-		`SELECT id, name, start_date, end_date FROM semester WHERE id = ?`,
-		id,
-	).Scan(&s.ID, &s.Name, &s.StartDate, &s.EndDate)
+	err := DB.QueryRowContext(ctx,
+		`SELECT id, name, start_date, end_date, enrollment_open FROM semester WHERE id = ? AND institution_id = ?`,
+		id, institution(ctx),
+	).Scan(&s.ID, &s.Name, &s.StartDate, &s.EndDate, &s.EnrollmentOpen)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("semester not found")
@@ -87,7 +115,40 @@ func FindSemesterByID(id int) (*models.Semester, error) {
 	return &s, nil
 }
 
-func UpdateSemester(id int, name Semester, startDate, endDate time.Time) (*models.Semester, error) {
+// FindSemestersByIDs looks up several semesters at once, keyed by ID.
+func FindSemestersByIDs(ctx context.Context, ids []int) (map[int]*models.Semester, error) {
+	semesters := map[int]*models.Semester{}
+	if len(ids) == 0 {
+		return semesters, nil
+	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	marks, args := inList(ids)
+	rows, err := DB.QueryContext(ctx,
+		`SELECT id, name, start_date, end_date, enrollment_open FROM semester WHERE institution_id = ? AND id IN (`+marks+`)`,
+		append([]any{institution(ctx)}, args...)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s models.Semester
+		if err := rows.Scan(&s.ID, &s.Name, &s.StartDate, &s.EndDate, &s.EnrollmentOpen); err != nil {
+			return nil, err
+		}
+		semesters[s.ID] = &s
+	}
+	return semesters, rows.Err()
+}
+
+func UpdateSemester(ctx context.Context, id int, name Semester, startDate, endDate time.Time) (*models.Semester, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if id <= 0 {
 		return nil, errors.New("invalid semester id")
 	}
@@ -98,18 +159,20 @@ func UpdateSemester(id int, name Semester, startDate, endDate time.Time) (*model
 		return nil, errors.New("end date cannot be before start date")
 	}
 
-	result, err := DB.Exec(
+	result, err := DB.ExecContext(ctx,
 		`UPDATE semester 
 		 SET name = ?, start_date = ?, end_date = ?
-		 WHERE id = ?`,
+		 WHERE id = ? AND institution_id = ?`,
 		string(name),
 		startDate,
 		endDate,
 		id,
+		institution(ctx),
 	)
 	if err != nil {
 		return nil, err
 	}
+	forgetSemesters(ctx)
 
 	rows, err := result.RowsAffected()
 	if err != nil {
@@ -119,23 +182,58 @@ func UpdateSemester(id int, name Semester, startDate, endDate time.Time) (*model
 		return nil, errors.New("semester not found")
 	}
 
-	return &models.Semester{
-		ID:        id,
-		Name:      string(name),
-		StartDate: startDate,
-		EndDate:   endDate,
-	}, nil
+	return FindSemesterByID(ctx, id)
 }
 
-func DeleteSemester(id int) error {
+var ErrEnrollmentClosed = errors.New("enrollment for this semester is closed")
+
+// SetEnrollmentOpen opens or closes enrollment for a semester and reports
+// whether that changed anything.
+func SetEnrollmentOpen(ctx context.Context, id int, open bool) (bool, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	result, err := DB.ExecContext(ctx,
+		`UPDATE semester SET enrollment_open = ? WHERE id = ? AND institution_id = ? AND enrollment_open <> ?`,
+		open, id, institution(ctx), open,
+	)
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if rows > 0 {
+		forgetSemesters(ctx)
+		return true, nil
+	}
+
+	var exists int
+	if err := DB.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM semester WHERE id = ? AND institution_id = ?`, id, institution(ctx),
+	).Scan(&exists); err != nil {
+		return false, err
+	}
+	if exists == 0 {
+		return false, errors.New("semester not found")
+	}
+	return false, nil
+}
+
+func DeleteSemester(ctx context.Context, id int) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
 	if id <= 0 {
 		return errors.New("invalid semester id")
 	}
 
-	result, err := DB.Exec(`DELETE FROM semester WHERE id = ?`, id)
+	result, err := DB.ExecContext(ctx, `DELETE FROM semester WHERE id = ? AND institution_id = ?`, id, institution(ctx))
 	if err != nil {
 		return err
 	}
+	forgetSemesters(ctx)
 
 	rows, err := result.RowsAffected()
 	if err != nil {
@@ -0,0 +1,255 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// snapshotTables lists every table holding data, parents before children
+// so a snapshot can be loaded with foreign keys enforced. A migration that
+// adds a table must add it here too.
+var snapshotTables = []string{
+	"institution",
+	"user",
+	"semester",
+	"department",
+	"course",
+	"programme",
+	"programme_core_course",
+	"programme_elective",
+	"enrollment",
+	"offering",
+	"waitlist",
+	"grade",
+	"class_session",
+	"attendance",
+	"appeal",
+	"grade_change",
+	"user_token",
+	"password_history",
+	"evaluation_question",
+	"evaluation_submission",
+	"evaluation_response",
+	"evaluation_answer",
+}
+
+var (
+	ErrSnapshotVersion  = errors.New("snapshot was taken at a different schema version")
+	ErrDatabaseNotEmpty = errors.New("database already has users")
+)
+
+// Snapshot is a full copy of the data, independent of the database it
+// came from.
+type Snapshot struct {
+	SchemaVersion int             `json:"schema_version"`
+	TakenAt       time.Time       `json:"taken_at"`
+	Tables        []SnapshotTable `json:"tables"`
+}
+
+type SnapshotTable struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+}
+
+func schemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// ExportSnapshot writes every table as JSON, for every institution: it is
+// an operator's backup of the whole database, so unlike everything else in
+// this package it is not scoped to one. It reads in one transaction
+// so the copy is consistent, and is bounded only by ctx since a large
+// database takes longer than a single query is allowed.
+func ExportSnapshot(ctx context.Context, w io.Writer) error {
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	snap := Snapshot{SchemaVersion: schemaVersion(), TakenAt: time.Now().UTC()}
+	for _, name := range snapshotTables {
+		table, err := exportTable(ctx, tx, name)
+		if err != nil {
+			return fmt.Errorf("exporting %s: %w", name, err)
+		}
+		snap.Tables = append(snap.Tables, *table)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(snap)
+}
+
+func exportTable(ctx context.Context, tx *sql.Tx, name string) (*SnapshotTable, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT * FROM "+quoteIdent(name))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	table := &SnapshotTable{Name: name, Columns: columns, Rows: [][]any{}}
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		for i, v := range values {
+			// MySQL returns most columns as bytes.
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		table.Rows = append(table.Rows, values)
+	}
+	return table, rows.Err()
+}
+
+// ImportSnapshot loads a snapshot taken at the current schema version. The
+// database must not have any users yet unless replace is set, in which
+// case everything in it is deleted first. Either all of the snapshot is
+// loaded or nothing is. Like ExportSnapshot it covers every institution.
+func ImportSnapshot(ctx context.Context, r io.Reader, replace bool) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var snap Snapshot
+	if err := dec.Decode(&snap); err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+	if snap.SchemaVersion != schemaVersion() {
+		return fmt.Errorf("%w: snapshot %d, database %d", ErrSnapshotVersion, snap.SchemaVersion, schemaVersion())
+	}
+
+	tx, err := DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if !replace {
+		var users int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM user`).Scan(&users); err != nil {
+			return err
+		}
+		if users > 0 {
+			return ErrDatabaseNotEmpty
+		}
+	}
+	// Migrations seed some tables, so clear them all, children first.
+	for i := len(snapshotTables) - 1; i >= 0; i-- {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+quoteIdent(snapshotTables[i])); err != nil {
+			return fmt.Errorf("clearing %s: %w", snapshotTables[i], err)
+		}
+	}
+
+	known := map[string]bool{}
+	for _, name := range snapshotTables {
+		known[name] = true
+	}
+	for _, table := range snap.Tables {
+		if !known[table.Name] {
+			return fmt.Errorf("snapshot has unknown table %q", table.Name)
+		}
+		if err := importTable(ctx, tx, table); err != nil {
+			return fmt.Errorf("importing %s: %w", table.Name, err)
+		}
+	}
+	defer readCache.DeletePrefix("")
+	return tx.Commit()
+}
+
+func importTable(ctx context.Context, tx *sql.Tx, table SnapshotTable) error {
+	if len(table.Rows) == 0 {
+		return nil
+	}
+	timeColumns, err := timeColumns(ctx, tx, table.Name)
+	if err != nil {
+		return err
+	}
+
+	quoted := make([]string, len(table.Columns))
+	marks := make([]string, len(table.Columns))
+	for i, c := range table.Columns {
+		quoted[i] = quoteIdent(c)
+		marks[i] = "?"
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		quoteIdent(table.Name), strings.Join(quoted, ", "), strings.Join(marks, ", ")))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, row := range table.Rows {
+		if len(row) != len(table.Columns) {
+			return fmt.Errorf("row has %d values for %d columns", len(row), len(table.Columns))
+		}
+		args := make([]any, len(row))
+		for i, v := range row {
+			if args[i], err = snapshotValue(v, timeColumns[table.Columns[i]]); err != nil {
+				return fmt.Errorf("column %s: %w", table.Columns[i], err)
+			}
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// timeColumns reports which of the table's columns hold dates or times,
+// which JSON carries as strings.
+func timeColumns(ctx context.Context, tx *sql.Tx, table string) (map[string]bool, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT * FROM "+quoteIdent(table)+" WHERE 1 = 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	columns := map[string]bool{}
+	for _, t := range types {
+		switch strings.ToUpper(t.DatabaseTypeName()) {
+		case "DATE", "DATETIME", "TIMESTAMP":
+			columns[t.Name()] = true
+		}
+	}
+	return columns, rows.Err()
+}
+
+func snapshotValue(v any, isTime bool) (any, error) {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case string:
+		if isTime {
+			return time.Parse(time.RFC3339Nano, v)
+		}
+	}
+	return v, nil
+}
+
+func quoteIdent(name string) string {
+	return "`" + name + "`"
+}
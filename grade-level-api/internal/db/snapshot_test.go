@@ -0,0 +1,102 @@
+package db_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/dbtest"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	var gradeID int
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("export", func(t *testing.T) {
+		dbtest.Open(t)
+		ctx := t.Context()
+
+		lecturer, err := db.CreateUser(ctx, db.DB, "Ada", "ada@example.com", "correct-horse-battery", db.Lecturer)
+		require.NoError(t, err)
+		student, err := db.CreateUser(ctx, db.DB, "Grace", "grace@example.com", "correct-horse-battery", db.Student)
+		require.NoError(t, err)
+		require.NoError(t, db.MarkEmailVerified(ctx, student.ID, start))
+		course, err := db.CreateCourse(ctx, "Compilers", 400, 3, lecturer.ID)
+		require.NoError(t, err)
+		semester, err := db.CreateSemester(ctx, db.FirstSemster, start, start.AddDate(0, 4, 0))
+		require.NoError(t, err)
+		_, err = db.EnrollStudent(ctx, student.ID, course.ID, semester.ID)
+		require.NoError(t, err)
+		grade, err := db.SaveStudentGrade(ctx, student.ID, course.ID, semester.ID, 71.5)
+		require.NoError(t, err)
+		gradeID = grade.ID
+		_, err = db.PublishGrades(ctx, course.ID, semester.ID)
+		require.NoError(t, err)
+
+		require.NoError(t, db.ExportSnapshot(ctx, &buf))
+
+		// Every table but the migration bookkeeping is in the snapshot.
+		rows, err := db.DB.QueryContext(ctx,
+			`SELECT name FROM sqlite_master WHERE type = 'table'
+			AND name NOT IN ('schema_migrations', 'sqlite_sequence')`)
+		require.NoError(t, err)
+		defer rows.Close()
+		var tables []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			tables = append(tables, name)
+		}
+		var snap db.Snapshot
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &snap))
+		var exported []string
+		for _, table := range snap.Tables {
+			exported = append(exported, table.Name)
+		}
+		assert.ElementsMatch(t, tables, exported)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		dbtest.Open(t)
+		ctx := t.Context()
+
+		require.NoError(t, db.ImportSnapshot(ctx, bytes.NewReader(buf.Bytes()), false))
+
+		student, err := db.GetUserByEmail(ctx, "grace@example.com")
+		require.NoError(t, err)
+		require.NotNil(t, student.EmailVerifiedAt)
+		assert.True(t, start.Equal(*student.EmailVerifiedAt))
+
+		grade, err := db.FindStudentGrade(ctx, gradeID)
+		require.NoError(t, err)
+		assert.Equal(t, 71.5, grade.Score)
+		assert.Equal(t, "Compilers", grade.CourseName)
+		assert.NotNil(t, grade.PublishedAt)
+
+		semesters, err := db.ListSemesters(ctx)
+		require.NoError(t, err)
+		require.Len(t, semesters, 1)
+		assert.True(t, start.Equal(semesters[0].StartDate))
+
+		questions, err := db.ListEvaluationQuestions(ctx, true)
+		require.NoError(t, err)
+		assert.Len(t, questions, 5, "seeded questions are replaced, not duplicated")
+
+		// New rows continue after the imported IDs.
+		user, err := db.CreateUser(ctx, db.DB, "Alan", "alan@example.com", "correct-horse-battery", db.Student)
+		require.NoError(t, err)
+		assert.Greater(t, user.ID, student.ID)
+
+		err = db.ImportSnapshot(ctx, bytes.NewReader(buf.Bytes()), false)
+		assert.ErrorIs(t, err, db.ErrDatabaseNotEmpty)
+		require.NoError(t, db.ImportSnapshot(ctx, bytes.NewReader(buf.Bytes()), true))
+		_, err = db.GetUserByEmail(ctx, "alan@example.com")
+		assert.Error(t, err, "replace drops rows missing from the snapshot")
+	})
+}
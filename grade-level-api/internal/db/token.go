@@ -0,0 +1,128 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type TokenPurpose string
+
+const (
+	TokenVerifyEmail   TokenPurpose = "verify_email"
+	TokenPasswordReset TokenPurpose = "password_reset"
+)
+
+var ErrTokenInvalid = errors.New("invalid or expired token")
+
+type UserToken struct {
+	ID        int
+	UserID    int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+func CreateUserToken(ctx context.Context, userID int, purpose TokenPurpose, hash string, expiresAt, now time.Time) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx,
+		`INSERT INTO user_token (user_id, purpose, token_hash, expires_at, created_at, institution_id) VALUES (?, ?, ?, ?, ?, ?)`,
+		userID, string(purpose), hash, expiresAt, now, institution(ctx),
+	)
+	return err
+}
+
+// FindUsableToken looks up an unused, unexpired token by its hash.
+func FindUsableToken(ctx context.Context, purpose TokenPurpose, hash string, now time.Time) (*UserToken, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	t := &UserToken{}
+	var usedAt sql.NullTime
+	err := DB.QueryRowContext(ctx,
+		`SELECT id, user_id, expires_at, used_at FROM user_token WHERE token_hash = ? AND purpose = ? AND institution_id = ?`,
+		hash, string(purpose), institution(ctx),
+	).Scan(&t.ID, &t.UserID, &t.ExpiresAt, &usedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if usedAt.Valid || !now.Before(t.ExpiresAt) {
+		return nil, ErrTokenInvalid
+	}
+	return t, nil
+}
+
+// consumeToken marks a token used. It fails if another request got there
+// first, which is what makes tokens single-use.
+func consumeToken(ctx context.Context, tx *sql.Tx, tokenID int, now time.Time) error {
+	result, err := tx.ExecContext(ctx, `UPDATE user_token SET used_at = ? WHERE id = ? AND institution_id = ? AND used_at IS NULL`,
+		now, tokenID, institution(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n != 1 {
+		return ErrTokenInvalid
+	}
+	return nil
+}
+
+// VerifyEmail consumes a verification token and marks the address verified.
+func VerifyEmail(ctx context.Context, token *UserToken, now time.Time) error {
+	defer forgetUser(ctx, token.UserID)
+	return withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := consumeToken(ctx, tx, token.ID, now); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE user SET email_verified_at = ? WHERE id = ? AND institution_id = ? AND email_verified_at IS NULL`,
+			now, token.UserID, institution(ctx),
+		); err != nil {
+			return err
+		}
+		return nil
+	})
+}
+
+// ResetPassword consumes a reset token and sets the new password hash. It
+// also clears any login lockout, records the hash in the password history
+// and voids the user's other outstanding reset tokens. Receiving the email
+// proves ownership of the address, so it is marked verified too.
+func ResetPassword(ctx context.Context, token *UserToken, hash string, now time.Time) error {
+	defer forgetUser(ctx, token.UserID)
+	return withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if err := consumeToken(ctx, tx, token.ID, now); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE user SET password = ?, failed_login_attempts = 0, locked_until = NULL,
+				email_verified_at = COALESCE(email_verified_at, ?)
+			WHERE id = ? AND institution_id = ?`,
+			hash, now, token.UserID, institution(ctx),
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO password_history (user_id, password_hash, created_at, institution_id) VALUES (?, ?, ?, ?)`,
+			token.UserID, hash, now, institution(ctx),
+		); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE user_token SET used_at = ? WHERE user_id = ? AND institution_id = ? AND purpose = ? AND used_at IS NULL`,
+			now, token.UserID, institution(ctx), string(TokenPasswordReset),
+		); err != nil {
+			return err
+		}
+		return nil
+	})
+}
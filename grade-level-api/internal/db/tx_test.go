@@ -0,0 +1,141 @@
+package db_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/dbtest"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+func TestQueriesHonourCancellation(t *testing.T) {
+	dbtest.Open(t)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	_, err := db.ListCourses(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = db.EnrollStudent(ctx, 1, 1, 1)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSaveStudentGradeRequiresEnrollment(t *testing.T) {
+	dbtest.Open(t)
+	ctx := t.Context()
+
+	lecturer, err := db.CreateUser(ctx, db.DB, "Ada", "ada@example.com", "correct-horse-battery", db.Lecturer)
+	require.NoError(t, err)
+	student, err := db.CreateUser(ctx, db.DB, "Grace", "grace@example.com", "correct-horse-battery", db.Student)
+	require.NoError(t, err)
+	course, err := db.CreateCourse(ctx, "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	semester, err := db.CreateSemester(ctx, db.FirstSemster, start, start.AddDate(0, 4, 0))
+	require.NoError(t, err)
+
+	_, err = db.SaveStudentGrade(ctx, student.ID, course.ID, semester.ID, 70)
+	assert.ErrorIs(t, err, db.ErrEnrollmentNotFound)
+
+	_, err = db.EnrollStudent(ctx, student.ID, course.ID, semester.ID)
+	require.NoError(t, err)
+	_, err = db.EnrollStudent(ctx, student.ID, course.ID, semester.ID)
+	assert.Error(t, err, "duplicate enrollment")
+
+	grade, err := db.SaveStudentGrade(ctx, student.ID, course.ID, semester.ID, 70)
+	require.NoError(t, err)
+	assert.Equal(t, 70.0, grade.Score)
+
+	grade, err = db.SaveStudentGrade(ctx, student.ID, course.ID, semester.ID, 75)
+	require.NoError(t, err)
+	assert.Equal(t, 75.0, grade.Score)
+
+	_, err = db.PublishGrades(ctx, course.ID, semester.ID)
+	require.NoError(t, err)
+	_, err = db.SaveStudentGrade(ctx, student.ID, course.ID, semester.ID, 80)
+	assert.ErrorIs(t, err, db.ErrGradePublished)
+}
+
+func TestConcurrentEnrollmentRespectsCapacity(t *testing.T) {
+	dbtest.Open(t)
+	ctx := t.Context()
+
+	lecturer, err := db.CreateUser(ctx, db.DB, "Ada", "ada@example.com", "correct-horse-battery", db.Lecturer)
+	require.NoError(t, err)
+	course, err := db.CreateCourse(ctx, "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	semester, err := db.CreateSemester(ctx, db.FirstSemster, start, start.AddDate(0, 4, 0))
+	require.NoError(t, err)
+	capacity := 3
+	_, err = db.SetCapacity(ctx, course.ID, semester.ID, &capacity)
+	require.NoError(t, err)
+
+	students := make([]int, 10)
+	for i := range students {
+		s, err := db.CreateUser(ctx, db.DB, fmt.Sprintf("Student %d", i), fmt.Sprintf("s%d@example.com", i), "correct-horse-battery", db.Student)
+		require.NoError(t, err)
+		students[i] = s.ID
+	}
+
+	results := make([]*models.Enrollment, len(students))
+	var wg sync.WaitGroup
+	for i, id := range students {
+		wg.Go(func() {
+			e, err := db.EnrollStudent(ctx, id, course.ID, semester.ID)
+			assert.NoError(t, err)
+			results[i] = e
+		})
+	}
+	wg.Wait()
+
+	var enrolled []int
+	var positions []int
+	for _, e := range results {
+		if e.Status == string(db.Enrolled) {
+			enrolled = append(enrolled, e.StudentID)
+		} else {
+			positions = append(positions, e.Position)
+		}
+	}
+	assert.Len(t, enrolled, capacity)
+	assert.ElementsMatch(t, []int{1, 2, 3, 4, 5, 6, 7}, positions)
+
+	waitlist, err := db.ListWaitlist(ctx, course.ID, semester.ID)
+	require.NoError(t, err)
+	require.Len(t, waitlist, 7)
+
+	promoted, err := db.DropEnrollment(ctx, enrolled[0], course.ID, semester.ID)
+	require.NoError(t, err)
+	require.Len(t, promoted, 1)
+	assert.Equal(t, waitlist[0].StudentID, promoted[0].StudentID, "the first in line is promoted")
+
+	_, err = db.EnrollStudent(ctx, waitlist[1].StudentID, course.ID, semester.ID)
+	assert.ErrorIs(t, err, db.ErrAlreadyWaitlisted)
+	forced, err := db.ForceEnroll(ctx, waitlist[1].StudentID, course.ID, semester.ID)
+	require.NoError(t, err)
+	assert.Equal(t, string(db.Enrolled), forced.Status)
+
+	_, err = db.SaveStudentGrade(ctx, enrolled[1], course.ID, semester.ID, 70)
+	require.NoError(t, err)
+	_, err = db.DropEnrollment(ctx, enrolled[1], course.ID, semester.ID)
+	assert.ErrorIs(t, err, db.ErrEnrollmentGraded)
+
+	promoted, err = db.SetCapacity(ctx, course.ID, semester.ID, nil)
+	require.NoError(t, err)
+	assert.Len(t, promoted, 5, "lifting the limit promotes everyone still waiting")
+
+	offering, err := db.FindOffering(ctx, course.ID, semester.ID)
+	require.NoError(t, err)
+	assert.Nil(t, offering.Capacity)
+	assert.Equal(t, len(students)-1, offering.Enrolled)
+	assert.Zero(t, offering.Waitlisted)
+}
@@ -1,15 +1,17 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/falasefemi2/gradesystem/internal/auth"
 	"github.com/falasefemi2/gradesystem/internal/models"
 )
 
 type DBExecutor interface {
-	Exec(query string, args ...any) (sql.Result, error)
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
 type Role string
@@ -20,7 +22,15 @@ const (
 	Admin    Role = "admin"
 )
 
-func CreateUser(db DBExecutor, name, email, password string, role Role) (*models.User, error) {
+var (
+	ErrEmailTaken  = errors.New("email already exists")
+	ErrInvalidRole = errors.New("role must be student, lecturer or admin")
+)
+
+func CreateUser(ctx context.Context, db DBExecutor, name, email, password string, role Role) (*models.User, error) {
+	if role != Student && role != Lecturer && role != Admin {
+		return nil, ErrInvalidRole
+	}
 	if err := auth.ValidatePassword(password); err != nil {
 		return nil, err
 	}
@@ -28,9 +38,14 @@ func CreateUser(db DBExecutor, name, email, password string, role Role) (*models
 	if err != nil {
 		return nil, err
 	}
-	result, err := db.Exec("INSERT INTO user (name, email, password, role) VALUES (?, ?, ?, ?)", name, email, hash, string(role))
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+	result, err := db.ExecContext(ctx, "INSERT INTO user (name, email, password, role, institution_id) VALUES (?, ?, ?, ?, ?)", name, email, hash, string(role), institution(ctx))
+	if isDuplicateKey(err) {
+		return nil, ErrEmailTaken
+	}
 	if err != nil {
-		return nil, errors.New("email already exists or database error")
+		return nil, err
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
@@ -45,13 +60,14 @@ func CreateUser(db DBExecutor, name, email, password string, role Role) (*models
 	}, nil
 }
 
-func GetUserByEmail(email string) (*models.User, error) {
+const userColumns = `id, name, email, password, role, failed_login_attempts, locked_until, email_verified_at, department_id`
+
+func scanUser(row scanner) (*models.User, error) {
 	user := &models.User{}
 
-	err := DB.QueryRow(
-		"SELECT id, name, email, password, role FROM user WHERE email = ?",
-		email,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Role)
+	var lockedUntil, verifiedAt sql.NullTime
+	var departmentID sql.NullInt64
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Role, &user.FailedLoginAttempts, &lockedUntil, &verifiedAt, &departmentID)
 
 	if err == sql.ErrNoRows {
 		return nil, errors.New("user not found")
@@ -59,31 +75,93 @@ func GetUserByEmail(email string) (*models.User, error) {
 	if err != nil {
 		return nil, err
 	}
+	if lockedUntil.Valid {
+		user.LockedUntil = &lockedUntil.Time
+	}
+	if verifiedAt.Valid {
+		user.EmailVerifiedAt = &verifiedAt.Time
+	}
+	if departmentID.Valid {
+		id := int(departmentID.Int64)
+		user.DepartmentID = &id
+	}
 
 	return user, nil
 }
 
-func GetUserByID(id int) (*models.User, error) {
-	user := &models.User{}
+// GetUserByEmail is a variable so tests can stub out the lookup. It runs
+// on every authenticated request, so the user is cached.
+var GetUserByEmail = func(ctx context.Context, email string) (*models.User, error) {
+	if id, ok := readCache.Get(tenantKey(ctx, userEmailKey(email))); ok {
+		return GetUserByID(ctx, id.(int))
+	}
 
-	err := DB.QueryRow(
-		"SELECT id, name, email, password, role FROM user WHERE id = ?",
-		id,
-	).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Role)
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
 
-	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+	user, err := scanUser(DB.QueryRowContext(ctx, "SELECT "+userColumns+" FROM user WHERE email = ? AND institution_id = ?", email, institution(ctx)))
+	if err != nil {
+		return nil, err
+	}
+	readCache.Set(tenantKey(ctx, userEmailKey(email)), user.ID)
+	readCache.Set(tenantKey(ctx, userKey(user.ID)), cloneUser(user))
+	return user, nil
+}
+
+func GetUserByID(ctx context.Context, id int) (*models.User, error) {
+	return cached(ctx, userKey(id), cloneUser, func() (*models.User, error) {
+		ctx, cancel := queryContext(ctx)
+		defer cancel()
+
+		return scanUser(DB.QueryRowContext(ctx, "SELECT "+userColumns+" FROM user WHERE id = ? AND institution_id = ?", id, institution(ctx)))
+	})
+}
+
+// GetUsersByIDs looks up several users at once, keyed by ID. IDs with no
+// user are left out.
+func GetUsersByIDs(ctx context.Context, ids []int) (map[int]*models.User, error) {
+	users := map[int]*models.User{}
+	if len(ids) == 0 {
+		return users, nil
 	}
+
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	marks, args := inList(ids)
+	rows, err := DB.QueryContext(ctx, "SELECT "+userColumns+" FROM user WHERE institution_id = ? AND id IN ("+marks+")", append([]any{institution(ctx)}, args...)...)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users[user.ID] = user
+	}
+	return users, rows.Err()
+}
 
-	return user, nil
+func GetAllUsers(ctx context.Context) ([]models.User, error) {
+	return listUsers(ctx, "")
 }
 
-func GetAllUsers() ([]models.User, error) {
-	rows, err := DB.Query(
-		"SELECT id, name, email, role FROM user",
+func GetUsersByRole(ctx context.Context, role Role) ([]models.User, error) {
+	return listUsers(ctx, "AND role = ?", string(role))
+}
+
+// listUsers returns the institution's users matching filter, which
+// continues the WHERE clause, without their password hashes.
+func listUsers(ctx context.Context, filter string, args ...any) ([]models.User, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		"SELECT id, name, email, role, failed_login_attempts, locked_until, email_verified_at, department_id FROM user WHERE institution_id = ? "+filter,
+		append([]any{institution(ctx)}, args...)...,
 	)
 	if err != nil {
 		return nil, err
@@ -91,64 +169,243 @@ func GetAllUsers() ([]models.User, error) {
 	defer rows.Close()
 
 	var users []models.User
-
 	for rows.Next() {
 		var user models.User
+		var lockedUntil, verifiedAt sql.NullTime
+		var departmentID sql.NullInt64
 		if err := rows.Scan(
 			&user.ID,
 			&user.Name,
 			&user.Email,
 			&user.Role,
+			&user.FailedLoginAttempts,
+			&lockedUntil,
+			&verifiedAt,
+			&departmentID,
 		); err != nil {
 			return nil, err
 		}
+		if lockedUntil.Valid {
+			user.LockedUntil = &lockedUntil.Time
+		}
+		if verifiedAt.Valid {
+			user.EmailVerifiedAt = &verifiedAt.Time
+		}
+		if departmentID.Valid {
+			id := int(departmentID.Int64)
+			user.DepartmentID = &id
+		}
 		users = append(users, user)
 	}
+	return users, rows.Err()
+}
 
-	if err := rows.Err(); err != nil {
+func VerifyUser(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := GetUserByEmail(ctx, email)
+	if err != nil {
 		return nil, err
 	}
 
-	return users, nil
+	if err := auth.VerifyPassword(password, user.Password); err != nil {
+		return nil, errors.New("invalid email or password")
+	}
+
+	return user, nil
+}
+
+// RecordFailedLogin bumps the user's consecutive failed login count and
+// returns the new count.
+func RecordFailedLogin(ctx context.Context, userID int) (int, error) {
+	defer forgetUser(ctx, userID)
+	var attempts int
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE user SET failed_login_attempts = failed_login_attempts + 1 WHERE id = ? AND institution_id = ?`,
+			userID, institution(ctx),
+		); err != nil {
+			return err
+		}
+		return tx.QueryRowContext(ctx, `SELECT failed_login_attempts FROM user WHERE id = ? AND institution_id = ?`, userID, institution(ctx)).Scan(&attempts)
+	})
+	return attempts, err
 }
 
-func GetUsersByRole(role Role) ([]models.User, error) {
-	rows, err := DB.Query(
-		"SELECT id, name, email, role FROM user WHERE role = ?",
-		string(role),
+func LockUser(ctx context.Context, userID int, until time.Time) error {
+	defer forgetUser(ctx, userID)
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx, `UPDATE user SET locked_until = ? WHERE id = ? AND institution_id = ?`, until, userID, institution(ctx))
+	return err
+}
+
+// ResetLoginFailures clears the failure count and any lock, after a
+// successful login or when an admin unlocks the account.
+func ResetLoginFailures(ctx context.Context, userID int) error {
+	defer forgetUser(ctx, userID)
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	result, err := DB.ExecContext(ctx,
+		`UPDATE user SET failed_login_attempts = 0, locked_until = NULL WHERE id = ? AND institution_id = ?`,
+		userID, institution(ctx),
+	)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return errors.New("user not found")
+	}
+	return nil
+}
+
+func ListLockedUsers(ctx context.Context, now time.Time) ([]models.User, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT id, name, email, role, failed_login_attempts, locked_until
+		 FROM user WHERE locked_until > ? AND institution_id = ? ORDER BY locked_until DESC`,
+		now, institution(ctx),
 	)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var users []models.User
+	users := []models.User{}
 
 	for rows.Next() {
-		var user models.User
+		var (
+			user        models.User
+			lockedUntil time.Time
+		)
 		if err := rows.Scan(
 			&user.ID,
 			&user.Name,
 			&user.Email,
 			&user.Role,
+			&user.FailedLoginAttempts,
+			&lockedUntil,
 		); err != nil {
 			return nil, err
 		}
+		user.LockedUntil = &lockedUntil
 		users = append(users, user)
 	}
 
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
 	return users, nil
 }
 
-func VerifyUser(email, password string) (*models.User, error) {
-	user, err := GetUserByEmail(email)
+func MarkEmailVerified(ctx context.Context, userID int, at time.Time) error {
+	defer forgetUser(ctx, userID)
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx,
+		`UPDATE user SET email_verified_at = ? WHERE id = ? AND institution_id = ? AND email_verified_at IS NULL`,
+		at, userID, institution(ctx),
+	)
+	return err
+}
+
+// RegisterUser creates a user and records their first password in the
+// password history, in one transaction.
+func RegisterUser(ctx context.Context, name, email, password string, role Role) (*models.User, error) {
+	var user *models.User
+	err := withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		var err error
+		user, err = CreateUser(ctx, tx, name, email, password, role)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO password_history (user_id, password_hash, created_at, institution_id) VALUES (?, ?, ?, ?)`,
+			user.ID, user.Password, time.Now(), institution(ctx),
+		)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	return user, nil
+}
 
-	if err := auth.VerifyPassword(password, user.Password); err != nil {
-		return nil, errors.New("invalid email or password")
+func AddPasswordHistory(ctx context.Context, userID int, hash string, at time.Time) error {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	_, err := DB.ExecContext(ctx,
+		`INSERT INTO password_history (user_id, password_hash, created_at, institution_id) VALUES (?, ?, ?, ?)`,
+		userID, hash, at, institution(ctx),
+	)
+	return err
+}
+
+// RecentPasswordHashes returns the user's last n password hashes, newest
+// first.
+func RecentPasswordHashes(ctx context.Context, userID, n int) ([]string, error) {
+	ctx, cancel := queryContext(ctx)
+	defer cancel()
+
+	rows, err := DB.QueryContext(ctx,
+		`SELECT password_hash FROM password_history WHERE user_id = ? AND institution_id = ?
+		 ORDER BY created_at DESC, id DESC LIMIT ?`,
+		userID, institution(ctx), n,
+	)
+	if err != nil {
+		return nil, err
 	}
+	defer rows.Close()
 
-	return user, nil
+	var hashes []string
+	for rows.Next() {
+		var h string
+		if err := rows.Scan(&h); err != nil {
+			return nil, err
+		}
+		hashes = append(hashes, h)
+	}
+	return hashes, rows.Err()
+}
+
+// SetPassword replaces a user's password without a reset token, as when an
+// operator resets it. Like ResetPassword it clears any login lockout,
+// records the hash in the password history and voids outstanding reset
+// tokens.
+func SetPassword(ctx context.Context, userID int, hash string, now time.Time) error {
+	defer forgetUser(ctx, userID)
+	return withTx(ctx, func(ctx context.Context, tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx,
+			`UPDATE user SET password = ?, failed_login_attempts = 0, locked_until = NULL WHERE id = ? AND institution_id = ?`,
+			hash, userID, institution(ctx),
+		)
+		if err != nil {
+			return err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return errors.New("user not found")
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO password_history (user_id, password_hash, created_at, institution_id) VALUES (?, ?, ?, ?)`,
+			userID, hash, now, institution(ctx),
+		); err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			`UPDATE user_token SET used_at = ? WHERE user_id = ? AND institution_id = ? AND purpose = ? AND used_at IS NULL`,
+			now, userID, institution(ctx), string(TokenPasswordReset),
+		)
+		return err
+	})
 }
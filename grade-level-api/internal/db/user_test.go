@@ -1,9 +1,12 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"testing"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -13,7 +16,7 @@ type MockDB struct {
 	mock.Mock
 }
 
-func (m *MockDB) Exec(query string, args ...any) (sql.Result, error) {
+func (m *MockDB) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
 	ret := m.Called(query, args)
 	return ret.Get(0).(sql.Result), ret.Error(1)
 }
@@ -37,13 +40,13 @@ func TestCreateUser(t *testing.T) {
 	mockDB := new(MockDB)
 	result := new(ResultMock)
 
-	result.On("LastInertId").Return(int64(1), nil)
-	mockDB.On("Exec",
-		"INSERT INTO user (name, email, password, role) VALUES (?, ?, ?, ?)",
+	result.On("LastInsertId").Return(int64(1), nil)
+	mockDB.On("ExecContext",
+		"INSERT INTO user (name, email, password, role, institution_id) VALUES (?, ?, ?, ?, ?)",
 		mock.Anything, mock.Anything, mock.Anything, mock.Anything,
 	).Return(result, nil)
 
-	user, err := CreateUser(mockDB, "Femi", "femi@example.com", "secret123", Student)
+	user, err := CreateUser(context.Background(), mockDB, "Femi", "femi@example.com", "correct-horse-42", Student)
 
 	assert.NoError(t, err)
 	assert.Equal(t, "Femi", user.Name)
@@ -54,3 +57,31 @@ func TestCreateUser(t *testing.T) {
 	mockDB.AssertExpectations(t)
 	result.AssertExpectations(t)
 }
+
+func TestCreateUserErrors(t *testing.T) {
+	insert := "INSERT INTO user (name, email, password, role, institution_id) VALUES (?, ?, ?, ?, ?)"
+	connErr := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		role    Role
+		execErr error
+		want    error
+	}{
+		{name: "duplicate email", role: Student, execErr: &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, want: ErrEmailTaken},
+		{name: "other database error", role: Student, execErr: connErr, want: connErr},
+		{name: "unknown role", role: Role("dean"), want: ErrInvalidRole},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(MockDB)
+			if tt.execErr != nil {
+				mockDB.On("ExecContext", insert, mock.Anything).Return(new(ResultMock), tt.execErr)
+			}
+
+			_, err := CreateUser(context.Background(), mockDB, "Femi", "femi@example.com", "correct-horse-42", tt.role)
+			assert.ErrorIs(t, err, tt.want)
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
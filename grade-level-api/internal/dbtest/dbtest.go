@@ -0,0 +1,81 @@
+// Package dbtest opens a real, migrated database for tests.
+//
+// By default each test gets a fresh SQLite file in its temp directory. Set
+// TEST_MYSQL_DSN (e.g. "root:admin@tcp(localhost:3306)/gradingsystem_test")
+// to run against MySQL instead; its tables are emptied before every test.
+package dbtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+)
+
+// Open points db.DB at a clean database for the duration of t.
+func Open(t testing.TB) {
+	t.Helper()
+
+	if dsn := os.Getenv("TEST_MYSQL_DSN"); dsn != "" {
+		if err := db.Open(db.MySQL, dsn); err != nil {
+			t.Fatalf("opening mysql: %v", err)
+		}
+		truncate(t)
+	} else {
+		dsn := "file:" + filepath.Join(t.TempDir(), "test.db")
+		if err := db.Open(db.SQLite, dsn); err != nil {
+			t.Fatalf("opening sqlite: %v", err)
+		}
+	}
+
+	t.Cleanup(func() {
+		db.Close()
+		db.DB = nil
+	})
+}
+
+func truncate(t testing.TB) {
+	t.Helper()
+
+	rows, err := db.DB.Query(
+		`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = DATABASE() AND table_name <> 'schema_migrations'`,
+	)
+	if err != nil {
+		t.Fatalf("listing tables: %v", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatalf("listing tables: %v", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+
+	// A single connection, so the session variable applies to every TRUNCATE.
+	conn, err := db.DB.Conn(t.Context())
+	if err != nil {
+		t.Fatalf("truncating tables: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(t.Context(), `SET FOREIGN_KEY_CHECKS = 0`); err != nil {
+		t.Fatalf("truncating tables: %v", err)
+	}
+	for _, table := range tables {
+		if _, err := conn.ExecContext(t.Context(), "TRUNCATE TABLE `"+table+"`"); err != nil {
+			t.Fatalf("truncating %s: %v", table, err)
+		}
+	}
+	conn.ExecContext(t.Context(), `SET FOREIGN_KEY_CHECKS = 1`)
+
+	// Everything without a tenant in its context belongs to the default
+	// institution, which the migrations created.
+	if _, err := conn.ExecContext(t.Context(),
+		`INSERT INTO institution (id, slug, name) VALUES (1, 'default', 'Default institution')`,
+	); err != nil {
+		t.Fatalf("seeding the default institution: %v", err)
+	}
+}
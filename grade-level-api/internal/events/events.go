@@ -0,0 +1,180 @@
+// Package events fans notifications out to connected users. Hub keeps
+// subscribers in process; a Broker backed by an external message bus can
+// replace it through SetBroker without touching publishers or the stream
+// handler.
+package events
+
+import "sync"
+
+type Type string
+
+const (
+	GradesPublished     Type = "grades_published"
+	EnrollmentOpened    Type = "enrollment_opened"
+	AppealStatusChanged Type = "appeal_status_changed"
+	WaitlistPromoted    Type = "waitlist_promoted"
+)
+
+type Event struct {
+	// ID is assigned by the broker and increases with every event.
+	ID   uint64
+	Type Type
+	Data any
+	// InstitutionID is the institution the event happened in. Only its
+	// users receive it.
+	InstitutionID int
+	// UserIDs are the recipients; an empty list sends the event to every
+	// subscriber of the institution.
+	UserIDs []int
+}
+
+type Broker interface {
+	Publish(e Event)
+	// Subscribe returns the event stream of a user of the institution and
+	// a function that ends the subscription. The channel is closed when the
+	// broker shuts down.
+	Subscribe(institutionID, userID int) (<-chan Event, func())
+	Close()
+}
+
+// SubscriberBuffer is how many undelivered events a subscriber may have
+// before further events to it are dropped.
+const SubscriberBuffer = 16
+
+// Hub is an in-process Broker. Publishing never blocks: a subscriber that
+// falls SubscriberBuffer events behind misses the events after that.
+type Hub struct {
+	mu     sync.Mutex
+	lastID uint64
+	subs   map[subscriber]map[chan Event]struct{}
+	closed bool
+}
+
+type subscriber struct {
+	institutionID, userID int
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: map[subscriber]map[chan Event]struct{}{}}
+}
+
+func (h *Hub) Publish(e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.lastID++
+	e.ID = h.lastID
+
+	if len(e.UserIDs) == 0 {
+		for sub, chans := range h.subs {
+			if sub.institutionID == e.InstitutionID {
+				deliver(chans, e)
+			}
+		}
+		return
+	}
+	for _, id := range e.UserIDs {
+		deliver(h.subs[subscriber{e.InstitutionID, id}], e)
+	}
+}
+
+func deliver(chans map[chan Event]struct{}, e Event) {
+	for ch := range chans {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+func (h *Hub) Subscribe(institutionID, userID int) (<-chan Event, func()) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, SubscriberBuffer)
+	if h.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	sub := subscriber{institutionID, userID}
+	if h.subs[sub] == nil {
+		h.subs[sub] = map[chan Event]struct{}{}
+	}
+	h.subs[sub][ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			// Close already closed the channel if it ran first.
+			if _, ok := h.subs[sub][ch]; !ok {
+				return
+			}
+			delete(h.subs[sub], ch)
+			if len(h.subs[sub]) == 0 {
+				delete(h.subs, sub)
+			}
+			close(ch)
+		})
+	}
+}
+
+// Subscribers returns how many streams are open.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	n := 0
+	for _, chans := range h.subs {
+		n += len(chans)
+	}
+	return n
+}
+
+// Close ends every subscription, which lets open streams finish, and
+// drops anything published afterwards.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for _, chans := range h.subs {
+		for ch := range chans {
+			close(ch)
+		}
+	}
+	h.subs = map[subscriber]map[chan Event]struct{}{}
+}
+
+var (
+	mu     sync.RWMutex
+	broker Broker = NewHub()
+)
+
+// SetBroker replaces the broker used by Publish and Subscribe.
+func SetBroker(b Broker) {
+	mu.Lock()
+	defer mu.Unlock()
+	broker = b
+}
+
+func Default() Broker {
+	mu.RLock()
+	defer mu.RUnlock()
+	return broker
+}
+
+func Publish(e Event) {
+	Default().Publish(e)
+}
+
+func Subscribe(institutionID, userID int) (<-chan Event, func()) {
+	return Default().Subscribe(institutionID, userID)
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHubDeliversToRecipients(t *testing.T) {
+	hub := NewHub()
+	alice, stopAlice := hub.Subscribe(1, 1)
+	defer stopAlice()
+	bob, stopBob := hub.Subscribe(1, 2)
+	defer stopBob()
+
+	hub.Publish(Event{Type: GradesPublished, InstitutionID: 1, UserIDs: []int{1}})
+	hub.Publish(Event{Type: EnrollmentOpened, InstitutionID: 1})
+
+	e := <-alice
+	assert.Equal(t, GradesPublished, e.Type)
+	assert.Equal(t, uint64(1), e.ID)
+	e = <-alice
+	assert.Equal(t, EnrollmentOpened, e.Type)
+
+	e = <-bob
+	assert.Equal(t, EnrollmentOpened, e.Type, "bob only gets the broadcast")
+	assert.Equal(t, uint64(2), e.ID)
+	assert.Empty(t, bob)
+}
+
+func TestHubKeepsInstitutionsApart(t *testing.T) {
+	hub := NewHub()
+	home, stopHome := hub.Subscribe(1, 7)
+	defer stopHome()
+	other, stopOther := hub.Subscribe(2, 8)
+	defer stopOther()
+
+	hub.Publish(Event{Type: EnrollmentOpened, InstitutionID: 1})
+	hub.Publish(Event{Type: GradesPublished, InstitutionID: 1, UserIDs: []int{8}})
+
+	e := <-home
+	assert.Equal(t, EnrollmentOpened, e.Type)
+	assert.Empty(t, other, "neither a broadcast nor a stray user ID crosses institutions")
+}
+
+func TestHubDropsEventsForSlowSubscribers(t *testing.T) {
+	hub := NewHub()
+	stream, stop := hub.Subscribe(1, 1)
+	defer stop()
+
+	for range SubscriberBuffer + 5 {
+		hub.Publish(Event{Type: AppealStatusChanged, InstitutionID: 1, UserIDs: []int{1}})
+	}
+	assert.Len(t, stream, SubscriberBuffer)
+}
+
+func TestHubUnsubscribeAndClose(t *testing.T) {
+	hub := NewHub()
+	first, stopFirst := hub.Subscribe(1, 1)
+	second, stopSecond := hub.Subscribe(1, 1)
+	require.Equal(t, 2, hub.Subscribers())
+
+	stopFirst()
+	stopFirst()
+	_, ok := <-first
+	assert.False(t, ok, "unsubscribing closes the stream")
+	assert.Equal(t, 1, hub.Subscribers())
+
+	hub.Close()
+	_, ok = <-second
+	assert.False(t, ok, "closing the hub ends open streams")
+	stopSecond()
+
+	late, _ := hub.Subscribe(1, 1)
+	_, ok = <-late
+	assert.False(t, ok)
+	hub.Publish(Event{Type: GradesPublished})
+}
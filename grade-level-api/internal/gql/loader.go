@@ -0,0 +1,66 @@
+package gql
+
+import (
+	"context"
+	"sync"
+)
+
+// loader batches the lookups made while one query resolves. Resolvers ask
+// for a key and get back a thunk; graphql-go runs thunks only after every
+// resolver at the same depth has been called, so the first thunk fetches
+// all keys queued by its siblings in one query instead of one per parent.
+type loader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	queued  map[K]bool
+	values  map[K]V
+	errs    map[K]error
+}
+
+func newLoader[K comparable, V any](fetch func(context.Context, []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{
+		fetch:  fetch,
+		queued: map[K]bool{},
+		values: map[K]V{},
+		errs:   map[K]error{},
+	}
+}
+
+// load queues key and returns a thunk that resolves to its value, or to
+// nil when the fetch found nothing for it.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (any, error) {
+	l.mu.Lock()
+	if !l.queued[key] {
+		l.queued[key] = true
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (any, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if len(l.pending) > 0 {
+			keys := l.pending
+			l.pending = nil
+			values, err := l.fetch(ctx, keys)
+			for _, k := range keys {
+				if err != nil {
+					l.errs[k] = err
+				} else if v, ok := values[k]; ok {
+					l.values[k] = v
+				}
+			}
+		}
+		if err := l.errs[key]; err != nil {
+			return nil, err
+		}
+		v, ok := l.values[key]
+		if !ok {
+			return nil, nil
+		}
+		return v, nil
+	}
+}
@@ -0,0 +1,49 @@
+package gql
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderBatches(t *testing.T) {
+	var batches [][]int
+	l := newLoader(func(_ context.Context, keys []int) (map[int]string, error) {
+		batches = append(batches, keys)
+		out := map[int]string{}
+		for _, k := range keys {
+			if k != 3 {
+				out[k] = string(rune('a' + k))
+			}
+		}
+		return out, nil
+	})
+	ctx := context.Background()
+
+	thunks := []func() (any, error){l.load(ctx, 1), l.load(ctx, 2), l.load(ctx, 1), l.load(ctx, 3)}
+	var got []any
+	for _, th := range thunks {
+		v, err := th()
+		require.NoError(t, err)
+		got = append(got, v)
+	}
+	assert.Equal(t, []any{"b", "c", "b", nil}, got)
+	assert.Equal(t, [][]int{{1, 2, 3}}, batches)
+
+	// Keys already fetched are served from the cache.
+	v, err := l.load(ctx, 2)()
+	require.NoError(t, err)
+	assert.Equal(t, "c", v)
+	assert.Len(t, batches, 1)
+}
+
+func TestLoaderError(t *testing.T) {
+	boom := errors.New("boom")
+	l := newLoader(func(context.Context, []int) (map[int]string, error) { return nil, boom })
+
+	_, err := l.load(context.Background(), 1)()
+	assert.ErrorIs(t, err, boom)
+}
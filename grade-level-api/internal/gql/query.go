@@ -0,0 +1,196 @@
+package gql
+
+import (
+	"errors"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+func idArg(required bool) *graphql.ArgumentConfig {
+	if required {
+		return &graphql.ArgumentConfig{Type: nonNullInt}
+	}
+	return &graphql.ArgumentConfig{Type: graphql.Int}
+}
+
+func userPointers(users []models.User) []*models.User {
+	out := make([]*models.User, len(users))
+	for i := range users {
+		out[i] = &users[i]
+	}
+	return out
+}
+
+func queryType() *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return viewer(p)
+				},
+			},
+
+			// Admins may look anyone up; everyone else only themselves.
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{"id": idArg(true)},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					me, err := viewer(p)
+					if err != nil {
+						return nil, err
+					}
+					id := p.Args["id"].(int)
+					if id != me.ID && me.Role != string(db.Admin) {
+						return nil, ErrForbidden
+					}
+					return loadersFrom(p.Context).users.load(p.Context, id), nil
+				},
+			},
+
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+				Args: graphql.FieldConfigArgument{"role": &graphql.ArgumentConfig{Type: graphql.String}},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if _, err := requireRole(p, db.Admin); err != nil {
+						return nil, err
+					}
+					role, _ := p.Args["role"].(string)
+					var users []models.User
+					var err error
+					switch db.Role(role) {
+					case "":
+						users, err = db.GetAllUsers(p.Context)
+					case db.Admin, db.Lecturer, db.Student:
+						users, err = db.GetUsersByRole(p.Context, db.Role(role))
+					default:
+						return nil, errors.New("invalid role")
+					}
+					if err != nil {
+						return nil, err
+					}
+					return userPointers(users), nil
+				},
+			},
+
+			"semesters": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(semesterType))),
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if _, err := viewer(p); err != nil {
+						return nil, err
+					}
+					semesters, err := db.ListSemesters(p.Context)
+					if err != nil {
+						return nil, err
+					}
+					out := make([]*models.Semester, len(semesters))
+					for i := range semesters {
+						out[i] = &semesters[i]
+					}
+					return out, nil
+				},
+			},
+
+			"semester": &graphql.Field{
+				Type: semesterType,
+				Args: graphql.FieldConfigArgument{"id": idArg(true)},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if _, err := requireRole(p, db.Admin); err != nil {
+						return nil, err
+					}
+					return loadersFrom(p.Context).semesters.load(p.Context, p.Args["id"].(int)), nil
+				},
+			},
+
+			// Same rules as GET /courses: lecturers see their own courses,
+			// students pick a level and admins may list everything.
+			"courses": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(courseType))),
+				Args: graphql.FieldConfigArgument{"level": idArg(false)},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					me, err := viewer(p)
+					if err != nil {
+						return nil, err
+					}
+					level, hasLevel := p.Args["level"].(int)
+					switch {
+					case me.Role == string(db.Lecturer):
+						return db.FindCoursesByLecturerID(p.Context, me.ID)
+					case hasLevel:
+						if level <= 0 {
+							return nil, errors.New("invalid level")
+						}
+						return db.FindCoursesByLevel(p.Context, level)
+					case me.Role == string(db.Admin):
+						return db.ListCourses(p.Context)
+					}
+					return nil, errors.New("level is required")
+				},
+			},
+
+			"course": &graphql.Field{
+				Type: courseType,
+				Args: graphql.FieldConfigArgument{"id": idArg(true)},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					if _, err := requireRole(p, db.Admin, db.Lecturer); err != nil {
+						return nil, err
+					}
+					return loadersFrom(p.Context).courses.load(p.Context, p.Args["id"].(int)), nil
+				},
+			},
+
+			// Students get their own published grades, optionally narrowed
+			// to a course or semester. Staff read one offering and, like
+			// GET /courses/{id}/grades, lecturers only their own.
+			"grades": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gradeType))),
+				Args: graphql.FieldConfigArgument{
+					"courseId":   idArg(false),
+					"semesterId": idArg(false),
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					me, err := viewer(p)
+					if err != nil {
+						return nil, err
+					}
+					courseID, hasCourse := p.Args["courseId"].(int)
+					semesterID, hasSemester := p.Args["semesterId"].(int)
+
+					if me.Role == string(db.Student) {
+						grades, err := db.ListPublishedGradesForStudent(p.Context, me.ID)
+						if err != nil {
+							return nil, err
+						}
+						var out []*models.StudentGrade
+						for _, g := range gradePointers(grades) {
+							if (!hasCourse || g.CourseID == courseID) && (!hasSemester || g.SemesterID == semesterID) {
+								out = append(out, g)
+							}
+						}
+						return out, nil
+					}
+
+					if !hasCourse || !hasSemester {
+						return nil, errors.New("courseId and semesterId are required")
+					}
+					course, err := db.FindCourseByID(p.Context, courseID)
+					if err != nil {
+						return nil, err
+					}
+					if me.Role != string(db.Admin) && course.LecturerID != me.ID {
+						return nil, errors.New("you do not teach this course")
+					}
+					grades, err := db.ListGradesForOffering(p.Context, course.ID, semesterID)
+					if err != nil {
+						return nil, err
+					}
+					return gradePointers(grades), nil
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,228 @@
+// Package gql serves a read-only GraphQL view of users, courses, semesters
+// and grades. Root fields apply the same role rules as the REST routes
+// they mirror, and nested lookups are batched per query.
+package gql
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+var (
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+)
+
+type loaders struct {
+	users           *loader[int, *models.User]
+	courses         *loader[int, *models.Course]
+	semesters       *loader[int, *models.Semester]
+	lecturerCourses *loader[int, []*models.Course]
+	studentGrades   *loader[int, []*models.StudentGrade]
+}
+
+type loadersKey struct{}
+
+func newLoaders() *loaders {
+	return &loaders{
+		users:     newLoader(db.GetUsersByIDs),
+		courses:   newLoader(db.FindCoursesByIDs),
+		semesters: newLoader(db.FindSemestersByIDs),
+		lecturerCourses: newLoader(func(ctx context.Context, ids []int) (map[int][]*models.Course, error) {
+			courses, err := db.FindCoursesByLecturerIDs(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range ids {
+				if courses[id] == nil {
+					courses[id] = []*models.Course{}
+				}
+			}
+			return courses, nil
+		}),
+		studentGrades: newLoader(func(ctx context.Context, ids []int) (map[int][]*models.StudentGrade, error) {
+			grades, err := db.ListPublishedGradesForStudents(ctx, ids)
+			if err != nil {
+				return nil, err
+			}
+			out := make(map[int][]*models.StudentGrade, len(ids))
+			for _, id := range ids {
+				out[id] = gradePointers(grades[id])
+			}
+			return out, nil
+		}),
+	}
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// Execute runs a query on behalf of the user in ctx.
+func Execute(ctx context.Context, query string, variables map[string]any, operationName string) *graphql.Result {
+	ctx = context.WithValue(ctx, loadersKey{}, newLoaders())
+	return graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  query,
+		VariableValues: variables,
+		OperationName:  operationName,
+		Context:        ctx,
+	})
+}
+
+func viewer(p graphql.ResolveParams) (*models.User, error) {
+	user, err := middleware.GetUserFromContext(p.Context)
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	return user, nil
+}
+
+func requireRole(p graphql.ResolveParams, roles ...db.Role) (*models.User, error) {
+	user, err := viewer(p)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(roles, db.Role(user.Role)) {
+		return nil, ErrForbidden
+	}
+	return user, nil
+}
+
+// prop resolves a field from a struct the parent resolver returned.
+func prop[T any](typ graphql.Output, get func(*T) any) *graphql.Field {
+	return &graphql.Field{
+		Type: typ,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return get(p.Source.(*T)), nil
+		},
+	}
+}
+
+func gradePointers(grades []models.StudentGrade) []*models.StudentGrade {
+	out := make([]*models.StudentGrade, len(grades))
+	for i := range grades {
+		out[i] = &grades[i]
+	}
+	return out
+}
+
+var (
+	nonNullInt    = graphql.NewNonNull(graphql.Int)
+	nonNullString = graphql.NewNonNull(graphql.String)
+)
+
+var userType, courseType, semesterType, gradeType *graphql.Object
+
+func init() {
+	userType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":    prop(nonNullInt, func(u *models.User) any { return u.ID }),
+				"name":  prop(nonNullString, func(u *models.User) any { return u.Name }),
+				"email": prop(nonNullString, func(u *models.User) any { return u.Email }),
+				"role":  prop(nonNullString, func(u *models.User) any { return u.Role }),
+				"emailVerified": prop(graphql.NewNonNull(graphql.Boolean), func(u *models.User) any {
+					return u.EmailVerifiedAt != nil
+				}),
+				"courses": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(courseType))),
+					Description: "Courses the user teaches.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return loadersFrom(p.Context).lecturerCourses.load(p.Context, p.Source.(*models.User).ID), nil
+					},
+				},
+				"grades": &graphql.Field{
+					Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(gradeType))),
+					Description: "Published grades; visible to the student and to admins.",
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						me, err := viewer(p)
+						if err != nil {
+							return nil, err
+						}
+						student := p.Source.(*models.User)
+						if me.ID != student.ID && me.Role != string(db.Admin) {
+							return nil, ErrForbidden
+						}
+						return loadersFrom(p.Context).studentGrades.load(p.Context, student.ID), nil
+					},
+				},
+			}
+		}),
+	})
+
+	courseType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Course",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":           prop(nonNullInt, func(c *models.Course) any { return c.ID }),
+				"name":         prop(nonNullString, func(c *models.Course) any { return c.Name }),
+				"level":        prop(nonNullInt, func(c *models.Course) any { return c.Level }),
+				"units":        prop(nonNullInt, func(c *models.Course) any { return c.Units }),
+				"departmentId": prop(graphql.Int, func(c *models.Course) any { return c.DepartmentID }),
+				"lecturer": &graphql.Field{
+					Type: userType,
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return loadersFrom(p.Context).users.load(p.Context, p.Source.(*models.Course).LecturerID), nil
+					},
+				},
+			}
+		}),
+	})
+
+	semesterType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Semester",
+		Fields: graphql.Fields{
+			"id":             prop(nonNullInt, func(s *models.Semester) any { return s.ID }),
+			"name":           prop(nonNullString, func(s *models.Semester) any { return s.Name }),
+			"startDate":      prop(graphql.NewNonNull(graphql.DateTime), func(s *models.Semester) any { return s.StartDate }),
+			"endDate":        prop(graphql.NewNonNull(graphql.DateTime), func(s *models.Semester) any { return s.EndDate }),
+			"enrollmentOpen": prop(graphql.NewNonNull(graphql.Boolean), func(s *models.Semester) any { return s.EnrollmentOpen }),
+		},
+	})
+
+	gradeType = graphql.NewObject(graphql.ObjectConfig{
+		Name: "Grade",
+		Fields: graphql.FieldsThunk(func() graphql.Fields {
+			return graphql.Fields{
+				"id":          prop(nonNullInt, func(g *models.StudentGrade) any { return g.GradeID }),
+				"score":       prop(graphql.NewNonNull(graphql.Float), func(g *models.StudentGrade) any { return g.Score }),
+				"publishedAt": prop(graphql.DateTime, func(g *models.StudentGrade) any { return g.PublishedAt }),
+				"course": &graphql.Field{
+					Type: graphql.NewNonNull(courseType),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return loadersFrom(p.Context).courses.load(p.Context, p.Source.(*models.StudentGrade).CourseID), nil
+					},
+				},
+				"semester": &graphql.Field{
+					Type: graphql.NewNonNull(semesterType),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return loadersFrom(p.Context).semesters.load(p.Context, p.Source.(*models.StudentGrade).SemesterID), nil
+					},
+				},
+				"student": &graphql.Field{
+					Type: graphql.NewNonNull(userType),
+					Resolve: func(p graphql.ResolveParams) (any, error) {
+						return loadersFrom(p.Context).users.load(p.Context, p.Source.(*models.StudentGrade).StudentID), nil
+					},
+				},
+			}
+		}),
+	})
+
+	var err error
+	schema, err = graphql.NewSchema(graphql.SchemaConfig{Query: queryType()})
+	if err != nil {
+		panic(err)
+	}
+}
+
+var schema graphql.Schema
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/auth"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/internal/validate"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type TokenRequest struct {
+	Token string `json:"token" validate:"required,max=200"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required,max=200"`
+	Password string `json:"password" validate:"required"`
+}
+
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req TokenRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if err := service.VerifyEmail(r.Context(), req.Token); err != nil {
+		writeTokenError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "email verified"})
+}
+
+// ResendVerification and ForgotPassword answer the same way whether or not
+// the address is registered, and share the per-IP login limit.
+func ResendVerification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !allowByIP(w, r) {
+		return
+	}
+
+	var req EmailRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if err := service.ResendVerification(r.Context(), req.Email); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "could not send verification email")
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "if the account exists and is unverified, a verification email has been sent",
+	})
+}
+
+func ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !allowByIP(w, r) {
+		return
+	}
+
+	var req EmailRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	if err := service.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "could not send reset email")
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "if the account exists, a password reset email has been sent",
+	})
+}
+
+func ResetPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req ResetPasswordRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		writeValidationError(w, validate.Errors{{Field: "password", Message: err.Error()}})
+		return
+	}
+
+	err := service.ResetPassword(r.Context(), req.Token, req.Password)
+	if errors.Is(err, service.ErrPasswordReused) {
+		writeValidationError(w, validate.Errors{{Field: "password", Message: err.Error()}})
+		return
+	}
+	if err != nil {
+		writeTokenError(w, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "password updated"})
+}
+
+func allowByIP(w http.ResponseWriter, r *http.Request) bool {
+	if loginIPLimiter == nil {
+		return true
+	}
+	if ok, retryAfter := loginIPLimiter.Allow(clientIP(r)); !ok {
+		writeTooManyRequests(w, retryAfter, "too many requests, try again later")
+		return false
+	}
+	return true
+}
+
+func writeTokenError(w http.ResponseWriter, err error) {
+	if errors.Is(err, db.ErrTokenInvalid) {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.WriteError(w, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type FileAppealRequest struct {
+	GradeID int    `json:"grade_id" validate:"required,min=1"`
+	Reason  string `json:"reason" validate:"required,max=2000"`
+}
+
+type AppealDecisionRequest struct {
+	Decision string   `json:"decision" validate:"required,oneof=upheld rejected"`
+	Score    *float64 `json:"score" validate:"min=0,max=100"`
+	Note     string   `json:"note" validate:"max=2000"`
+}
+
+// AppealsHandler lets students file appeals and lists appeals for the
+// caller: students see their own, lecturers those on courses they teach and
+// admins every appeal. All of them can filter with ?status=.
+func AppealsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if user.Role != string(db.Student) {
+			utils.WriteError(w, http.StatusForbidden, "student access required")
+			return
+		}
+
+		var req FileAppealRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		appeal, err := service.FileAppeal(r.Context(), user.ID, req.GradeID, req.Reason)
+		if err != nil {
+			writeAppealError(w, r, err)
+			return
+		}
+		utils.WriteJSON(w, http.StatusCreated, appeal)
+
+	case http.MethodGet:
+		filter := db.AppealFilter{Status: db.AppealStatus(r.URL.Query().Get("status"))}
+		switch db.Role(user.Role) {
+		case db.Student:
+			filter.StudentID = user.ID
+		case db.Lecturer:
+			filter.LecturerID = user.ID
+		}
+
+		appeals, err := db.ListAppeals(r.Context(), filter)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, appeals)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// ReviewAppeal is the lecturer's step: recommend upholding or rejecting a
+// pending appeal before it goes to an admin.
+func ReviewAppeal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	appealID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid appeal id")
+		return
+	}
+
+	var req AppealDecisionRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	appeal, err := service.ReviewAppeal(r.Context(), user.ID, appealID, db.AppealStatus(req.Decision), req.Score, req.Note)
+	if err != nil {
+		writeAppealError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, appeal)
+}
+
+// DecideAppeal is the admin's final step. Upholding an appeal adjusts the
+// grade and records the change in its history.
+func DecideAppeal(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	appealID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid appeal id")
+		return
+	}
+
+	var req AppealDecisionRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	appeal, err := service.DecideAppeal(r.Context(), user.ID, appealID, db.AppealStatus(req.Decision), req.Score, req.Note)
+	if err != nil {
+		writeAppealError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, appeal)
+}
+
+func writeAppealError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, db.ErrAppealNotFound), errors.Is(err, db.ErrGradeNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, service.ErrNotYourAppeal):
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+	case errors.Is(err, service.ErrGradeNotPublished), errors.Is(err, service.ErrAppealWindowClosed),
+		errors.Is(err, db.ErrAppealInProgress), errors.Is(err, db.ErrAppealNotPending), errors.Is(err, db.ErrAppealNotReviewed):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrAppealReasonRequired), errors.Is(err, service.ErrInvalidDecision),
+		errors.Is(err, service.ErrScoreOutOfRange), errors.Is(err, service.ErrProposedScoreRequired),
+		errors.Is(err, service.ErrScoreRequired):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "processing appeal", slog.Any("error", err))
+		utils.WriteError(w, http.StatusInternalServerError, "could not process appeal")
+	}
+}
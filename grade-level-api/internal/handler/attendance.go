@@ -0,0 +1,304 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type CreateSessionRequest struct {
+	SemesterID   int    `json:"semester_id" validate:"required,min=1"`
+	Topic        string `json:"topic" validate:"max=255"`
+	HeldAt       string `json:"held_at" validate:"datetime"`
+	GenerateCode bool   `json:"generate_code"`
+}
+
+type MarkAttendanceRequest struct {
+	StudentIDs []int `json:"student_ids" validate:"required,min=1"`
+}
+
+type CheckInRequest struct {
+	Code string `json:"code" validate:"required,max=12"`
+}
+
+// ClassSessionsHandler lets a lecturer open a class session for one of
+// their courses, optionally with a short-lived code students check in with.
+func ClassSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	course := courseForStaff(w, r, user, courseID)
+	if course == nil {
+		return
+	}
+
+	var req CreateSessionRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if _, err := db.FindSemesterByID(r.Context(), req.SemesterID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	heldAt := time.Now()
+	if req.HeldAt != "" {
+		heldAt, _ = time.Parse(time.RFC3339, req.HeldAt)
+	}
+
+	var (
+		code      string
+		expiresAt *time.Time
+	)
+	if req.GenerateCode {
+		code, err = service.NewSessionCode()
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, "could not generate session code")
+			return
+		}
+		exp := time.Now().Add(config.Get().AttendanceCodeTTL)
+		expiresAt = &exp
+	}
+
+	session, err := db.CreateClassSession(r.Context(), course.ID, req.SemesterID, req.Topic, heldAt, code, expiresAt)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, session)
+}
+
+// MarkAttendance records attendance manually for a list of students.
+// Students already marked present for the session are left untouched.
+func MarkAttendance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	sessionID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid session id")
+		return
+	}
+	session, err := db.FindClassSessionByID(r.Context(), sessionID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if courseForStaff(w, r, user, session.CourseID) == nil {
+		return
+	}
+
+	var req MarkAttendanceRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	for _, studentID := range req.StudentIDs {
+		if _, err := db.FindEnrollment(r.Context(), studentID, session.CourseID, session.SemesterID); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("student %d is not enrolled in this course", studentID))
+			return
+		}
+	}
+
+	records, err := db.RecordAttendanceBatch(r.Context(), session.ID, req.StudentIDs, db.AttendanceManual)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, records)
+}
+
+func CheckIn(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	var req CheckInRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+
+	session, err := db.FindClassSessionByCode(r.Context(), code)
+	if err != nil || session.CodeExpiresAt == nil || time.Now().After(*session.CodeExpiresAt) {
+		utils.WriteError(w, http.StatusBadRequest, "invalid or expired session code")
+		return
+	}
+
+	if _, err := db.FindEnrollment(r.Context(), user.ID, session.CourseID, session.SemesterID); err != nil {
+		utils.WriteError(w, http.StatusForbidden, service.ErrNotEnrolled.Error())
+		return
+	}
+
+	record, err := db.RecordAttendance(r.Context(), session.ID, user.ID, db.AttendanceCode)
+	if errors.Is(err, db.ErrAttendanceRecorded) {
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, record)
+}
+
+// CourseAttendanceHandler returns attendance percentages and exam
+// eligibility for a course offering. Students only see their own record.
+func CourseAttendanceHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	semesterID, err := queryID(r, "semester_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if user.Role == string(db.Student) {
+		summary, err := service.StudentAttendanceSummary(r.Context(), user.ID, courseID, semesterID)
+		if err == service.ErrNotEnrolled {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, summary)
+		return
+	}
+
+	if courseForStaff(w, r, user, courseID) == nil {
+		return
+	}
+	summaries, err := service.AttendanceSummaries(r.Context(), courseID, semesterID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, summaries)
+}
+
+func AttendanceReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	semesterID, err := queryID(r, "semester_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	course := courseForStaff(w, r, user, courseID)
+	if course == nil {
+		return
+	}
+
+	summaries, err := service.AttendanceSummaries(r.Context(), courseID, semesterID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var body bytes.Buffer
+	if err := writeAttendanceCSV(&body, summaries); err != nil {
+		slog.ErrorContext(r.Context(), "rendering attendance report", slog.Any("error", err))
+		utils.WriteError(w, http.StatusInternalServerError, "could not render attendance report")
+		return
+	}
+
+	filename := fmt.Sprintf("attendance-course-%d-semester-%d.csv", course.ID, semesterID)
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body.Bytes()); err != nil {
+		slog.ErrorContext(r.Context(), "writing attendance report", slog.Any("error", err))
+	}
+}
+
+func writeAttendanceCSV(buf *bytes.Buffer, summaries []models.AttendanceSummary) error {
+	cw := csv.NewWriter(buf)
+	if err := cw.Write([]string{"student_id", "student_name", "attended", "total_sessions", "percentage", "exam_eligible"}); err != nil {
+		return err
+	}
+	for _, s := range summaries {
+		if err := cw.Write([]string{
+			strconv.Itoa(s.StudentID),
+			s.StudentName,
+			strconv.Itoa(s.Attended),
+			strconv.Itoa(s.Total),
+			strconv.FormatFloat(s.Percentage, 'f', 2, 64),
+			strconv.FormatBool(s.ExamEligible),
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
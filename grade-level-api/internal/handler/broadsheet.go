@@ -0,0 +1,118 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/internal/xlsx"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+// Broadsheet exports the semester result sheet of a level as CSV (the
+// default) or XLSX, chosen with ?format=.
+func Broadsheet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	level, err := queryID(r, "level")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	semesterID, err := queryID(r, "semester_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "xlsx" {
+		utils.WriteError(w, http.StatusBadRequest, "format must be csv or xlsx")
+		return
+	}
+
+	sheet, err := service.BuildBroadsheet(r.Context(), level, semesterID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	table := broadsheetTable(sheet)
+	filename := fmt.Sprintf("broadsheet-level-%d-semester-%d.%s", level, semesterID, format)
+
+	// Build the file before writing headers so a failure can still be
+	// reported as an error response.
+	var body bytes.Buffer
+	contentType := "text/csv"
+	if format == "xlsx" {
+		contentType = xlsx.ContentType
+		err = xlsx.Write(&body, fmt.Sprintf("Level %d %s", level, sheet.SemesterName), table)
+	} else {
+		err = writeCSV(&body, table)
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body.Bytes())
+}
+
+// broadsheetTable lays a broadsheet out as rows of cells: a header, then
+// one row per student with a score and grade column for every course.
+func broadsheetTable(sheet *models.Broadsheet) [][]any {
+	header := []any{"student_id", "student_name"}
+	for _, c := range sheet.Courses {
+		label := fmt.Sprintf("%s (%d units)", c.Name, c.Units)
+		header = append(header, label+" score", label+" grade")
+	}
+	header = append(header, "units", "gpa", "cgpa", "remark")
+
+	table := [][]any{header}
+	for _, row := range sheet.Rows {
+		cells := []any{row.StudentID, row.StudentName}
+		for _, res := range row.Results {
+			if res.Score == nil {
+				cells = append(cells, nil, nil)
+				continue
+			}
+			cells = append(cells, *res.Score, res.Grade)
+		}
+		cells = append(cells, row.Units, row.GPA, row.CGPA, row.Remark)
+		table = append(table, cells)
+	}
+	return table
+}
+
+func writeCSV(buf *bytes.Buffer, table [][]any) error {
+	cw := csv.NewWriter(buf)
+	for _, row := range table {
+		record := make([]string, len(row))
+		for i, cell := range row {
+			switch v := cell.(type) {
+			case string:
+				record[i] = v
+			case int:
+				record[i] = strconv.Itoa(v)
+			case float64:
+				record[i] = strconv.FormatFloat(v, 'f', 2, 64)
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
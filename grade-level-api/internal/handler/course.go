@@ -1,10 +1,8 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"github.com/falasefemi2/gradesystem/internal/db"
 	"github.com/falasefemi2/gradesystem/internal/middleware"
@@ -28,17 +26,11 @@ func CoursesHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var req CreateCourseRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
-		if req.Name == "" || req.Level <= 0 {
-			utils.WriteError(w, http.StatusBadRequest, "name and level are required")
-			return
-		}
-
-		course, err := db.CreateCourse(req.Name, req.Level, user.ID)
+		course, err := db.CreateCourse(r.Context(), req.Name, req.Level, req.units(), user.ID)
 		if err != nil {
 			utils.WriteError(w, http.StatusBadRequest, err.Error())
 			return
@@ -52,7 +44,7 @@ func CoursesHandler(w http.ResponseWriter, r *http.Request) {
 
 		// Lecturer → only their courses
 		if user.Role == string(db.Lecturer) {
-			courses, err := db.FindCoursesByLecturerID(user.ID)
+			courses, err := db.FindCoursesByLecturerID(r.Context(), user.ID)
 			if err != nil {
 				utils.WriteError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -69,7 +61,7 @@ func CoursesHandler(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			courses, err := db.FindCoursesByLevel(level)
+			courses, err := db.FindCoursesByLevel(r.Context(), level)
 			if err != nil {
 				utils.WriteError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -80,7 +72,7 @@ func CoursesHandler(w http.ResponseWriter, r *http.Request) {
 
 		// Admin → all courses
 		if user.Role == string(db.Admin) {
-			courses, err := db.ListCourses()
+			courses, err := db.ListCourses(r.Context())
 			if err != nil {
 				utils.WriteError(w, http.StatusInternalServerError, err.Error())
 				return
@@ -103,9 +95,8 @@ func CourseByIDHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := strings.TrimPrefix(r.URL.Path, "/courses/")
-	id, err := strconv.Atoi(idStr)
-	if err != nil || id <= 0 {
+	id, err := pathID(r, "id")
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
 		return
 	}
@@ -113,7 +104,7 @@ func CourseByIDHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 
 	case http.MethodGet:
-		course, err := db.FindCourseByID(id)
+		course, err := db.FindCourseByID(r.Context(), id)
 		if err != nil {
 			utils.WriteError(w, http.StatusNotFound, err.Error())
 			return
@@ -127,12 +118,11 @@ func CourseByIDHandler(w http.ResponseWriter, r *http.Request) {
 		}
 
 		var req CreateCourseRequest
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		if !decodeRequest(w, r, &req) {
 			return
 		}
 
-		course, err := db.UpdateCourse(id, req.Name, req.Level, user.ID)
+		course, err := db.UpdateCourse(r.Context(), id, req.Name, req.Level, req.units(), user.ID)
 		if err != nil {
 			utils.WriteError(w, http.StatusBadRequest, err.Error())
 			return
@@ -145,7 +135,7 @@ func CourseByIDHandler(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := db.DeleteCourse(id); err != nil {
+		if err := db.DeleteCourse(r.Context(), id); err != nil {
 			utils.WriteError(w, http.StatusBadRequest, err.Error())
 			return
 		}
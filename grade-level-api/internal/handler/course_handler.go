@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/falasefemi2/gradesystem/internal/db"
@@ -10,8 +9,19 @@ import (
 )
 
 type CreateCourseRequest struct {
-	Name  string `json:"name"`
-	Level int    `json:"level"`
+	Name  string `json:"name" validate:"required,max=255"`
+	Level int    `json:"level" validate:"required,min=1"`
+	// Units is the course's credit load; it defaults to DefaultCourseUnits.
+	Units int `json:"units" validate:"min=1,max=12"`
+}
+
+const DefaultCourseUnits = 3
+
+func (req CreateCourseRequest) units() int {
+	if req.Units == 0 {
+		return DefaultCourseUnits
+	}
+	return req.Units
 }
 
 func CreateCourse(w http.ResponseWriter, r *http.Request) {
@@ -20,24 +30,24 @@ func CreateCourse(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	var req CreateCourseRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeRequest(w, r, &req) {
 		return
 	}
-	if req.Name == "" || req.Level <= 0 {
-		utils.WriteError(w, http.StatusBadRequest, "all fields are required and must be valid")
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
 		return
 	}
-
-	user := middleware.GetUserFromContext(r.Context())
 	if user.Role != string(db.Lecturer) {
 		utils.WriteError(w, http.StatusForbidden, "lecturer access required")
 		return
 	}
 
-	course, err := db.CreateCourse(
+	course, err := db.CreateCourse(r.Context(),
 		req.Name,
 		req.Level,
+		req.units(),
 		user.ID,
 	)
 	if err != nil {
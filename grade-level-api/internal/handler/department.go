@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type CreateDepartmentRequest struct {
+	Name string `json:"name" validate:"required,max=255"`
+}
+
+type AssignCourseRequest struct {
+	CourseID int `json:"course_id" validate:"required,min=1"`
+}
+
+func DepartmentsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		departments, err := db.ListDepartments(r.Context())
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, departments)
+
+	case http.MethodPost:
+		if user.Role != string(db.Admin) {
+			utils.WriteError(w, http.StatusForbidden, "admin access required")
+			return
+		}
+
+		var req CreateDepartmentRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		department, err := db.CreateDepartment(r.Context(), req.Name)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusCreated, department)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// AssignDepartmentCourse moves a course into the department.
+func AssignDepartmentCourse(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	departmentID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid department id")
+		return
+	}
+	if _, err := db.FindDepartmentByID(r.Context(), departmentID); err != nil {
+		if errors.Is(err, db.ErrDepartmentNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var req AssignCourseRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if err := db.AssignCourseDepartment(r.Context(), req.CourseID, departmentID); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	course, err := db.FindCourseByID(r.Context(), req.CourseID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, course)
+}
@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type EnrollRequest struct {
+	SemesterID int `json:"semester_id" validate:"required,min=1"`
+	// StudentID and OverrideCapacity are for admins, who enroll on a
+	// student's behalf and may do so past the offering's capacity.
+	StudentID        int  `json:"student_id,omitempty" validate:"min=1"`
+	OverrideCapacity bool `json:"override_capacity,omitempty"`
+}
+
+type CapacityRequest struct {
+	SemesterID int `json:"semester_id" validate:"required,min=1"`
+	// Capacity is the most students who can enroll; null lifts the limit.
+	Capacity *int `json:"capacity" validate:"min=0"`
+}
+
+// EnrollmentsHandler enrolls students in a course offering, waitlisting
+// them when it is full, and lets them drop it again. Admins act on behalf
+// of a student given by student_id.
+func EnrollmentsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		Enroll(w, r)
+	case http.MethodDelete:
+		DropEnrollment(w, r)
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func Enroll(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+
+	var req EnrollRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	studentID := user.ID
+	if db.Role(user.Role) == db.Admin {
+		if !validStudent(w, r, req.StudentID) {
+			return
+		}
+		studentID = req.StudentID
+	} else if req.StudentID != 0 || req.OverrideCapacity {
+		utils.WriteError(w, http.StatusForbidden, "only admins can enroll other students or override capacity")
+		return
+	}
+
+	if _, err := db.FindCourseByID(r.Context(), courseID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if _, err := db.FindSemesterByID(r.Context(), req.SemesterID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	enroll := db.EnrollStudent
+	if req.OverrideCapacity {
+		enroll = db.ForceEnroll
+	}
+	enrollment, err := enroll(r.Context(), studentID, courseID, req.SemesterID)
+	if errors.Is(err, db.ErrEnrollmentClosed) {
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if enrollment.Status == string(db.Waitlisted) {
+		utils.WriteJSON(w, http.StatusAccepted, enrollment)
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, enrollment)
+}
+
+func DropEnrollment(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	semesterID, err := queryID(r, "semester_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	studentID := user.ID
+	if db.Role(user.Role) == db.Admin {
+		if studentID, err = queryID(r, "student_id"); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	course, err := db.FindCourseByID(r.Context(), courseID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	err = service.DropEnrollment(r.Context(), course, studentID, semesterID)
+	switch {
+	case err == nil:
+		utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "enrollment dropped"})
+	case errors.Is(err, db.ErrEnrollmentNotFound):
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, db.ErrEnrollmentClosed), errors.Is(err, db.ErrEnrollmentGraded):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	}
+}
+
+// OfferingCapacityHandler shows and sets how many students can enroll in
+// a course offering.
+func OfferingCapacityHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	course := courseForStaff(w, r, user, courseID)
+	if course == nil {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		semesterID, err := queryID(r, "semester_id")
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		offering, err := db.FindOffering(r.Context(), course.ID, semesterID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, offering)
+
+	case http.MethodPut:
+		var req CapacityRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		offering, err := service.SetCapacity(r.Context(), course, req.SemesterID, req.Capacity)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, offering)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// CourseWaitlist lists the students waiting for a seat in a course
+// offering, in the order they will be promoted.
+func CourseWaitlist(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	course := courseForStaff(w, r, user, courseID)
+	if course == nil {
+		return
+	}
+	semesterID, err := queryID(r, "semester_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	entries, err := db.ListWaitlist(r.Context(), course.ID, semesterID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, entries)
+}
+
+// validStudent checks that id names a student account, writing the error
+// response itself when it does not.
+func validStudent(w http.ResponseWriter, r *http.Request, id int) bool {
+	if id == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "student_id is required")
+		return false
+	}
+	student, err := db.GetUserByID(r.Context(), id)
+	if err != nil || db.Role(student.Role) != db.Student {
+		utils.WriteError(w, http.StatusBadRequest, "student not found")
+		return false
+	}
+	return true
+}
+
+func pathID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(r.PathValue(name))
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid id")
+	}
+	return id, nil
+}
+
+func queryID(r *http.Request, name string) (int, error) {
+	id, err := strconv.Atoi(r.URL.Query().Get(name))
+	if err != nil || id <= 0 {
+		return 0, errors.New("invalid " + name)
+	}
+	return id, nil
+}
+
+// courseForStaff loads a course and checks that the user may manage it:
+// admins can manage any course, lecturers only the ones they teach. It
+// writes the error response itself and returns nil when access is denied.
+func courseForStaff(w http.ResponseWriter, r *http.Request, user *models.User, courseID int) *models.Course {
+	course, err := db.FindCourseByID(r.Context(), courseID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return nil
+	}
+	switch db.Role(user.Role) {
+	case db.Admin:
+		return course
+	case db.Lecturer:
+		if course.LecturerID == user.ID {
+			return course
+		}
+	}
+	utils.WriteError(w, http.StatusForbidden, "you do not teach this course")
+	return nil
+}
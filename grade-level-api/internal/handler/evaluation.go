@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type SubmitEvaluationRequest struct {
+	SemesterID int                       `json:"semester_id" validate:"required,min=1"`
+	Answers    []models.EvaluationAnswer `json:"answers" validate:"required,max=100"`
+}
+
+type CreateQuestionRequest struct {
+	Prompt   string `json:"prompt" validate:"required,max=500"`
+	Kind     string `json:"kind" validate:"required,oneof=likert text"`
+	Position int    `json:"position" validate:"min=0"`
+}
+
+// EvaluationQuestions lists the questions students are currently asked.
+func EvaluationQuestions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	questions, err := db.ListEvaluationQuestions(r.Context(), false)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, questions)
+}
+
+// AdminEvaluationQuestions lets admins configure the questionnaire. The
+// listing includes retired questions.
+func AdminEvaluationQuestions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		questions, err := db.ListEvaluationQuestions(r.Context(), true)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, questions)
+
+	case http.MethodPost:
+		var req CreateQuestionRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		question, err := db.CreateEvaluationQuestion(r.Context(), req.Prompt, db.QuestionKind(req.Kind), req.Position)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusCreated, question)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func RetireEvaluationQuestion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	id, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid question id")
+		return
+	}
+	if err := db.RetireEvaluationQuestion(r.Context(), id); err != nil {
+		if errors.Is(err, db.ErrQuestionNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "question retired"})
+}
+
+// CourseEvaluationsHandler takes a student's anonymous evaluation of a
+// course offering and shows staff the aggregated results once the
+// offering's grades are published.
+func CourseEvaluationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		if user.Role != string(db.Student) {
+			utils.WriteError(w, http.StatusForbidden, "student access required")
+			return
+		}
+
+		var req SubmitEvaluationRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		err := service.SubmitEvaluation(r.Context(), user.ID, courseID, req.SemesterID, req.Answers)
+		switch {
+		case err == nil:
+			utils.WriteJSON(w, http.StatusCreated, map[string]string{"message": "thank you, your evaluation was recorded anonymously"})
+		case errors.Is(err, service.ErrNotEnrolled):
+			utils.WriteError(w, http.StatusForbidden, err.Error())
+		case errors.Is(err, db.ErrEvaluationSubmitted), errors.Is(err, service.ErrEvaluationClosed):
+			utils.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+		}
+
+	case http.MethodGet:
+		course := courseForStaff(w, r, user, courseID)
+		if course == nil {
+			return
+		}
+		semesterID, err := queryID(r, "semester_id")
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		report, err := service.CourseEvaluationReport(r.Context(), course.ID, semesterID)
+		if errors.Is(err, service.ErrResultsNotPublished) || errors.Is(err, service.ErrTooFewResponses) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, report)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// EvaluationRankings ranks lecturers by their evaluation ratings within
+// each department for a semester.
+func EvaluationRankings(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	semesterID, err := queryID(r, "semester_id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	rankings, err := service.EvaluationRankings(r.Context(), semesterID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, rankings)
+}
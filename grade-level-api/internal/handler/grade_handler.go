@@ -1 +1,197 @@
 package handler
+
+import (
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/metrics"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type PostGradeRequest struct {
+	SemesterID int     `json:"semester_id" validate:"required,min=1"`
+	StudentID  int     `json:"student_id" validate:"required,min=1"`
+	Score      float64 `json:"score" validate:"min=0,max=100"`
+}
+
+type PublishGradesRequest struct {
+	SemesterID int `json:"semester_id" validate:"required,min=1"`
+}
+
+// CourseGradesHandler lets the course lecturer post scores and lets staff
+// list the grades of a course offering.
+func CourseGradesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	course := courseForStaff(w, r, user, courseID)
+	if course == nil {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		semesterID, err := queryID(r, "semester_id")
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		grades, err := db.ListGradesForOffering(r.Context(), course.ID, semesterID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, grades)
+
+	case http.MethodPost:
+		if course.LecturerID != user.ID {
+			utils.WriteError(w, http.StatusForbidden, "only the course lecturer can post grades")
+			return
+		}
+
+		var req PostGradeRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+
+		attendance, err := service.StudentAttendanceSummary(r.Context(), req.StudentID, course.ID, req.SemesterID)
+		if err == service.ErrNotEnrolled {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if !attendance.ExamEligible {
+			utils.WriteError(w, http.StatusConflict, "student did not meet the minimum attendance to sit the exam")
+			return
+		}
+
+		grade, err := db.SaveStudentGrade(r.Context(), req.StudentID, course.ID, req.SemesterID, req.Score)
+		if err == db.ErrEnrollmentNotFound {
+			utils.WriteError(w, http.StatusBadRequest, service.ErrNotEnrolled.Error())
+			return
+		}
+		if err == db.ErrGradePublished {
+			utils.WriteError(w, http.StatusConflict, "grade has already been published; changes must go through an appeal")
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		metrics.GradesPosted.Inc()
+		utils.WriteJSON(w, http.StatusOK, grade)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func PublishGrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	courseID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid course id")
+		return
+	}
+	course := courseForStaff(w, r, user, courseID)
+	if course == nil {
+		return
+	}
+
+	var req PublishGradesRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	published, err := service.PublishGrades(r.Context(), course, req.SemesterID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]int{"published": published})
+}
+
+// MyGrades lists the signed-in student's published grades.
+func MyGrades(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	grades, err := db.ListPublishedGradesForStudent(r.Context(), user.ID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, grades)
+}
+
+// GradeHistory shows every change made to a grade after publication.
+func GradeHistory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	gradeID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid grade id")
+		return
+	}
+	grade, err := db.FindStudentGrade(r.Context(), gradeID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	if user.Role == string(db.Student) {
+		if grade.StudentID != user.ID {
+			utils.WriteError(w, http.StatusForbidden, "forbidden")
+			return
+		}
+	} else if courseForStaff(w, r, user, grade.CourseID) == nil {
+		return
+	}
+
+	changes, err := db.ListGradeChanges(r.Context(), grade.GradeID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, changes)
+}
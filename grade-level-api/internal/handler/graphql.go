@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/gql"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type GraphQLRequest struct {
+	Query         string         `json:"query" validate:"required"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// GraphQL answers read queries. As usual for GraphQL, errors from
+// individual fields, including forbidden ones, come back in the body's
+// errors list with a 200 status.
+func GraphQL(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	var req GraphQLRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	result := gql.Execute(r.Context(), req.Query, req.Variables, req.OperationName)
+	utils.WriteJSON(w, http.StatusOK, result)
+}
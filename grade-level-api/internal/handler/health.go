@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+var shuttingDown atomic.Bool
+
+// MarkShuttingDown makes /readyz fail so load balancers stop sending new
+// traffic while in-flight requests drain.
+func MarkShuttingDown() {
+	shuttingDown.Store(true)
+}
+
+// Healthz reports that the process is alive. It never touches the database
+// so a slow database does not get the process restarted.
+func Healthz(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// Readyz reports whether the server can take traffic, which requires a
+// reachable database.
+func Readyz(w http.ResponseWriter, r *http.Request) {
+	if shuttingDown.Load() {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if err := db.Ping(ctx); err != nil {
+		utils.WriteJSON(w, http.StatusServiceUnavailable, map[string]string{
+			"status": "unavailable",
+			"error":  "database unreachable",
+		})
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+type ProgrammeRequest struct {
+	MinCGPA       float64                      `json:"min_cgpa" validate:"min=0,max=5"`
+	CoreCourseIDs []int                        `json:"core_course_ids"`
+	Electives     []models.ElectiveRequirement `json:"electives"`
+}
+
+type AssignStudentRequest struct {
+	StudentID int `json:"student_id" validate:"required,min=1"`
+}
+
+// ProgrammeHandler shows a department's graduation requirements and lets
+// admins replace them.
+func ProgrammeHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	departmentID, ok := departmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		programme, err := db.FindProgramme(r.Context(), departmentID)
+		if errors.Is(err, db.ErrProgrammeNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, programme)
+
+	case http.MethodPut:
+		if user.Role != string(db.Admin) {
+			utils.WriteError(w, http.StatusForbidden, "admin access required")
+			return
+		}
+		var req ProgrammeRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		programme := &models.Programme{
+			DepartmentID:  departmentID,
+			MinCGPA:       req.MinCGPA,
+			CoreCourseIDs: req.CoreCourseIDs,
+			Electives:     req.Electives,
+		}
+		if err := service.SaveProgramme(r.Context(), programme); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		saved, err := db.FindProgramme(r.Context(), departmentID)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, saved)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// AssignDepartmentStudent puts a student on the department's programme.
+func AssignDepartmentStudent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	departmentID, ok := departmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req AssignStudentRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if !validStudent(w, r, req.StudentID) {
+		return
+	}
+	if err := db.AssignStudentDepartment(r.Context(), req.StudentID, departmentID); err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	student, err := db.GetUserByID(r.Context(), req.StudentID)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	student.Password = ""
+	utils.WriteJSON(w, http.StatusOK, student)
+}
+
+// StudentAudit reports which graduation requirements a student has met.
+// Students may only audit themselves.
+func StudentAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	studentID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid student id")
+		return
+	}
+	if user.Role == string(db.Student) && user.ID != studentID {
+		utils.WriteError(w, http.StatusForbidden, "students can only audit themselves")
+		return
+	}
+	if user.Role == string(db.Admin) && !validStudent(w, r, studentID) {
+		return
+	}
+
+	audit, err := service.AuditStudent(r.Context(), studentID)
+	switch {
+	case err == nil:
+		utils.WriteJSON(w, http.StatusOK, audit)
+	case errors.Is(err, service.ErrNoDepartment), errors.Is(err, db.ErrProgrammeNotFound):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	default:
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// CohortAudit audits every student on a department's programme, or with
+// semester_id the cohort enrolled in that semester.
+func CohortAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	departmentID, ok := departmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var semesterID int
+	if r.URL.Query().Has("semester_id") {
+		id, err := queryID(r, "semester_id")
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		semesterID = id
+	}
+
+	cohort, err := service.AuditCohort(r.Context(), departmentID, semesterID)
+	if errors.Is(err, db.ErrProgrammeNotFound) {
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, cohort)
+}
+
+// departmentFromPath reads the department ID from the path and checks the
+// department exists, writing the error response itself when not.
+func departmentFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	departmentID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid department id")
+		return 0, false
+	}
+	if _, err := db.FindDepartmentByID(r.Context(), departmentID); err != nil {
+		if errors.Is(err, db.ErrDepartmentNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return 0, false
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return 0, false
+	}
+	return departmentID, true
+}
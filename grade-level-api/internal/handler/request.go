@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/falasefemi2/gradesystem/internal/validate"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+// decodeRequest decodes the JSON body into req and validates it against
+// its `validate` tags. On failure it writes the error response and
+// returns false.
+func decodeRequest(w http.ResponseWriter, r *http.Request, req any) bool {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+		return false
+	}
+	if err := validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return false
+	}
+	return true
+}
+
+func writeValidationError(w http.ResponseWriter, err error) {
+	var fieldErrs validate.Errors
+	if errors.As(err, &fieldErrs) {
+		utils.WriteErrorDetails(w, http.StatusBadRequest, "validation failed", fieldErrs)
+		return
+	}
+	utils.WriteError(w, http.StatusBadRequest, err.Error())
+}
@@ -2,20 +2,18 @@
 package handler
 
 import (
-	"encoding/json"
 	"net/http"
-	"strconv"
-	"strings"
 	"time"
 
 	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/service"
 	"github.com/falasefemi2/gradesystem/utils"
 )
 
 type CreateSemesterRequest struct {
-	Name      string `json:"name"`
-	StartDate string `json:"start_date"`
-	EndDate   string `json:"end_date"`
+	Name      string `json:"name" validate:"required,max=50"`
+	StartDate string `json:"start_date" validate:"required,date"`
+	EndDate   string `json:"end_date" validate:"required,date"`
 }
 
 func CreateSemester(w http.ResponseWriter, r *http.Request) {
@@ -25,29 +23,14 @@ func CreateSemester(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req CreateSemesterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	if req.Name == "" || req.StartDate == "" || req.EndDate == "" {
-		utils.WriteError(w, http.StatusBadRequest, "all fields are required")
-		return
-	}
-
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid start_date format (YYYY-MM-DD)")
-		return
-	}
-
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid end_date format (YYYY-MM-DD)")
-		return
-	}
+	startDate, _ := time.Parse("2006-01-02", req.StartDate)
+	endDate, _ := time.Parse("2006-01-02", req.EndDate)
 
-	semester, err := db.CreateSemester(
+	semester, err := db.CreateSemester(r.Context(),
 		db.Semester(req.Name),
 		startDate,
 		endDate,
@@ -66,7 +49,7 @@ func GetAllSemesters(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	semesters, err := db.ListSemesters()
+	semesters, err := db.ListSemesters(r.Context())
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -81,19 +64,13 @@ func GetSemesterByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	idStr := r.URL.Query().Get("id")
-	if idStr == "" {
-		utils.WriteError(w, http.StatusBadRequest, "missing semester id")
-		return
-	}
-
-	semesterID, err := strconv.Atoi(idStr)
-	if err != nil || semesterID <= 0 {
+	semesterID, err := pathID(r, "id")
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "invalid semester id")
 		return
 	}
 
-	semester, err := db.FindSemesterByID(semesterID)
+	semester, err := db.FindSemesterByID(r.Context(), semesterID)
 	if err != nil {
 		utils.WriteError(w, http.StatusNotFound, err.Error())
 		return
@@ -108,42 +85,21 @@ func UpdateSemester(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 3 {
-		utils.WriteError(w, http.StatusBadRequest, "invalid URL path")
-		return
-	}
-
-	semesterID, err := strconv.Atoi(parts[2])
-	if err != nil || semesterID <= 0 {
+	semesterID, err := pathID(r, "id")
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "invalid semester id")
 		return
 	}
 
 	var req CreateSemesterRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	if req.Name == "" || req.StartDate == "" || req.EndDate == "" {
-		utils.WriteError(w, http.StatusBadRequest, "all fields are required")
-		return
-	}
+	startDate, _ := time.Parse("2006-01-02", req.StartDate)
+	endDate, _ := time.Parse("2006-01-02", req.EndDate)
 
-	startDate, err := time.Parse("2006-01-02", req.StartDate)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid start_date format (YYYY-MM-DD)")
-		return
-	}
-
-	endDate, err := time.Parse("2006-01-02", req.EndDate)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid end_date format (YYYY-MM-DD)")
-		return
-	}
-
-	semester, err := db.UpdateSemester(
+	semester, err := db.UpdateSemester(r.Context(),
 		semesterID,
 		db.Semester(req.Name),
 		startDate,
@@ -162,20 +118,45 @@ func DeleteSemester(w http.ResponseWriter, r *http.Request) {
 		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 3 {
-		utils.WriteError(w, http.StatusBadRequest, "invalid URL path")
-		return
-	}
-	semesterID, err := strconv.Atoi(parts[2])
-	if err != nil || semesterID <= 0 {
+	semesterID, err := pathID(r, "id")
+	if err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "invalid semester id")
 		return
 	}
-	semester := db.DeleteSemester(semesterID)
+	semester := db.DeleteSemester(r.Context(), semesterID)
 	if semester != nil {
 		utils.WriteError(w, http.StatusNotFound, semester.Error())
 		return
 	}
 	utils.WriteJSON(w, http.StatusNoContent, nil)
 }
+
+type EnrollmentWindowRequest struct {
+	Open bool `json:"open"`
+}
+
+// SemesterEnrollment opens or closes enrollment for a semester.
+func SemesterEnrollment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	semesterID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid semester id")
+		return
+	}
+
+	var req EnrollmentWindowRequest
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+
+	semester, err := service.SetEnrollmentOpen(r.Context(), semesterID, req.Open)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, semester)
+}
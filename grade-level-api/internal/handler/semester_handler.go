@@ -23,7 +23,11 @@ func SemesterByIDHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func SemestersHandler(w http.ResponseWriter, r *http.Request) {
-	user := middleware.GetUserFromContext(r.Context())
+	user, err := middleware.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
 
 	switch r.Method {
 	case http.MethodPost:
@@ -2,87 +2,222 @@
 package handler
 
 import (
-	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/falasefemi2/gradesystem/internal/auth"
 	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/metrics"
 	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/ratelimit"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+	"github.com/falasefemi2/gradesystem/internal/validate"
 	"github.com/falasefemi2/gradesystem/utils"
 )
 
+// SignupRequest has no role: anyone signing up is a student. Staff
+// accounts are created by an admin through CreateUserRequest.
 type SignupRequest struct {
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	Name     string `json:"name" validate:"required,max=255"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type CreateUserRequest struct {
+	Name     string `json:"name" validate:"required,max=255"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+	Role     string `json:"role" validate:"required,oneof=student lecturer admin"`
 }
 
 type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email    string `json:"email" validate:"required"`
+	Password string `json:"password" validate:"required"`
 }
 
 func SignUp(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 	var req SignupRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeRequest(w, r, &req) {
+		return
+	}
+	if err := auth.ValidatePassword(req.Password); err != nil {
+		writeValidationError(w, validate.Errors{{Field: "password", Message: err.Error()}})
 		return
 	}
 
-	role := db.Role(req.Role)
-	user, err := db.CreateUser(
-		db.DB,
-		req.Name, req.Email, req.Password, role)
+	user, err := service.Register(r.Context(), req.Name, req.Email, req.Password, db.Student)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		writeRegisterError(w, r, err)
 		return
 	}
 	user.Password = ""
 	utils.WriteJSON(w, http.StatusCreated, user)
 }
 
+func writeRegisterError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, db.ErrEmailTaken):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, db.ErrInvalidRole):
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+	default:
+		slog.ErrorContext(r.Context(), "registering user", slog.Any("error", err))
+		utils.WriteError(w, http.StatusInternalServerError, "could not create user")
+	}
+}
+
+var (
+	loginIPLimiter      *ratelimit.Limiter
+	loginAccountLimiter *ratelimit.Limiter
+)
+
+// SetLoginLimiters installs the rate limiters applied to /login, per
+// client IP and per account. A nil limiter disables that check.
+func SetLoginLimiters(ip, account *ratelimit.Limiter) {
+	loginIPLimiter = ip
+	loginAccountLimiter = account
+}
+
 func Login(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
 
+	if loginIPLimiter != nil {
+		if ok, retryAfter := loginIPLimiter.Allow(clientIP(r)); !ok {
+			writeTooManyRequests(w, retryAfter, "too many login attempts, try again later")
+			return
+		}
+	}
+
 	var req LoginRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "invalid request body")
+	if !decodeRequest(w, r, &req) {
 		return
 	}
 
-	user, err := db.VerifyUser(req.Email, req.Password)
+	if loginAccountLimiter != nil {
+		account := strconv.Itoa(tenant.FromContext(r.Context())) + ":" + strings.ToLower(req.Email)
+		if ok, retryAfter := loginAccountLimiter.Allow(account); !ok {
+			writeTooManyRequests(w, retryAfter, "too many login attempts, try again later")
+			return
+		}
+	}
+
+	user, err := service.Authenticate(r.Context(), req.Email, req.Password)
+	var locked *service.AccountLockedError
+	if errors.As(err, &locked) {
+		metrics.FailedLogins.Inc()
+		writeTooManyRequests(w, time.Until(locked.Until), "account temporarily locked after repeated failed logins")
+		return
+	}
+	if errors.Is(err, service.ErrEmailNotVerified) {
+		utils.WriteError(w, http.StatusForbidden, err.Error())
+		return
+	}
 	if err != nil {
+		metrics.FailedLogins.Inc()
 		utils.WriteError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
 
-	token, err := middleware.GenerateJWT(user.Email)
+	token, err := middleware.GenerateJWT(tenant.FromContext(r.Context()), user.Email)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "could not generate token")
 		return
 	}
 
+	metrics.Logins.Inc()
 	utils.WriteJSON(w, http.StatusOK, map[string]string{
 		"token": token,
 	})
 }
 
-func GetAllUsers(w http.ResponseWriter, r *http.Request) {
+func writeTooManyRequests(w http.ResponseWriter, retryAfter time.Duration, msg string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	utils.WriteError(w, http.StatusTooManyRequests, msg)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// AdminUsersHandler lists accounts and lets admins create them with any
+// role. The new user still has to verify their email before logging in.
+func AdminUsersHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		user, err := db.GetAllUsers(r.Context())
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, user)
+
+	case http.MethodPost:
+		var req CreateUserRequest
+		if !decodeRequest(w, r, &req) {
+			return
+		}
+		if err := auth.ValidatePassword(req.Password); err != nil {
+			writeValidationError(w, validate.Errors{{Field: "password", Message: err.Error()}})
+			return
+		}
+		user, err := service.Register(r.Context(), req.Name, req.Email, req.Password, db.Role(req.Role))
+		if err != nil {
+			writeRegisterError(w, r, err)
+			return
+		}
+		user.Password = ""
+		utils.WriteJSON(w, http.StatusCreated, user)
+
+	default:
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func GetLockedUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
 		return
 	}
-	user, err := db.GetAllUsers()
+	users, err := db.ListLockedUsers(r.Context(), time.Now())
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	utils.WriteJSON(w, http.StatusOK, user)
+	utils.WriteJSON(w, http.StatusOK, users)
+}
+
+func UnlockUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		utils.WriteError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	userID, err := pathID(r, "id")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	if err := db.ResetLoginFailures(r.Context(), userID); err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "user unlocked"})
 }
@@ -0,0 +1,57 @@
+// Package mail sends transactional email such as verification and password
+// reset messages.
+package mail
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// LogSender writes messages to the log instead of delivering them, for
+// development and until a real provider is configured.
+type LogSender struct {
+	Logger *slog.Logger
+}
+
+func (s LogSender) Send(ctx context.Context, msg Message) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.InfoContext(ctx, "email",
+		slog.String("to", msg.To),
+		slog.String("subject", msg.Subject),
+		slog.String("body", msg.Body),
+	)
+	return nil
+}
+
+// Outbox keeps sent messages in memory so tests can inspect them.
+type Outbox struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func (o *Outbox) Send(_ context.Context, msg Message) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+func (o *Outbox) Messages() []Message {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]Message(nil), o.messages...)
+}
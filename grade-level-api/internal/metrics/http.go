@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var (
+	httpRequests = NewCounter(
+		"http_requests_total",
+		"HTTP requests handled, by method, route and status code.",
+		"method", "route", "status",
+	)
+	httpDuration = NewHistogram(
+		"http_request_duration_seconds",
+		"HTTP request latency, by method and route.",
+		DefaultBuckets,
+		"method", "route",
+	)
+
+	Logins = NewCounter(
+		"auth_logins_total",
+		"Successful logins.",
+	)
+	FailedLogins = NewCounter(
+		"auth_failed_logins_total",
+		"Login attempts rejected because of bad credentials.",
+	)
+	GradesPosted = NewCounter(
+		"grades_posted_total",
+		"Grades posted or corrected by lecturers.",
+	)
+)
+
+// Instrument records request counts and latencies per route. It must wrap
+// the ServeMux directly: the route is read from Request.Pattern, which the
+// mux sets on the request it is given.
+func Instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequests.Inc(r.Method, route, strconv.Itoa(rec.status))
+		httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
+
+// RegisterDBStats exposes connection pool statistics, read from stats on
+// every scrape.
+func RegisterDBStats(stats func() sql.DBStats) {
+	NewGaugeFunc("db_max_open_connections", "Maximum number of open connections to the database.",
+		func() float64 { return float64(stats().MaxOpenConnections) })
+	NewGaugeFunc("db_open_connections", "Established connections, both in use and idle.",
+		func() float64 { return float64(stats().OpenConnections) })
+	NewGaugeFunc("db_in_use_connections", "Connections currently in use.",
+		func() float64 { return float64(stats().InUse) })
+	NewGaugeFunc("db_idle_connections", "Idle connections.",
+		func() float64 { return float64(stats().Idle) })
+	NewCounterFunc("db_wait_count_total", "Connections waited for because the pool was exhausted.",
+		func() float64 { return float64(stats().WaitCount) })
+	NewCounterFunc("db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		func() float64 { return stats().WaitDuration.Seconds() })
+	NewCounterFunc("db_max_idle_closed_total", "Connections closed due to the idle connection limit.",
+		func() float64 { return float64(stats().MaxIdleClosed) })
+	NewCounterFunc("db_max_lifetime_closed_total", "Connections closed due to the maximum lifetime.",
+		func() float64 { return float64(stats().MaxLifetimeClosed) })
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
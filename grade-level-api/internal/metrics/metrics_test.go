@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	requests := newCounter("requests_total", "Requests.", "route")
+	requests.Inc("/courses")
+	requests.Add(2, `/a"b`)
+	r.register(requests)
+
+	latency := &Histogram{
+		metricName: "latency_seconds",
+		help:       "Latency.",
+		buckets:    []float64{0.1, 1},
+		series:     map[string]*histogramSeries{},
+	}
+	latency.Observe(0.05)
+	latency.Observe(0.5)
+	latency.Observe(5)
+	r.register(latency)
+
+	var out strings.Builder
+	r.Write(&out)
+
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{le="0.1"} 1
+latency_seconds_bucket{le="1"} 2
+latency_seconds_bucket{le="+Inf"} 3
+latency_seconds_sum 5.55
+latency_seconds_count 3
+# HELP requests_total Requests.
+# TYPE requests_total counter
+requests_total{route="/a\"b"} 2
+requests_total{route="/courses"} 1
+`, out.String())
+}
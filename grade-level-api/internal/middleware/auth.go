@@ -12,13 +12,17 @@ var jwtSecret = []byte("supersecretkey")
 
 type Claims struct {
 	Email string `json:"email"`
+	// Institution is the institution the user signed in to. The token is
+	// only accepted on requests for it.
+	Institution int `json:"institution"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(email string) (string, error) {
+func GenerateJWT(institutionID int, email string) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		Email: email,
+		Email:       email,
+		Institution: institutionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
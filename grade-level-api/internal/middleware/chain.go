@@ -0,0 +1,165 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"slices"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type Middleware func(http.Handler) http.Handler
+
+// Chain wraps h with the given middleware. The first middleware is the
+// outermost one, so it sees the request first and the response last.
+func Chain(h http.Handler, middlewares ...Middleware) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		h = middlewares[i](h)
+	}
+	return h
+}
+
+// requestInfo is shared between the access logger and the handlers below
+// it, so RoleAuth can report who made the request once it is known.
+type requestInfo struct {
+	userID int
+}
+
+// RequestID reuses the caller's X-Request-ID when it looks sane and
+// generates one otherwise. The ID is echoed in the response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		ctx := context.WithValue(r.Context(), requestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Logger writes one structured access log line per request.
+func Logger(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			info := &requestInfo{}
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+			ctx := context.WithValue(r.Context(), requestInfoKey, info)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			attrs := []slog.Attr{
+				slog.String("request_id", RequestIDFromContext(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", rec.status),
+				slog.Int("bytes", rec.bytes),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			}
+			if info.userID != 0 {
+				attrs = append(attrs, slog.Int("user_id", info.userID))
+			}
+			logger.LogAttrs(r.Context(), slog.LevelInfo, "request", attrs...)
+		})
+	}
+}
+
+// Recover turns a panic in a handler into a JSON 500 instead of dropping
+// the connection, and logs the panic with its stack trace.
+func Recover(logger *slog.Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			defer func() {
+				err := recover()
+				if err == nil {
+					return
+				}
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+				logger.LogAttrs(r.Context(), slog.LevelError, "panic recovered",
+					slog.String("request_id", RequestIDFromContext(r.Context())),
+					slog.Any("panic", err),
+					slog.String("stack", string(debug.Stack())),
+				)
+				if !rec.wroteHeader {
+					utils.WriteError(w, http.StatusInternalServerError, "internal server error")
+				}
+			}()
+			next.ServeHTTP(rec, r)
+		})
+	}
+}
+
+// Timeout sets a deadline on the request context. Handlers and database
+// calls that honour the context give up once it passes. Requests to the
+// streaming paths, which stay open until the client leaves, get none.
+func Timeout(d time.Duration, streamingPaths ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if slices.Contains(streamingPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streaming responses.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
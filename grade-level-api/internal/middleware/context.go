@@ -8,10 +8,32 @@ import (
 	"github.com/falasefemi2/gradesystem/internal/models"
 )
 
+type contextKey string
+
+const (
+	userKey        contextKey = "user"
+	requestIDKey   contextKey = "requestID"
+	requestInfoKey contextKey = "requestInfo"
+)
+
 func GetUserFromContext(ctx context.Context) (*models.User, error) {
-	user, ok := ctx.Value("user").(*models.User)
+	user, ok := ctx.Value(userKey).(*models.User)
 	if !ok {
 		return nil, errors.New("user not found in context")
 	}
 	return user, nil
 }
+
+func withUser(ctx context.Context, user *models.User) context.Context {
+	if info, ok := ctx.Value(requestInfoKey).(*requestInfo); ok {
+		info.userID = user.ID
+	}
+	return context.WithValue(ctx, userKey, user)
+}
+
+// RequestIDFromContext returns the ID assigned to the request by the
+// RequestID middleware, or an empty string outside of a request.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
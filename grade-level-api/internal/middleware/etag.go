@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ETag tags successful GET responses with a hash of their body and answers
+// 304 Not Modified when the client's If-None-Match already has it, so a
+// client polling an unchanged resource gets no body back. The response is
+// buffered to hash it, so the streaming paths are passed straight through.
+func ETag(streamingPaths ...string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet || slices.Contains(streamingPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			buf := &bufferedResponse{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(buf, r)
+
+			if buf.status != http.StatusOK {
+				w.WriteHeader(buf.status)
+				w.Write(buf.body.Bytes())
+				return
+			}
+			tag := w.Header().Get("ETag")
+			if tag == "" {
+				sum := sha256.Sum256(buf.body.Bytes())
+				tag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				w.Header().Set("ETag", tag)
+			}
+			if etagMatches(r.Header.Get("If-None-Match"), tag) {
+				h := w.Header()
+				h.Del("Content-Type")
+				h.Del("Content-Length")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(buf.body.Bytes())
+		})
+	}
+}
+
+// etagMatches reports whether an If-None-Match header lists tag. The
+// comparison is weak, as RFC 9110 requires for If-None-Match.
+func etagMatches(header, tag string) bool {
+	if header == "" {
+		return false
+	}
+	tag = strings.TrimPrefix(tag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// bufferedResponse holds back the status and body until the handler is
+// done. Headers still go to the underlying writer, which sends nothing
+// until WriteHeader is called on it.
+type bufferedResponse struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (b *bufferedResponse) WriteHeader(status int) {
+	if !b.wroteHeader {
+		b.status = status
+		b.wroteHeader = true
+	}
+}
+
+func (b *bufferedResponse) Write(p []byte) (int, error) {
+	b.wroteHeader = true
+	return b.body.Write(p)
+}
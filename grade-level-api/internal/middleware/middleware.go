@@ -1,11 +1,11 @@
 package middleware
 
 import (
-	"context"
 	"net/http"
 	"strings"
 
 	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
 	"github.com/falasefemi2/gradesystem/utils"
 )
 
@@ -29,7 +29,14 @@ func RoleAuth(next http.HandlerFunc, allowedRoles ...db.Role) http.HandlerFunc {
 			return
 		}
 
-		user, err := db.GetUserByEmail(claims.Email)
+		// Emails are only unique within an institution, so a token from
+		// another one could name a different user here.
+		if claims.Institution != tenant.FromContext(r.Context()) {
+			utils.WriteError(w, http.StatusUnauthorized, "token was issued for another institution")
+			return
+		}
+
+		user, err := db.GetUserByEmail(r.Context(), claims.Email)
 		if err != nil {
 			utils.WriteError(w, http.StatusUnauthorized, "user not found")
 			return
@@ -48,7 +55,6 @@ func RoleAuth(next http.HandlerFunc, allowedRoles ...db.Role) http.HandlerFunc {
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), "user", user)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(withUser(r.Context(), user)))
 	}
 }
@@ -1,7 +1,10 @@
 package middleware_test
 
 import (
+	"context"
 	"database/sql"
+	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,14 +13,16 @@ import (
 	"github.com/falasefemi2/gradesystem/internal/db"
 	"github.com/falasefemi2/gradesystem/internal/middleware"
 	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/mock"
 )
 
-func generateTestJWT(email string, secret []byte) (string, error) {
+func generateTestJWT(institutionID int, email string, secret []byte) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &middleware.Claims{
-		Email: email,
+		Email:       email,
+		Institution: institutionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -46,22 +51,28 @@ func TestRoleAuth(t *testing.T) {
 	})
 
 	// Create a request with a valid token for an admin user
-	adminToken, err := generateTestJWT("admin@example.com", []byte("supersecretkey"))
+	adminToken, err := generateTestJWT(tenant.Default, "admin@example.com", []byte("supersecretkey"))
 	if err != nil {
 		t.Fatalf("Failed to generate admin token: %v", err)
 	}
 
 	// Create a request with a valid token for a non-admin user
-	userToken, err := generateTestJWT("user@example.com", []byte("supersecretkey"))
+	userToken, err := generateTestJWT(tenant.Default, "user@example.com", []byte("supersecretkey"))
 	if err != nil {
 		t.Fatalf("Failed to generate user token: %v", err)
 	}
 
+	// A token an admin of another institution signed in with
+	otherToken, err := generateTestJWT(tenant.Default+1, "admin@example.com", []byte("supersecretkey"))
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
 	// Create a mock for GetUserByEmail
 	originalGetUserByEmail := db.GetUserByEmail
 	defer func() { db.GetUserByEmail = originalGetUserByEmail }()
 
-	db.GetUserByEmail = func(email string) (*models.User, error) {
+	db.GetUserByEmail = func(ctx context.Context, email string) (*models.User, error) {
 		if email == "admin@example.com" {
 			return &models.User{Email: email, Role: "admin"}, nil
 		}
@@ -86,6 +97,12 @@ func TestRoleAuth(t *testing.T) {
 			requiredRole:   db.Admin,
 			expectedStatus: http.StatusForbidden,
 		},
+		{
+			name:           "Other Institution",
+			token:          otherToken,
+			requiredRole:   db.Admin,
+			expectedStatus: http.StatusUnauthorized,
+		},
 		{
 			name:           "No Token",
 			token:          "",
@@ -116,3 +133,117 @@ func TestRoleAuth(t *testing.T) {
 		})
 	}
 }
+
+func TestRequestID(t *testing.T) {
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(middleware.RequestIDFromContext(r.Context())))
+	}))
+
+	testCases := []struct {
+		name     string
+		incoming string
+		reused   bool
+	}{
+		{name: "Generated", incoming: "", reused: false},
+		{name: "Propagated", incoming: "abc-123", reused: true},
+		{name: "Invalid Replaced", incoming: "bad id\n", reused: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tc.incoming != "" {
+				req.Header.Set(middleware.RequestIDHeader, tc.incoming)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			id := rr.Header().Get(middleware.RequestIDHeader)
+			if id == "" || id != rr.Body.String() {
+				t.Fatalf("request id not propagated: header %q, context %q", id, rr.Body.String())
+			}
+			if tc.reused != (id == tc.incoming) {
+				t.Errorf("got request id %q for incoming %q", id, tc.incoming)
+			}
+		})
+	}
+}
+
+func TestRecover(t *testing.T) {
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	handler := middleware.Chain(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}),
+		middleware.RequestID,
+		middleware.Logger(logger),
+		middleware.Recover(logger),
+	)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if rr.Code != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusInternalServerError)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON error, got content type %q", ct)
+	}
+}
+
+func TestETag(t *testing.T) {
+	handler := middleware.ETag("/events")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	first := httptest.NewRecorder()
+	handler.ServeHTTP(first, httptest.NewRequest("GET", "/courses", nil))
+	tag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || tag == "" || first.Body.String() != `{"ok":true}` {
+		t.Fatalf("got %d, ETag %q, body %q", first.Code, tag, first.Body.String())
+	}
+
+	testCases := []struct {
+		name        string
+		method      string
+		path        string
+		ifNoneMatch string
+		wantStatus  int
+		wantTag     bool
+	}{
+		{name: "No Validator", method: "GET", path: "/courses", wantStatus: http.StatusOK, wantTag: true},
+		{name: "Matching", method: "GET", path: "/courses", ifNoneMatch: tag, wantStatus: http.StatusNotModified, wantTag: true},
+		{name: "Weak In List", method: "GET", path: "/courses", ifNoneMatch: `"other", W/` + tag, wantStatus: http.StatusNotModified, wantTag: true},
+		{name: "Wildcard", method: "GET", path: "/courses", ifNoneMatch: "*", wantStatus: http.StatusNotModified, wantTag: true},
+		{name: "Stale", method: "GET", path: "/courses", ifNoneMatch: `"other"`, wantStatus: http.StatusOK, wantTag: true},
+		{name: "Error Untagged", method: "GET", path: "/missing", ifNoneMatch: "*", wantStatus: http.StatusNotFound},
+		{name: "Write Untagged", method: "POST", path: "/courses", ifNoneMatch: tag, wantStatus: http.StatusOK},
+		{name: "Streaming Untagged", method: "GET", path: "/events", ifNoneMatch: tag, wantStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			if tc.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tc.ifNoneMatch)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Errorf("got status %d want %d", rr.Code, tc.wantStatus)
+			}
+			if got := rr.Header().Get("ETag"); (got != "") != tc.wantTag || (tc.wantTag && got != tag) {
+				t.Errorf("got ETag %q", got)
+			}
+			if rr.Code == http.StatusNotModified && rr.Body.Len() != 0 {
+				t.Errorf("304 carried a body: %q", rr.Body.String())
+			}
+		})
+	}
+}
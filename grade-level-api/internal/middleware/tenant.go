@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+	"github.com/falasefemi2/gradesystem/utils"
+)
+
+const InstitutionHeader = "X-Institution"
+
+// Tenant decides which institution a request is for, by the slug in the
+// X-Institution header or else the subdomain of baseDomain the request
+// was sent to, e.g. "unilag" for unilag.grades.example.com. Requests that
+// name neither are for the default institution; naming an unknown one is
+// a 404.
+func Tenant(baseDomain string) Middleware {
+	baseDomain = strings.ToLower(strings.TrimPrefix(baseDomain, "."))
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			slug := strings.ToLower(r.Header.Get(InstitutionHeader))
+			if slug == "" && baseDomain != "" {
+				slug = subdomain(r.Host, baseDomain)
+			}
+			if slug == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			inst, err := db.FindInstitutionBySlug(r.Context(), slug)
+			if errors.Is(err, db.ErrInstitutionNotFound) {
+				utils.WriteError(w, http.StatusNotFound, "institution not found")
+				return
+			}
+			if err != nil {
+				utils.WriteError(w, http.StatusInternalServerError, "could not look up institution")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(tenant.With(r.Context(), inst.ID)))
+		})
+	}
+}
+
+// subdomain returns the label host has in front of baseDomain, or "" if
+// host is not a direct subdomain of it.
+func subdomain(host, baseDomain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	label, ok := strings.CutSuffix(strings.ToLower(host), "."+baseDomain)
+	if !ok || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type Appeal struct {
+	ID                     int        `json:"id"`
+	GradeID                int        `json:"grade_id"`
+	StudentID              int        `json:"student_id"`
+	Reason                 string     `json:"reason"`
+	Status                 string     `json:"status"`
+	LecturerID             *int       `json:"lecturer_id,omitempty"`
+	LecturerRecommendation string     `json:"lecturer_recommendation,omitempty"`
+	LecturerNote           string     `json:"lecturer_note,omitempty"`
+	ProposedScore          *float64   `json:"proposed_score,omitempty"`
+	ReviewedAt             *time.Time `json:"reviewed_at,omitempty"`
+	AdminID                *int       `json:"admin_id,omitempty"`
+	AdminNote              string     `json:"admin_note,omitempty"`
+	DecidedAt              *time.Time `json:"decided_at,omitempty"`
+	CreatedAt              time.Time  `json:"created_at"`
+}
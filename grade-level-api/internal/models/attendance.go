@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+type ClassSession struct {
+	ID            int        `json:"id"`
+	CourseID      int        `json:"course_id"`
+	SemesterID    int        `json:"semester_id"`
+	Topic         string     `json:"topic"`
+	HeldAt        time.Time  `json:"held_at"`
+	Code          string     `json:"code,omitempty"`
+	CodeExpiresAt *time.Time `json:"code_expires_at,omitempty"`
+}
+
+type Attendance struct {
+	ID         int       `json:"id"`
+	SessionID  int       `json:"session_id"`
+	StudentID  int       `json:"student_id"`
+	Method     string    `json:"method"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+type AttendanceSummary struct {
+	StudentID    int     `json:"student_id"`
+	StudentName  string  `json:"student_name"`
+	Attended     int     `json:"attended"`
+	Total        int     `json:"total"`
+	Percentage   float64 `json:"percentage"`
+	ExamEligible bool    `json:"exam_eligible"`
+}
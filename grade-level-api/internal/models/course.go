@@ -4,5 +4,13 @@ type Course struct {
 	ID         int    `json:"id"`
 	Name       string `json:"name"`
 	Level      int    `json:"level"`
+	Units      int    `json:"units"`
 	LecturerID int    `json:"LecturerID"`
+	// DepartmentID is nil until an admin assigns the course to a department.
+	DepartmentID *int `json:"department_id,omitempty"`
+}
+
+type Department struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
 }
@@ -1,8 +1,31 @@
 package models
 
+import "time"
+
 type Enrollment struct {
 	ID         int `json:"id"`
 	StudentID  int `json:"studentID"`
 	CourseID   int `json:"courseID"`
 	SemesterID int `json:"semesterID"`
+	// Status is "enrolled", or "waitlisted" when the offering was full, in
+	// which case ID is zero and Position is the student's place in line.
+	Status   string `json:"status"`
+	Position int    `json:"position,omitempty"`
+}
+
+// Offering is a course as taught in one semester. Capacity is nil when
+// enrollment is unlimited.
+type Offering struct {
+	CourseID   int  `json:"course_id"`
+	SemesterID int  `json:"semester_id"`
+	Capacity   *int `json:"capacity"`
+	Enrolled   int  `json:"enrolled"`
+	Waitlisted int  `json:"waitlisted"`
+}
+
+type WaitlistEntry struct {
+	Position    int       `json:"position"`
+	StudentID   int       `json:"student_id"`
+	StudentName string    `json:"student_name"`
+	CreatedAt   time.Time `json:"created_at"`
 }
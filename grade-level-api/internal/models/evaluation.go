@@ -0,0 +1,57 @@
+package models
+
+type EvaluationQuestion struct {
+	ID       int    `json:"id"`
+	Prompt   string `json:"prompt"`
+	Kind     string `json:"kind"`
+	Position int    `json:"position"`
+	Active   bool   `json:"active"`
+}
+
+// EvaluationAnswer answers one question: Rating for Likert questions,
+// Comment for free-text ones.
+type EvaluationAnswer struct {
+	QuestionID int    `json:"question_id"`
+	Rating     *int   `json:"rating,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+}
+
+// QuestionResult aggregates the answers to one question of a course
+// offering's evaluation.
+type QuestionResult struct {
+	QuestionID int    `json:"question_id"`
+	Prompt     string `json:"prompt"`
+	Kind       string `json:"kind"`
+	Answers    int    `json:"answers"`
+	// MeanRating and Distribution are set for Likert questions;
+	// Distribution[i] counts ratings of i+1.
+	MeanRating   *float64 `json:"mean_rating,omitempty"`
+	Distribution []int    `json:"distribution,omitempty"`
+	Comments     []string `json:"comments,omitempty"`
+}
+
+type EvaluationReport struct {
+	CourseID   int              `json:"course_id"`
+	SemesterID int              `json:"semester_id"`
+	Responses  int              `json:"responses"`
+	Questions  []QuestionResult `json:"questions"`
+}
+
+// LecturerRating is a lecturer's mean Likert rating over their evaluated
+// courses in one department.
+type LecturerRating struct {
+	DepartmentID   *int    `json:"department_id"`
+	DepartmentName string  `json:"department_name"`
+	LecturerID     int     `json:"lecturer_id"`
+	LecturerName   string  `json:"lecturer_name"`
+	Courses        int     `json:"courses"`
+	Responses      int     `json:"responses"`
+	MeanRating     float64 `json:"mean_rating"`
+	Rank           int     `json:"rank"`
+}
+
+type DepartmentRanking struct {
+	DepartmentID   *int             `json:"department_id"`
+	DepartmentName string           `json:"department_name"`
+	Lecturers      []LecturerRating `json:"lecturers"`
+}
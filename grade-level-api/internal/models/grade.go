@@ -1,7 +1,35 @@
 package models
 
+import "time"
+
 type Grade struct {
-	ID           int     `json:"id"`
-	EnrollmentID int     `json:"enrollmentid"`
-	Score        float64 `json:"score"`
+	ID           int        `json:"id"`
+	EnrollmentID int        `json:"enrollmentid"`
+	Score        float64    `json:"score"`
+	PublishedAt  *time.Time `json:"published_at,omitempty"`
+}
+
+// StudentGrade is a grade joined with the course and semester it belongs to.
+type StudentGrade struct {
+	GradeID      int        `json:"grade_id"`
+	StudentID    int        `json:"student_id"`
+	CourseID     int        `json:"course_id"`
+	CourseName   string     `json:"course_name"`
+	SemesterID   int        `json:"semester_id"`
+	SemesterName string     `json:"semester_name"`
+	Score        float64    `json:"score"`
+	PublishedAt  *time.Time `json:"published_at,omitempty"`
+}
+
+// GradeChange records every change made to a grade after it was first
+// posted, so adjusted results can be traced back to who changed them and why.
+type GradeChange struct {
+	ID        int       `json:"id"`
+	GradeID   int       `json:"grade_id"`
+	OldScore  float64   `json:"old_score"`
+	NewScore  float64   `json:"new_score"`
+	Reason    string    `json:"reason"`
+	AppealID  *int      `json:"appeal_id,omitempty"`
+	ChangedBy int       `json:"changed_by"`
+	ChangedAt time.Time `json:"changed_at"`
 }
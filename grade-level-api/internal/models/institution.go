@@ -0,0 +1,9 @@
+package models
+
+// Institution is a school hosted by the system. Its slug names it in the
+// X-Institution header and as a subdomain.
+type Institution struct {
+	ID   int    `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
@@ -0,0 +1,50 @@
+package models
+
+// Programme is what a student of a department must achieve to graduate.
+type Programme struct {
+	DepartmentID  int                   `json:"department_id"`
+	MinCGPA       float64               `json:"min_cgpa"`
+	CoreCourseIDs []int                 `json:"core_course_ids"`
+	Electives     []ElectiveRequirement `json:"electives"`
+}
+
+// ElectiveRequirement is the fewest units a student must pass at a level
+// in courses outside the programme's core.
+type ElectiveRequirement struct {
+	Level    int `json:"level"`
+	MinUnits int `json:"min_units"`
+}
+
+// RequirementCheck is one requirement of a programme measured against a
+// student's results: courses passed for a core course, units for
+// electives, or the CGPA.
+type RequirementCheck struct {
+	Kind        string  `json:"kind"`
+	Description string  `json:"description"`
+	CourseID    int     `json:"course_id,omitempty"`
+	Level       int     `json:"level,omitempty"`
+	Required    float64 `json:"required"`
+	Achieved    float64 `json:"achieved"`
+	// Score is the best published score in a core course, if any.
+	Score *float64 `json:"score,omitempty"`
+}
+
+type DegreeAudit struct {
+	StudentID    int                `json:"student_id"`
+	StudentName  string             `json:"student_name"`
+	DepartmentID int                `json:"department_id"`
+	CGPA         float64            `json:"cgpa"`
+	Units        int                `json:"units"`
+	Satisfied    []RequirementCheck `json:"satisfied"`
+	Outstanding  []RequirementCheck `json:"outstanding"`
+	Eligible     bool               `json:"eligible"`
+}
+
+// CohortAudit is the degree audit of every student in a graduating cohort.
+type CohortAudit struct {
+	DepartmentID int           `json:"department_id"`
+	SemesterID   int           `json:"semester_id,omitempty"`
+	Students     int           `json:"students"`
+	Eligible     int           `json:"eligible"`
+	Audits       []DegreeAudit `json:"audits"`
+}
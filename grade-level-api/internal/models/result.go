@@ -0,0 +1,49 @@
+package models
+
+// CourseResult is a published score with what is needed to weigh it in a
+// GPA.
+type CourseResult struct {
+	StudentID  int
+	CourseID   int
+	SemesterID int
+	Level      int
+	Units      int
+	Score      float64
+}
+
+type BroadsheetCell struct {
+	Score  *float64 `json:"score"`
+	Grade  string   `json:"grade"`
+	Points float64  `json:"points"`
+}
+
+type BroadsheetRow struct {
+	StudentID   int    `json:"student_id"`
+	StudentName string `json:"student_name"`
+	// Results holds one cell per course, in the order of Broadsheet.Courses.
+	Results []BroadsheetCell `json:"results"`
+	Units   int              `json:"units"`
+	GPA     float64          `json:"gpa"`
+	CGPA    float64          `json:"cgpa"`
+	Remark  string           `json:"remark"`
+}
+
+// Broadsheet is the end-of-semester result sheet for one level.
+type Broadsheet struct {
+	Level        int             `json:"level"`
+	SemesterID   int             `json:"semester_id"`
+	SemesterName string          `json:"semester_name"`
+	Courses      []Course        `json:"courses"`
+	Rows         []BroadsheetRow `json:"rows"`
+}
+
+// Standing is a student's overall result for a semester, without the
+// per-course breakdown of a broadsheet row.
+type Standing struct {
+	StudentID   int     `json:"student_id"`
+	StudentName string  `json:"student_name"`
+	Units       int     `json:"units"`
+	GPA         float64 `json:"gpa"`
+	CGPA        float64 `json:"cgpa"`
+	Remark      string  `json:"remark"`
+}
@@ -7,4 +7,7 @@ type Semester struct {
 	Name      string    `json:"name"`
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
+	// EnrollmentOpen is whether students may currently enroll in the
+	// semester's courses.
+	EnrollmentOpen bool `json:"enrollment_open"`
 }
@@ -1,9 +1,17 @@
 package models
 
+import "time"
+
 type User struct {
-	ID       int    `json:"id"`
-	Name     string `json:"name"`
-	Email    string `json:"email"`
-	Password string `json:"password"`
-	Role     string `json:"role"`
+	ID                  int        `json:"id"`
+	Name                string     `json:"name"`
+	Email               string     `json:"email"`
+	Password            string     `json:"password"`
+	Role                string     `json:"role"`
+	FailedLoginAttempts int        `json:"failed_login_attempts,omitempty"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at,omitempty"`
+	// DepartmentID is the department whose programme a student is on, nil
+	// until an admin assigns one.
+	DepartmentID *int `json:"department_id,omitempty"`
 }
@@ -0,0 +1,282 @@
+// Package openapi builds an OpenAPI 3 document from route descriptions.
+// Request and response schemas are derived from the Go types themselves,
+// including their `validate` tags, so the document cannot drift from the
+// validation the handlers apply.
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/validate"
+)
+
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+	Type        string // "integer" or "string"; defaults to "string"
+}
+
+type Operation struct {
+	Method  string
+	Summary string
+	// Roles lists who may call the operation; empty means it is public.
+	Roles []string
+	Query []Param
+	// Request is a value of the JSON body type, or nil for no body.
+	Request any
+	// Response is a value of the success response type, or nil for none.
+	Response any
+	// Status is the success status code, 200 when zero.
+	Status int
+	// ContentType overrides the success response media type.
+	ContentType string
+}
+
+type Path struct {
+	Pattern    string
+	Operations []Operation
+}
+
+type Document struct {
+	OpenAPI    string                               `json:"openapi"`
+	Info       map[string]string                    `json:"info"`
+	Paths      map[string]map[string]map[string]any `json:"paths"`
+	Components map[string]any                       `json:"components"`
+}
+
+var pathParam = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+func Build(title, version string, paths []Path) *Document {
+	b := &builder{schemas: map[string]any{
+		"Error": map[string]any{
+			"type":       "object",
+			"required":   []string{"error"},
+			"properties": map[string]any{"error": map[string]any{"type": "string"}},
+		},
+	}}
+	b.schemas["ValidationError"] = map[string]any{
+		"type":     "object",
+		"required": []string{"error", "details"},
+		"properties": map[string]any{
+			"error":   map[string]any{"type": "string"},
+			"details": map[string]any{"type": "array", "items": b.schema(reflect.TypeFor[validate.FieldError]())},
+		},
+	}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info:    map[string]string{"title": title, "version": version},
+		Paths:   map[string]map[string]map[string]any{},
+	}
+	for _, p := range paths {
+		item := map[string]map[string]any{}
+		for _, op := range p.Operations {
+			item[strings.ToLower(op.Method)] = b.operation(p.Pattern, op)
+		}
+		doc.Paths[p.Pattern] = item
+	}
+	doc.Components = map[string]any{
+		"schemas": b.schemas,
+		"securitySchemes": map[string]any{
+			"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+		},
+	}
+	return doc
+}
+
+type builder struct {
+	schemas map[string]any
+}
+
+func (b *builder) operation(pattern string, op Operation) map[string]any {
+	out := map[string]any{
+		"summary":     op.Summary,
+		"operationId": operationID(op.Method, pattern),
+	}
+
+	var params []map[string]any
+	for _, m := range pathParam.FindAllStringSubmatch(pattern, -1) {
+		params = append(params, map[string]any{
+			"name": m[1], "in": "path", "required": true,
+			"schema": map[string]any{"type": "integer", "minimum": 1},
+		})
+	}
+	for _, q := range op.Query {
+		typ := q.Type
+		if typ == "" {
+			typ = "string"
+		}
+		param := map[string]any{
+			"name": q.Name, "in": "query", "required": q.Required,
+			"schema": map[string]any{"type": typ},
+		}
+		if q.Description != "" {
+			param["description"] = q.Description
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		out["parameters"] = params
+	}
+
+	responses := map[string]any{}
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]any{"description": http.StatusText(status)}
+	if op.Response != nil {
+		contentType := op.ContentType
+		schema := map[string]any{"type": "string"}
+		if contentType == "" {
+			contentType = "application/json"
+			schema = b.schema(reflect.TypeOf(op.Response))
+		}
+		success["content"] = map[string]any{contentType: map[string]any{"schema": schema}}
+	}
+	responses[strconv.Itoa(status)] = success
+
+	if op.Request != nil {
+		out["requestBody"] = map[string]any{
+			"required": true,
+			"content": map[string]any{
+				"application/json": map[string]any{"schema": b.schema(reflect.TypeOf(op.Request))},
+			},
+		}
+		responses["400"] = errorResponse("Invalid request", "ValidationError")
+	}
+
+	if len(op.Roles) > 0 {
+		out["security"] = []map[string][]string{{"bearerAuth": {}}}
+		out["description"] = "Allowed roles: " + strings.Join(op.Roles, ", ") + "."
+		responses["401"] = errorResponse("Missing or invalid token", "Error")
+		responses["403"] = errorResponse("Role not allowed", "Error")
+	}
+	out["responses"] = responses
+	return out
+}
+
+func errorResponse(description, schema string) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content": map[string]any{
+			"application/json": map[string]any{"schema": ref(schema)},
+		},
+	}
+}
+
+func operationID(method, pattern string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(pattern, func(r rune) bool {
+		return r == '/' || r == '{' || r == '}' || r == '.' || r == '_' || r == '-'
+	}) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func ref(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+var timeType = reflect.TypeFor[time.Time]()
+
+func (b *builder) schema(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, ok := b.schemas[t.Name()]; !ok {
+			b.schemas[t.Name()] = nil // placeholder for recursive types
+			b.schemas[t.Name()] = b.structSchema(t)
+		}
+		return ref(t.Name())
+	}
+	return map[string]any{}
+}
+
+func (b *builder) structSchema(t reflect.Type) map[string]any {
+	props := map[string]any{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() || f.Tag.Get("json") == "-" {
+			continue
+		}
+		name := validate.FieldName(f)
+		prop := b.schema(f.Type)
+		for _, rule := range validate.Rules(f.Tag.Get("validate")) {
+			if rule.Name == "required" {
+				required = append(required, name)
+				continue
+			}
+			prop = withRule(prop, f.Type, rule)
+		}
+		props[name] = prop
+	}
+	s := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		s["required"] = required
+	}
+	return s
+}
+
+func withRule(prop map[string]any, t reflect.Type, rule validate.Rule) map[string]any {
+	out := map[string]any{}
+	for k, v := range prop {
+		out[k] = v
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch rule.Name {
+	case "email":
+		out["format"] = "email"
+	case "date":
+		out["format"] = "date"
+	case "datetime":
+		out["format"] = "date-time"
+	case "oneof":
+		out["enum"] = strings.Fields(rule.Param)
+	case "min", "max":
+		n, _ := strconv.ParseFloat(rule.Param, 64)
+		key := map[reflect.Kind]string{reflect.String: "Length", reflect.Slice: "Items", reflect.Array: "Items"}[t.Kind()]
+		if key == "" {
+			key = map[string]string{"min": "minimum", "max": "maximum"}[rule.Name]
+		} else {
+			key = rule.Name + key
+		}
+		out[key] = n
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+// Package ratelimit implements token bucket rate limiting on top of a
+// pluggable bucket store.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type Bucket struct {
+	Tokens  float64
+	Updated time.Time
+}
+
+// Store holds buckets by key. Update must run fn atomically for a given
+// key; fn receives the zero Bucket when the key has no bucket yet.
+type Store interface {
+	Update(key string, fn func(Bucket) Bucket)
+}
+
+type Limiter struct {
+	store  Store
+	prefix string
+	rate   float64 // tokens per second
+	burst  float64
+	now    func() time.Time
+}
+
+// New returns a limiter allowing burst requests at once, refilled at
+// perMinute requests per minute. prefix namespaces the keys so several
+// limiters can share one store.
+func New(store Store, prefix string, perMinute, burst int) *Limiter {
+	return &Limiter{
+		store:  store,
+		prefix: prefix,
+		rate:   float64(perMinute) / 60,
+		burst:  float64(burst),
+		now:    time.Now,
+	}
+}
+
+// Allow takes a token for key. When none is left it reports how long the
+// caller should wait before retrying.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	var (
+		allowed    bool
+		retryAfter time.Duration
+	)
+	now := l.now()
+	l.store.Update(l.prefix+":"+key, func(b Bucket) Bucket {
+		if b.Updated.IsZero() {
+			b = Bucket{Tokens: l.burst, Updated: now}
+		}
+		elapsed := now.Sub(b.Updated).Seconds()
+		b.Tokens = math.Min(l.burst, b.Tokens+elapsed*l.rate)
+		b.Updated = now
+
+		if b.Tokens >= 1 {
+			b.Tokens--
+			allowed = true
+			return b
+		}
+		retryAfter = time.Duration((1 - b.Tokens) / l.rate * float64(time.Second))
+		return b
+	})
+	return allowed, retryAfter
+}
+
+// MemoryStore keeps buckets in process memory. Buckets untouched for
+// longer than idle are dropped so the map does not grow without bound.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]Bucket
+	idle      time.Duration
+	lastSweep time.Time
+}
+
+func NewMemoryStore(idle time.Duration) *MemoryStore {
+	return &MemoryStore{buckets: map[string]Bucket{}, idle: idle}
+}
+
+func (s *MemoryStore) Update(key string, fn func(Bucket) Bucket) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) > s.idle {
+		for k, b := range s.buckets {
+			if now.Sub(b.Updated) > s.idle {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	s.buckets[key] = fn(s.buckets[key])
+}
@@ -0,0 +1,30 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	l := New(NewMemoryStore(time.Hour), "login", 6, 2)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("1.2.3.4")
+	assert.True(t, ok)
+	ok, _ = l.Allow("1.2.3.4")
+	assert.True(t, ok)
+
+	ok, retryAfter := l.Allow("1.2.3.4")
+	assert.False(t, ok, "burst should be exhausted")
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	ok, _ = l.Allow("5.6.7.8")
+	assert.True(t, ok, "other keys have their own bucket")
+
+	now = now.Add(10 * time.Second)
+	ok, _ = l.Allow("1.2.3.4")
+	assert.True(t, ok, "a token should be refilled after retryAfter")
+}
@@ -0,0 +1,1118 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/dbtest"
+	"github.com/falasefemi2/gradesystem/internal/mail"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/server"
+	"github.com/falasefemi2/gradesystem/internal/service"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+)
+
+const testPassword = "correct-horse-battery"
+
+// testServer boots the full handler stack, middleware included, against
+// a fresh database.
+func testServer(t *testing.T) *client {
+	t.Helper()
+	dbtest.Open(t)
+	config.Get().TokenSecret = "test-token-secret"
+	outbox := &mail.Outbox{}
+	service.SetMailer(outbox)
+
+	logger := slog.New(slog.NewJSONHandler(io.Discard, nil))
+	srv := httptest.NewServer(server.NewHandler(config.Get(), logger))
+	t.Cleanup(srv.Close)
+	return &client{t: t, url: srv.URL, outbox: outbox}
+}
+
+type client struct {
+	t      *testing.T
+	url    string
+	token  string
+	outbox *mail.Outbox
+	// institution is sent as X-Institution when set.
+	institution string
+}
+
+// header sets the headers every request from c carries.
+func (c *client) header(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.institution != "" {
+		req.Header.Set(middleware.InstitutionHeader, c.institution)
+	}
+}
+
+// at returns an unauthenticated client for the institution.
+func (c *client) at(slug string) *client {
+	return &client{t: c.t, url: c.url, outbox: c.outbox, institution: slug}
+}
+
+// do sends body as JSON and decodes a JSON response into out, if given.
+func (c *client) do(method, path string, body, out any) int {
+	c.t.Helper()
+
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		require.NoError(c.t, err)
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.url+path, r)
+	require.NoError(c.t, err)
+	req.Header.Set("Content-Type", "application/json")
+	c.header(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+
+	if out != nil {
+		require.NoError(c.t, json.NewDecoder(resp.Body).Decode(out), "%s %s", method, path)
+	}
+	return resp.StatusCode
+}
+
+// raw performs a GET and returns the status, headers and body as is.
+func (c *client) raw(path string) (int, http.Header, string) {
+	c.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	require.NoError(c.t, err)
+	c.header(req)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(c.t, err)
+	return resp.StatusCode, resp.Header, string(body)
+}
+
+// as returns a client authenticated as the given account.
+func (c *client) as(email, password string) *client {
+	c.t.Helper()
+
+	var resp map[string]string
+	status := c.do(http.MethodPost, "/login", map[string]string{"email": email, "password": password}, &resp)
+	require.Equal(c.t, http.StatusOK, status, "login as %s", email)
+	return &client{t: c.t, url: c.url, token: resp["token"], outbox: c.outbox, institution: c.institution}
+}
+
+var mailedToken = regexp.MustCompile(`token[^:]*: (\S+)`)
+
+// lastToken returns the token in the most recent email sent to addr.
+func (c *client) lastToken(addr string) string {
+	c.t.Helper()
+
+	msgs := c.outbox.Messages()
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].To == addr {
+			m := mailedToken.FindStringSubmatch(msgs[i].Body)
+			require.NotNil(c.t, m, "no token in %q", msgs[i].Body)
+			return m[1]
+		}
+	}
+	c.t.Fatalf("no email sent to %s", addr)
+	return ""
+}
+
+func seedUser(t *testing.T, name, email string, role db.Role) *models.User {
+	t.Helper()
+	user, err := service.Register(t.Context(), name, email, testPassword, role)
+	require.NoError(t, err)
+	require.NoError(t, db.MarkEmailVerified(t.Context(), user.ID, time.Now()))
+	return user
+}
+
+func seedSemester(t *testing.T) *models.Semester {
+	t.Helper()
+	return seedSemesterStarting(t, time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func seedSemesterStarting(t *testing.T, start time.Time) *models.Semester {
+	t.Helper()
+	semester, err := db.CreateSemester(t.Context(), db.FirstSemster, start, start.AddDate(0, 4, 0))
+	require.NoError(t, err)
+	return semester
+}
+
+// seedPublishedGrade enrolls a student and publishes their score.
+func seedPublishedGrade(t *testing.T, studentID, courseID, semesterID int, score float64) {
+	t.Helper()
+	_, err := db.EnrollStudent(t.Context(), studentID, courseID, semesterID)
+	require.NoError(t, err)
+	_, err = db.SaveStudentGrade(t.Context(), studentID, courseID, semesterID, score)
+	require.NoError(t, err)
+	_, err = db.PublishGrades(t.Context(), courseID, semesterID)
+	require.NoError(t, err)
+}
+
+func TestSignupLoginCreateAndListCourses(t *testing.T) {
+	c := testServer(t)
+
+	var student models.User
+	status := c.do(http.MethodPost, "/signup", map[string]string{
+		"name": "Mallory", "email": "mallory@example.com", "password": testPassword, "role": "admin",
+	}, &student)
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, string(db.Student), student.Role, "signup cannot choose a role")
+
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	admin := c.as("admin@example.com", testPassword)
+
+	var lecturer models.User
+	status = admin.do(http.MethodPost, "/admin/users", map[string]string{
+		"name": "Ada", "email": "ada@example.com", "password": testPassword, "role": "lecturer",
+	}, &lecturer)
+	require.Equal(t, http.StatusCreated, status)
+	assert.NotZero(t, lecturer.ID)
+	assert.Equal(t, string(db.Lecturer), lecturer.Role)
+	assert.Empty(t, lecturer.Password)
+
+	assert.Equal(t, http.StatusConflict, c.do(http.MethodPost, "/signup", map[string]string{
+		"name": "Ada", "email": "ada@example.com", "password": testPassword,
+	}, nil), "duplicate email")
+	assert.Equal(t, http.StatusConflict, admin.do(http.MethodPost, "/admin/users", map[string]string{
+		"name": "Ada", "email": "ADA@example.com", "password": testPassword, "role": "admin",
+	}, nil), "duplicate email, whatever its case")
+
+	assert.Equal(t, http.StatusUnauthorized, c.do(http.MethodPost, "/login", map[string]string{
+		"email": "ada@example.com", "password": "wrong-password",
+	}, nil))
+
+	assert.Equal(t, http.StatusForbidden, c.do(http.MethodPost, "/login", map[string]string{
+		"email": "ada@example.com", "password": testPassword,
+	}, nil), "email not verified yet")
+
+	token := c.lastToken("ada@example.com")
+	require.Equal(t, http.StatusOK, c.do(http.MethodPost, "/email/verify", map[string]string{"token": token}, nil))
+	assert.Equal(t, http.StatusBadRequest, c.do(http.MethodPost, "/email/verify", map[string]string{"token": token}, nil), "token reused")
+
+	lc := c.as("ada@example.com", testPassword)
+
+	var course models.Course
+	status = lc.do(http.MethodPost, "/courses", map[string]any{"name": "Compilers", "level": 400}, &course)
+	require.Equal(t, http.StatusCreated, status)
+	assert.Equal(t, "Compilers", course.Name)
+	assert.Equal(t, lecturer.ID, course.LecturerID)
+
+	var own []models.Course
+	require.Equal(t, http.StatusOK, lc.do(http.MethodGet, "/courses", nil, &own))
+	require.Len(t, own, 1)
+	assert.Equal(t, course.ID, own[0].ID)
+
+	seedUser(t, "Grace", "grace@example.com", db.Student)
+	sc := c.as("grace@example.com", testPassword)
+
+	var atLevel []models.Course
+	require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/courses?level=400", nil, &atLevel))
+	require.Len(t, atLevel, 1)
+	assert.Equal(t, course.ID, atLevel[0].ID)
+
+	require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/courses?level=100", nil, &atLevel))
+	assert.Empty(t, atLevel)
+
+	assert.Equal(t, http.StatusForbidden, sc.do(http.MethodPost, "/courses", map[string]any{"name": "Hacking", "level": 100}, nil))
+	assert.Equal(t, http.StatusForbidden, sc.do(http.MethodPost, "/admin/users", map[string]string{
+		"name": "Eve", "email": "eve@example.com", "password": testPassword, "role": "admin",
+	}, nil))
+}
+
+func TestCourseRoutesRequireAuth(t *testing.T) {
+	c := testServer(t)
+
+	assert.Equal(t, http.StatusUnauthorized, c.do(http.MethodGet, "/courses", nil, nil))
+
+	c.token = "not-a-jwt"
+	assert.Equal(t, http.StatusUnauthorized, c.do(http.MethodGet, "/courses", nil, nil))
+}
+
+// revalidate performs a GET with If-None-Match set to etag and returns the
+// status and the ETag of the response.
+func (c *client) revalidate(path, etag string) (int, string) {
+	c.t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, c.url+path, nil)
+	require.NoError(c.t, err)
+	c.header(req)
+	req.Header.Set("If-None-Match", etag)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("ETag")
+}
+
+func TestCourseReadsRevalidate(t *testing.T) {
+	c := testServer(t)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	seedUser(t, "Grace", "grace@example.com", db.Student)
+	course, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+
+	sc := c.as("grace@example.com", testPassword)
+	status, header, _ := sc.raw("/courses?level=400")
+	require.Equal(t, http.StatusOK, status)
+	etag := header.Get("ETag")
+	require.NotEmpty(t, etag)
+
+	status, _ = sc.revalidate("/courses?level=400", etag)
+	assert.Equal(t, http.StatusNotModified, status, "unchanged list")
+
+	lc := c.as("ada@example.com", testPassword)
+	require.Equal(t, http.StatusOK, lc.do(http.MethodPut, "/courses/"+strconv.Itoa(course.ID),
+		map[string]any{"name": "Compiler Construction", "level": 400}, nil))
+
+	status, fresh := sc.revalidate("/courses?level=400", etag)
+	assert.Equal(t, http.StatusOK, status, "update invalidates the cached list")
+	assert.NotEqual(t, etag, fresh)
+
+	var courses []models.Course
+	require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/courses?level=400", nil, &courses))
+	require.Len(t, courses, 1)
+	assert.Equal(t, "Compiler Construction", courses[0].Name)
+}
+
+func TestInstitutionsAreIsolated(t *testing.T) {
+	c := testServer(t)
+	north, err := db.CreateInstitution(t.Context(), "north", "North College")
+	require.NoError(t, err)
+	northCtx := tenant.With(t.Context(), north.ID)
+
+	// The same people, course level and semester in both institutions.
+	type campus struct {
+		course, semester, grade int
+	}
+	seed := func(ctx context.Context, courseName string) campus {
+		t.Helper()
+		lecturer, err := service.Register(ctx, "Ada", "ada@example.com", testPassword, db.Lecturer)
+		require.NoError(t, err)
+		student, err := service.Register(ctx, "Grace", "grace@example.com", testPassword, db.Student)
+		require.NoError(t, err)
+		for _, u := range []*models.User{lecturer, student} {
+			require.NoError(t, db.MarkEmailVerified(ctx, u.ID, time.Now()))
+		}
+		course, err := db.CreateCourse(ctx, courseName, 400, 3, lecturer.ID)
+		require.NoError(t, err)
+		start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+		semester, err := db.CreateSemester(ctx, db.FirstSemster, start, start.AddDate(0, 4, 0))
+		require.NoError(t, err)
+		_, err = db.EnrollStudent(ctx, student.ID, course.ID, semester.ID)
+		require.NoError(t, err)
+		grade, err := db.SaveStudentGrade(ctx, student.ID, course.ID, semester.ID, 70)
+		require.NoError(t, err)
+		_, err = db.PublishGrades(ctx, course.ID, semester.ID)
+		require.NoError(t, err)
+		return campus{course.ID, semester.ID, grade.ID}
+	}
+	home := seed(t.Context(), "Compilers")
+	away := seed(northCtx, "Databases")
+
+	for _, tc := range []struct {
+		name       string
+		client     *client
+		own, other campus
+		courseName string
+	}{
+		{"default institution", c, home, away, "Compilers"},
+		{"north by header", c.at("north"), away, home, "Databases"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sc := tc.client.as("grace@example.com", testPassword)
+			lc := tc.client.as("ada@example.com", testPassword)
+
+			var courses []models.Course
+			require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/courses?level=400", nil, &courses))
+			require.Len(t, courses, 1)
+			assert.Equal(t, tc.courseName, courses[0].Name)
+			assert.Equal(t, http.StatusOK, lc.do(http.MethodGet, "/courses/"+strconv.Itoa(tc.own.course), nil, nil))
+			assert.Equal(t, http.StatusNotFound, lc.do(http.MethodGet, "/courses/"+strconv.Itoa(tc.other.course), nil, nil))
+
+			var semesters []models.Semester
+			require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/semesters", nil, &semesters))
+			require.Len(t, semesters, 1)
+			assert.Equal(t, tc.own.semester, semesters[0].ID)
+
+			var grades []models.StudentGrade
+			require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/grades", nil, &grades))
+			require.Len(t, grades, 1)
+			assert.Equal(t, tc.own.grade, grades[0].GradeID)
+
+			assert.Equal(t, http.StatusNotFound, sc.do(http.MethodPost, "/courses/"+strconv.Itoa(tc.other.course)+"/enrollments",
+				map[string]int{"semester_id": tc.other.semester}, nil), "cannot enroll in another institution's course")
+		})
+	}
+
+	// Nothing written through one institution is visible to the other.
+	homeUsers, err := db.GetAllUsers(t.Context())
+	require.NoError(t, err)
+	northUsers, err := db.GetAllUsers(northCtx)
+	require.NoError(t, err)
+	require.Len(t, homeUsers, 2)
+	require.Len(t, northUsers, 2)
+	assert.NotEqual(t, homeUsers[0].ID, northUsers[0].ID)
+	_, err = db.FindStudentGrade(northCtx, home.grade)
+	assert.Error(t, err)
+	enrolled, err := db.ListEnrolledStudents(northCtx, home.course, home.semester)
+	require.NoError(t, err)
+	assert.Empty(t, enrolled)
+
+	t.Run("tokens only work where they were issued", func(t *testing.T) {
+		sc := c.as("grace@example.com", testPassword)
+		sc.institution = "north"
+		assert.Equal(t, http.StatusUnauthorized, sc.do(http.MethodGet, "/grades", nil, nil))
+		assert.Equal(t, http.StatusNotFound, c.at("nowhere").do(http.MethodGet, "/semesters", nil, nil))
+	})
+
+	t.Run("subdomain", func(t *testing.T) {
+		cfg := *config.Get()
+		cfg.TenantDomain = "grades.test"
+		srv := httptest.NewServer(server.NewHandler(&cfg, slog.New(slog.NewJSONHandler(io.Discard, nil))))
+		defer srv.Close()
+
+		login := func(host string) *http.Response {
+			body := strings.NewReader(`{"email":"grace@example.com","password":"` + testPassword + `"}`)
+			req, err := http.NewRequest(http.MethodPost, srv.URL+"/login", body)
+			require.NoError(t, err)
+			req.Host = host
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			resp.Body.Close()
+			return resp
+		}
+		assert.Equal(t, http.StatusOK, login("north.grades.test").StatusCode)
+		assert.Equal(t, http.StatusNotFound, login("south.grades.test").StatusCode)
+	})
+}
+
+func TestEnrollmentAgainstSeededFixtures(t *testing.T) {
+	c := testServer(t)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	seedUser(t, "Grace", "grace@example.com", db.Student)
+	semester := seedSemester(t)
+	course, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+
+	sc := c.as("grace@example.com", testPassword)
+	path := "/courses/" + strconv.Itoa(course.ID) + "/enrollments"
+
+	var enrollment models.Enrollment
+	require.Equal(t, http.StatusCreated, sc.do(http.MethodPost, path, map[string]int{"semester_id": semester.ID}, &enrollment))
+	assert.Equal(t, course.ID, enrollment.CourseID)
+
+	assert.Equal(t, http.StatusBadRequest, sc.do(http.MethodPost, path, map[string]int{"semester_id": semester.ID}, nil), "duplicate enrollment")
+	assert.Equal(t, http.StatusNotFound, sc.do(http.MethodPost, "/courses/999/enrollments", map[string]int{"semester_id": semester.ID}, nil))
+
+	students, err := db.ListEnrolledStudents(t.Context(), course.ID, semester.ID)
+	require.NoError(t, err)
+	require.Len(t, students, 1)
+	assert.Equal(t, "grace@example.com", students[0].Email)
+}
+
+func TestWaitlist(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	grace := seedUser(t, "Grace", "grace@example.com", db.Student)
+	alan := seedUser(t, "Alan", "alan@example.com", db.Student)
+	barbara := seedUser(t, "Barbara", "barbara@example.com", db.Student)
+	semester := seedSemester(t)
+	course, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+
+	admin := c.as("admin@example.com", testPassword)
+	lc := c.as("ada@example.com", testPassword)
+	gc := c.as("grace@example.com", testPassword)
+	ac := c.as("alan@example.com", testPassword)
+	alanEvents := ac.stream()
+	bc := c.as("barbara@example.com", testPassword)
+
+	base := "/courses/" + strconv.Itoa(course.ID)
+	semesterQuery := "?semester_id=" + strconv.Itoa(semester.ID)
+	var offering models.Offering
+	require.Equal(t, http.StatusOK, lc.do(http.MethodPut, base+"/capacity", map[string]int{"semester_id": semester.ID, "capacity": 1}, &offering))
+	require.NotNil(t, offering.Capacity)
+	assert.Equal(t, 1, *offering.Capacity)
+
+	enroll := map[string]int{"semester_id": semester.ID}
+	var enrollment models.Enrollment
+	require.Equal(t, http.StatusCreated, gc.do(http.MethodPost, base+"/enrollments", enroll, &enrollment))
+	assert.Equal(t, "enrolled", enrollment.Status)
+	require.Equal(t, http.StatusAccepted, ac.do(http.MethodPost, base+"/enrollments", enroll, &enrollment))
+	assert.Equal(t, "waitlisted", enrollment.Status)
+	assert.Equal(t, 1, enrollment.Position)
+	require.Equal(t, http.StatusAccepted, bc.do(http.MethodPost, base+"/enrollments", enroll, &enrollment))
+	assert.Equal(t, 2, enrollment.Position)
+
+	assert.Equal(t, http.StatusForbidden, gc.do(http.MethodPost, base+"/enrollments",
+		map[string]any{"semester_id": semester.ID, "override_capacity": true}, nil), "students cannot override capacity")
+
+	var waitlist []models.WaitlistEntry
+	require.Equal(t, http.StatusOK, lc.do(http.MethodGet, base+"/waitlist"+semesterQuery, nil, &waitlist))
+	require.Len(t, waitlist, 2)
+	assert.Equal(t, alan.ID, waitlist[0].StudentID)
+	assert.Equal(t, barbara.ID, waitlist[1].StudentID)
+
+	// Grace drops and Alan, first in line, takes her seat.
+	require.Equal(t, http.StatusOK, gc.do(http.MethodDelete, base+"/enrollments"+semesterQuery, nil, nil))
+	e := nextEvent(t, alanEvents)
+	assert.Equal(t, "waitlist_promoted", e.Type)
+	assert.Equal(t, "Compilers", e.Data["course_name"])
+	_, err = db.FindEnrollment(t.Context(), alan.ID, course.ID, semester.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, gc.do(http.MethodDelete, base+"/enrollments"+semesterQuery, nil, nil))
+
+	// The admin seats Grace again past capacity; Barbara keeps waiting.
+	require.Equal(t, http.StatusCreated, admin.do(http.MethodPost, base+"/enrollments",
+		map[string]any{"semester_id": semester.ID, "student_id": grace.ID, "override_capacity": true}, &enrollment))
+	assert.Equal(t, grace.ID, enrollment.StudentID)
+	require.Equal(t, http.StatusOK, admin.do(http.MethodGet, base+"/capacity"+semesterQuery, nil, &offering))
+	assert.Equal(t, 2, offering.Enrolled)
+	assert.Equal(t, 1, offering.Waitlisted)
+
+	require.Equal(t, http.StatusOK, admin.do(http.MethodPut, base+"/capacity", map[string]any{"semester_id": semester.ID, "capacity": nil}, &offering))
+	assert.Nil(t, offering.Capacity)
+	assert.Equal(t, 3, offering.Enrolled, "lifting the limit promotes Barbara")
+	assert.Zero(t, offering.Waitlisted)
+}
+
+func TestDegreeAudit(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	grace := seedUser(t, "Grace", "grace@example.com", db.Student)
+	alan := seedUser(t, "Alan", "alan@example.com", db.Student)
+	semester := seedSemester(t)
+	department, err := db.CreateDepartment(t.Context(), "Computer Science")
+	require.NoError(t, err)
+	compilers, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+	graphics, err := db.CreateCourse(t.Context(), "Graphics", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+	seedPublishedGrade(t, grace.ID, compilers.ID, semester.ID, 74)
+	seedPublishedGrade(t, grace.ID, graphics.ID, semester.ID, 66)
+	seedPublishedGrade(t, alan.ID, compilers.ID, semester.ID, 35)
+
+	admin := c.as("admin@example.com", testPassword)
+	gc := c.as("grace@example.com", testPassword)
+	base := "/departments/" + strconv.Itoa(department.ID)
+
+	assert.Equal(t, http.StatusNotFound, gc.do(http.MethodGet, base+"/programme", nil, nil))
+	assert.Equal(t, http.StatusForbidden, gc.do(http.MethodPut, base+"/programme", map[string]any{"min_cgpa": 1}, nil))
+	assert.Equal(t, http.StatusBadRequest, admin.do(http.MethodPut, base+"/programme", map[string]any{
+		"min_cgpa": 2, "core_course_ids": []int{999},
+	}, nil), "unknown core course")
+
+	var programme models.Programme
+	require.Equal(t, http.StatusOK, admin.do(http.MethodPut, base+"/programme", map[string]any{
+		"min_cgpa":        2.5,
+		"core_course_ids": []int{compilers.ID},
+		"electives":       []map[string]int{{"level": 400, "min_units": 3}},
+	}, &programme))
+	assert.Equal(t, []int{compilers.ID}, programme.CoreCourseIDs)
+
+	path := "/students/" + strconv.Itoa(grace.ID) + "/audit"
+	assert.Equal(t, http.StatusConflict, gc.do(http.MethodGet, path, nil, nil), "not on a programme yet")
+	for _, id := range []int{grace.ID, alan.ID} {
+		require.Equal(t, http.StatusOK, admin.do(http.MethodPost, base+"/students", map[string]int{"student_id": id}, nil))
+	}
+
+	var audit models.DegreeAudit
+	require.Equal(t, http.StatusOK, gc.do(http.MethodGet, path, nil, &audit))
+	assert.True(t, audit.Eligible)
+	assert.Len(t, audit.Satisfied, 3)
+	assert.Empty(t, audit.Outstanding)
+	assert.Equal(t, http.StatusForbidden, gc.do(http.MethodGet, "/students/"+strconv.Itoa(alan.ID)+"/audit", nil, nil))
+
+	var cohort models.CohortAudit
+	require.Equal(t, http.StatusOK, admin.do(http.MethodGet, base+"/graduation?semester_id="+strconv.Itoa(semester.ID), nil, &cohort))
+	assert.Equal(t, 2, cohort.Students)
+	assert.Equal(t, 1, cohort.Eligible)
+	require.Len(t, cohort.Audits, 2)
+	assert.Equal(t, "Alan", cohort.Audits[0].StudentName)
+	assert.False(t, cohort.Audits[0].Eligible)
+	assert.Len(t, cohort.Audits[0].Outstanding, 3, "failed core, no electives, low CGPA")
+}
+
+func TestPasswordReset(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Grace", "grace@example.com", db.Student)
+
+	require.Equal(t, http.StatusAccepted, c.do(http.MethodPost, "/password/forgot", map[string]string{"email": "grace@example.com"}, nil))
+	assert.Equal(t, http.StatusAccepted, c.do(http.MethodPost, "/password/forgot", map[string]string{"email": "nobody@example.com"}, nil),
+		"unknown addresses get the same answer")
+	token := c.lastToken("grace@example.com")
+
+	reset := func(token, password string) int {
+		return c.do(http.MethodPost, "/password/reset", map[string]string{"token": token, "password": password}, nil)
+	}
+
+	assert.Equal(t, http.StatusBadRequest, reset(token, "short"), "policy")
+	assert.Equal(t, http.StatusBadRequest, reset(token, "qwertyuiop"), "breached")
+	assert.Equal(t, http.StatusBadRequest, reset(token, testPassword), "reused")
+	assert.Equal(t, http.StatusBadRequest, reset(token+"x", "a brand new passphrase"), "tampered")
+
+	require.Equal(t, http.StatusOK, reset(token, "a brand new passphrase"))
+	assert.Equal(t, http.StatusBadRequest, reset(token, "another new passphrase"), "single use")
+
+	assert.Equal(t, http.StatusUnauthorized, c.do(http.MethodPost, "/login", map[string]string{
+		"email": "grace@example.com", "password": testPassword,
+	}, nil))
+	c.as("grace@example.com", "a brand new passphrase")
+
+	require.Equal(t, http.StatusAccepted, c.do(http.MethodPost, "/password/forgot", map[string]string{"email": "grace@example.com"}, nil))
+	assert.Equal(t, http.StatusBadRequest, reset(c.lastToken("grace@example.com"), "a brand new passphrase"), "current password")
+	assert.Equal(t, http.StatusBadRequest, reset(c.lastToken("grace@example.com"), testPassword), "still in history")
+}
+
+// TestOperatorAccounts covers the account tasks gradectl runs directly
+// against the services.
+func TestOperatorAccounts(t *testing.T) {
+	c := testServer(t)
+
+	admin, err := service.CreateAdmin(t.Context(), "Root", "root@example.com", testPassword)
+	require.NoError(t, err)
+	assert.Equal(t, string(db.Admin), admin.Role)
+	ac := c.as("root@example.com", testPassword)
+	var users []models.User
+	require.Equal(t, http.StatusOK, ac.do(http.MethodGet, "/admin/users", nil, &users), "no email verification needed")
+	require.Len(t, users, 1)
+	assert.NotNil(t, users[0].EmailVerifiedAt)
+	assert.Empty(t, users[0].Password)
+
+	assert.ErrorIs(t, service.SetPassword(t.Context(), "root@example.com", testPassword), service.ErrPasswordReused)
+	require.NoError(t, service.SetPassword(t.Context(), "root@example.com", "a brand new passphrase"))
+	assert.Equal(t, http.StatusUnauthorized, c.do(http.MethodPost, "/login", map[string]string{
+		"email": "root@example.com", "password": testPassword,
+	}, nil))
+	c.as("root@example.com", "a brand new passphrase")
+}
+
+func TestBroadsheetExport(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	alan := seedUser(t, "Alan", "alan@example.com", db.Student)
+	barbara := seedUser(t, "Barbara", "barbara@example.com", db.Student)
+
+	earlier := seedSemesterStarting(t, time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC))
+	current := seedSemester(t)
+
+	course := func(name string, level, units int) int {
+		c, err := db.CreateCourse(t.Context(), name, level, units, lecturer.ID)
+		require.NoError(t, err)
+		return c.ID
+	}
+	compilers := course("Compilers", 400, 3)
+	databases := course("Databases", 400, 2)
+	intro := course("Intro to Programming", 100, 2)
+
+	// Alan: A in Compilers, C in Databases this semester -> GPA (15+6)/5 = 4.2;
+	// with a B over 2 units earlier, CGPA (21+8)/7 = 4.14.
+	seedPublishedGrade(t, alan.ID, compilers, current.ID, 75)
+	seedPublishedGrade(t, alan.ID, databases, current.ID, 52)
+	seedPublishedGrade(t, alan.ID, intro, earlier.ID, 65)
+	// Barbara: F in Compilers, nothing yet in Databases -> probation territory.
+	seedPublishedGrade(t, barbara.ID, compilers, current.ID, 30)
+	_, err := db.EnrollStudent(t.Context(), barbara.ID, databases, current.ID)
+	require.NoError(t, err)
+
+	admin := c.as("admin@example.com", testPassword)
+	path := "/admin/broadsheet?level=400&semester_id=" + strconv.Itoa(current.ID)
+
+	status, header, body := admin.raw(path)
+	require.Equal(t, http.StatusOK, status, body)
+	assert.Equal(t, "text/csv", header.Get("Content-Type"))
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+	assert.Equal(t, []string{
+		"student_id", "student_name",
+		"Compilers (3 units) score", "Compilers (3 units) grade",
+		"Databases (2 units) score", "Databases (2 units) grade",
+		"units", "gpa", "cgpa", "remark",
+	}, records[0])
+	assert.Equal(t, []string{strconv.Itoa(alan.ID), "Alan", "75.00", "A", "52.00", "C", "5", "4.20", "4.14", "pass"}, records[1])
+	assert.Equal(t, []string{strconv.Itoa(barbara.ID), "Barbara", "30.00", "F", "", "", "3", "0.00", "0.00", "withdrawal"}, records[2])
+
+	status, header, body = admin.raw(path + "&format=xlsx")
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", header.Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(body, "PK"), "xlsx is a zip archive")
+
+	status, _, _ = admin.raw(path + "&format=pdf")
+	assert.Equal(t, http.StatusBadRequest, status)
+
+	lc := c.as("ada@example.com", testPassword)
+	status, _, _ = lc.raw(path)
+	assert.Equal(t, http.StatusForbidden, status)
+}
+
+func TestCourseEvaluations(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	// Evaluations open around the end of the semester, so end it about now.
+	semester := seedSemesterStarting(t, time.Now().AddDate(0, -4, 0))
+	course, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+
+	admin := c.as("admin@example.com", testPassword)
+	var dept models.Department
+	require.Equal(t, http.StatusCreated, admin.do(http.MethodPost, "/departments", map[string]string{"name": "Computer Science"}, &dept))
+	require.Equal(t, http.StatusOK, admin.do(http.MethodPost, "/departments/"+strconv.Itoa(dept.ID)+"/courses", map[string]int{"course_id": course.ID}, nil))
+
+	var clarity models.EvaluationQuestion
+	require.Equal(t, http.StatusCreated, admin.do(http.MethodPost, "/admin/evaluation-questions",
+		map[string]any{"prompt": "Lectures were clear.", "kind": "likert", "position": 0}, &clarity))
+
+	evaluate := func(sc *client, rating int, comment string) int {
+		var questions []models.EvaluationQuestion
+		require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/evaluation-questions", nil, &questions))
+		var answers []models.EvaluationAnswer
+		for _, q := range questions {
+			a := models.EvaluationAnswer{QuestionID: q.ID}
+			if q.Kind == "likert" {
+				a.Rating = &rating
+			} else {
+				a.Comment = comment
+			}
+			answers = append(answers, a)
+		}
+		return sc.do(http.MethodPost, "/courses/"+strconv.Itoa(course.ID)+"/evaluations",
+			map[string]any{"semester_id": semester.ID, "answers": answers}, nil)
+	}
+
+	for i, rating := range []int{5, 4, 3} {
+		email := "student" + strconv.Itoa(i) + "@example.com"
+		student := seedUser(t, "Student", email, db.Student)
+		_, err := db.EnrollStudent(t.Context(), student.ID, course.ID, semester.ID)
+		require.NoError(t, err)
+		_, err = db.SaveStudentGrade(t.Context(), student.ID, course.ID, semester.ID, 60)
+		require.NoError(t, err)
+
+		sc := c.as(email, testPassword)
+		assert.Equal(t, http.StatusCreated, evaluate(sc, rating, "More examples please"))
+		if i == 0 {
+			assert.Equal(t, http.StatusConflict, evaluate(sc, rating, ""), "one evaluation per offering")
+			assert.Equal(t, http.StatusBadRequest, sc.do(http.MethodPost, "/courses/"+strconv.Itoa(course.ID)+"/evaluations",
+				map[string]any{"semester_id": semester.ID, "answers": []models.EvaluationAnswer{{QuestionID: clarity.ID}}}, nil),
+				"likert questions need a rating")
+		}
+	}
+
+	seedUser(t, "Outsider", "outsider@example.com", db.Student)
+	assert.Equal(t, http.StatusForbidden, evaluate(c.as("outsider@example.com", testPassword), 1, ""))
+
+	// Responses carry nothing that links them back to a student.
+	rows, err := db.DB.Query(`SELECT * FROM evaluation_response`)
+	require.NoError(t, err)
+	columns, err := rows.Columns()
+	require.NoError(t, err)
+	rows.Close()
+	assert.ElementsMatch(t, []string{"id", "course_id", "semester_id", "institution_id"}, columns)
+
+	lc := c.as("ada@example.com", testPassword)
+	path := "/courses/" + strconv.Itoa(course.ID) + "/evaluations?semester_id=" + strconv.Itoa(semester.ID)
+	assert.Equal(t, http.StatusConflict, lc.do(http.MethodGet, path, nil, nil), "hidden until grades are published")
+
+	_, err = db.PublishGrades(t.Context(), course.ID, semester.ID)
+	require.NoError(t, err)
+
+	var report models.EvaluationReport
+	require.Equal(t, http.StatusOK, lc.do(http.MethodGet, path, nil, &report))
+	assert.Equal(t, 3, report.Responses)
+	require.NotEmpty(t, report.Questions)
+	first := report.Questions[0]
+	assert.Equal(t, clarity.ID, first.QuestionID)
+	assert.Equal(t, 3, first.Answers)
+	require.NotNil(t, first.MeanRating)
+	assert.Equal(t, 4.0, *first.MeanRating)
+	assert.Equal(t, []int{0, 0, 1, 1, 1}, first.Distribution)
+
+	var rankings []models.DepartmentRanking
+	require.Equal(t, http.StatusOK, admin.do(http.MethodGet, "/admin/evaluations/rankings?semester_id="+strconv.Itoa(semester.ID), nil, &rankings))
+	require.Len(t, rankings, 1)
+	assert.Equal(t, "Computer Science", rankings[0].DepartmentName)
+	require.Len(t, rankings[0].Lecturers, 1)
+	assert.Equal(t, lecturer.ID, rankings[0].Lecturers[0].LecturerID)
+	assert.Equal(t, 1, rankings[0].Lecturers[0].Rank)
+	assert.Equal(t, 3, rankings[0].Lecturers[0].Responses)
+
+	assert.Equal(t, http.StatusForbidden, lc.do(http.MethodGet, "/admin/evaluations/rankings?semester_id="+strconv.Itoa(semester.ID), nil, nil))
+}
+
+type graphQLResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (c *client) graphql(query string) graphQLResponse {
+	var res graphQLResponse
+	status := c.do(http.MethodPost, "/graphql", map[string]any{"query": query}, &res)
+	require.Equal(c.t, http.StatusOK, status)
+	return res
+}
+
+func TestGraphQL(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	ada := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	seedUser(t, "Grace", "grace@example.com", db.Lecturer)
+	alan := seedUser(t, "Alan", "alan@example.com", db.Student)
+	seedUser(t, "Barbara", "barbara@example.com", db.Student)
+	semester := seedSemester(t)
+
+	compilers, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, ada.ID)
+	require.NoError(t, err)
+	databases, err := db.CreateCourse(t.Context(), "Databases", 400, 2, ada.ID)
+	require.NoError(t, err)
+	seedPublishedGrade(t, alan.ID, compilers.ID, semester.ID, 75)
+	seedPublishedGrade(t, alan.ID, databases.ID, semester.ID, 52)
+
+	student := c.as("alan@example.com", testPassword)
+	res := student.graphql(`{
+		me {
+			name
+			grades { score course { name units lecturer { name } } semester { name } }
+		}
+	}`)
+	require.Empty(t, res.Errors)
+	me := res.Data["me"].(map[string]any)
+	assert.Equal(t, "Alan", me["name"])
+	grades := me["grades"].([]any)
+	require.Len(t, grades, 2)
+	first := grades[0].(map[string]any)
+	course := first["course"].(map[string]any)
+	assert.Contains(t, []any{"Compilers", "Databases"}, course["name"])
+	assert.Equal(t, "Ada", course["lecturer"].(map[string]any)["name"])
+
+	forbidden := []struct {
+		name  string
+		as    *client
+		query string
+		want  string
+	}{
+		{"student lists users", student, `{ users { id } }`, "forbidden"},
+		{"student reads another user", student, `{ user(id: ` + strconv.Itoa(ada.ID) + `) { name } }`, "forbidden"},
+		{"student reads a course by id", student, `{ course(id: ` + strconv.Itoa(compilers.ID) + `) { name } }`, "forbidden"},
+		{"lecturer reads a student's transcript", c.as("ada@example.com", testPassword), fmt.Sprintf(`{ grades(courseId: %d, semesterId: %d) { student { grades { score } } } }`, compilers.ID, semester.ID), "forbidden"},
+		{"other lecturer reads an offering", c.as("grace@example.com", testPassword), fmt.Sprintf(`{ grades(courseId: %d, semesterId: %d) { score } }`, compilers.ID, semester.ID), "you do not teach this course"},
+	}
+	for _, tt := range forbidden {
+		t.Run(tt.name, func(t *testing.T) {
+			res := tt.as.graphql(tt.query)
+			require.NotEmpty(t, res.Errors)
+			assert.Equal(t, tt.want, res.Errors[0].Message)
+		})
+	}
+
+	lecturer := c.as("ada@example.com", testPassword)
+	res = lecturer.graphql(fmt.Sprintf(`{ grades(courseId: %d, semesterId: %d) { score student { name } } }`, compilers.ID, semester.ID))
+	require.Empty(t, res.Errors)
+	offering := res.Data["grades"].([]any)
+	require.Len(t, offering, 1)
+	assert.Equal(t, "Alan", offering[0].(map[string]any)["student"].(map[string]any)["name"])
+
+	admin := c.as("admin@example.com", testPassword)
+	res = admin.graphql(`{ users(role: "lecturer") { name courses { name } } }`)
+	require.Empty(t, res.Errors)
+	assert.Len(t, res.Data["users"], 2)
+}
+
+type streamEvent struct {
+	Type string
+	Data map[string]any
+}
+
+// stream opens /events and relays each event received on the returned
+// channel until the test ends.
+func (c *client) stream() <-chan streamEvent {
+	c.t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.t.Cleanup(cancel)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url+"/events", nil)
+	require.NoError(c.t, err)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(c.t, err)
+	require.Equal(c.t, http.StatusOK, resp.StatusCode)
+	require.Equal(c.t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	out := make(chan streamEvent, 16)
+	go func() {
+		defer resp.Body.Close()
+		var e streamEvent
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				e.Type = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &e.Data)
+			case line == "" && e.Type != "":
+				out <- e
+				e = streamEvent{}
+			}
+		}
+	}()
+	return out
+}
+
+func nextEvent(t *testing.T, events <-chan streamEvent) streamEvent {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return streamEvent{}
+	}
+}
+
+func TestEventStream(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	student := seedUser(t, "Alan", "alan@example.com", db.Student)
+	seedUser(t, "Barbara", "barbara@example.com", db.Student)
+	semester := seedSemesterStarting(t, time.Now().AddDate(0, -4, 0))
+	course, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+
+	admin := c.as("admin@example.com", testPassword)
+	lc := c.as("ada@example.com", testPassword)
+	sc := c.as("alan@example.com", testPassword)
+	alan := sc.stream()
+	barbara := c.as("barbara@example.com", testPassword).stream()
+
+	// Enrollment window.
+	enrollment := "/semesters/" + strconv.Itoa(semester.ID) + "/enrollment"
+	enroll := "/courses/" + strconv.Itoa(course.ID) + "/enrollments"
+	require.Equal(t, http.StatusOK, admin.do(http.MethodPost, enrollment, map[string]bool{"open": false}, nil))
+	assert.Equal(t, http.StatusConflict, sc.do(http.MethodPost, enroll, map[string]int{"semester_id": semester.ID}, nil))
+
+	var opened models.Semester
+	require.Equal(t, http.StatusOK, admin.do(http.MethodPost, enrollment, map[string]bool{"open": true}, &opened))
+	assert.True(t, opened.EnrollmentOpen)
+	for _, stream := range []<-chan streamEvent{alan, barbara} {
+		e := nextEvent(t, stream)
+		assert.Equal(t, "enrollment_opened", e.Type)
+		assert.Equal(t, float64(semester.ID), e.Data["semester_id"])
+	}
+	require.Equal(t, http.StatusCreated, sc.do(http.MethodPost, enroll, map[string]int{"semester_id": semester.ID}, nil))
+
+	// Grade publication reaches only the students whose grades they are.
+	grade, err := db.SaveStudentGrade(t.Context(), student.ID, course.ID, semester.ID, 55)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, lc.do(http.MethodPost, "/courses/"+strconv.Itoa(course.ID)+"/grades/publish", map[string]int{"semester_id": semester.ID}, nil))
+	e := nextEvent(t, alan)
+	assert.Equal(t, "grades_published", e.Type)
+	assert.Equal(t, "Compilers", e.Data["course_name"])
+
+	// Appeal review.
+	var appeal models.Appeal
+	require.Equal(t, http.StatusCreated, sc.do(http.MethodPost, "/appeals", map[string]any{"grade_id": grade.ID, "reason": "Question 3 was not marked"}, &appeal))
+	require.Equal(t, http.StatusOK, lc.do(http.MethodPost, "/appeals/"+strconv.Itoa(appeal.ID)+"/review", map[string]any{"decision": "rejected", "note": "Marked correctly"}, nil))
+	e = nextEvent(t, alan)
+	assert.Equal(t, "appeal_status_changed", e.Type)
+	assert.Equal(t, "under_review", e.Data["status"])
+
+	select {
+	case e := <-barbara:
+		t.Fatalf("barbara received %s meant for alan", e.Type)
+	default:
+	}
+
+	status, _, _ := c.raw("/events")
+	assert.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestAttendance(t *testing.T) {
+	c := testServer(t)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	alan := seedUser(t, "Alan", "alan@example.com", db.Student)
+	barbara := seedUser(t, "Barbara", "barbara@example.com", db.Student)
+	grace := seedUser(t, "Grace", "grace@example.com", db.Student)
+	seedUser(t, "Linus", "linus@example.com", db.Student)
+	semester := seedSemester(t)
+
+	course, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+	for _, s := range []*models.User{alan, barbara, grace} {
+		_, err := db.EnrollStudent(t.Context(), s.ID, course.ID, semester.ID)
+		require.NoError(t, err)
+	}
+
+	lc := c.as("ada@example.com", testPassword)
+	coursePath := "/courses/" + strconv.Itoa(course.ID)
+
+	var session models.ClassSession
+	status := lc.do(http.MethodPost, coursePath+"/sessions", map[string]any{
+		"semester_id": semester.ID, "topic": "Parsing", "generate_code": true,
+	}, &session)
+	require.Equal(t, http.StatusCreated, status)
+	require.Len(t, session.Code, 6)
+	require.NotNil(t, session.CodeExpiresAt)
+
+	// Codes are case-insensitive, and a student checks in only once.
+	checkIn := map[string]string{"code": " " + strings.ToLower(session.Code) + " "}
+	gc := c.as("grace@example.com", testPassword)
+	var record models.Attendance
+	require.Equal(t, http.StatusCreated, gc.do(http.MethodPost, "/attendance/checkin", checkIn, &record))
+	assert.Equal(t, session.ID, record.SessionID)
+	assert.Equal(t, grace.ID, record.StudentID)
+	assert.Equal(t, string(db.AttendanceCode), record.Method)
+	assert.Equal(t, http.StatusConflict, gc.do(http.MethodPost, "/attendance/checkin", checkIn, nil))
+
+	linus := c.as("linus@example.com", testPassword)
+	assert.Equal(t, http.StatusForbidden, linus.do(http.MethodPost, "/attendance/checkin", checkIn, nil))
+
+	expiredAt := time.Now().Add(-time.Minute)
+	expired, err := db.CreateClassSession(t.Context(), course.ID, semester.ID, "Lexing", time.Now().Add(-time.Hour), "EXPRD2", &expiredAt)
+	require.NoError(t, err)
+	ac := c.as("alan@example.com", testPassword)
+	assert.Equal(t, http.StatusBadRequest, ac.do(http.MethodPost, "/attendance/checkin", map[string]string{"code": "EXPRD2"}, nil))
+	assert.Equal(t, http.StatusBadRequest, ac.do(http.MethodPost, "/attendance/checkin", map[string]string{"code": "NOPE22"}, nil))
+
+	// Grace is already recorded for the first session, so marking her
+	// again only records Alan.
+	var records []models.Attendance
+	status = lc.do(http.MethodPost, "/sessions/"+strconv.Itoa(session.ID)+"/attendance", map[string]any{
+		"student_ids": []int{grace.ID, alan.ID},
+	}, &records)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, records, 1)
+	assert.Equal(t, alan.ID, records[0].StudentID)
+	assert.Equal(t, string(db.AttendanceManual), records[0].Method)
+
+	status = lc.do(http.MethodPost, "/sessions/"+strconv.Itoa(expired.ID)+"/attendance", map[string]any{
+		"student_ids": []int{grace.ID},
+	}, &records)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, records, 1)
+
+	// Two sessions: Grace attended both, Alan one and Barbara none.
+	semesterQuery := "?semester_id=" + strconv.Itoa(semester.ID)
+	status, header, body := lc.raw(coursePath + "/attendance/report" + semesterQuery)
+	require.Equal(t, http.StatusOK, status, body)
+	assert.Equal(t, "text/csv", header.Get("Content-Type"))
+	rows, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"student_id", "student_name", "attended", "total_sessions", "percentage", "exam_eligible"},
+		{strconv.Itoa(alan.ID), "Alan", "1", "2", "50.00", "false"},
+		{strconv.Itoa(barbara.ID), "Barbara", "0", "2", "0.00", "false"},
+		{strconv.Itoa(grace.ID), "Grace", "2", "2", "100.00", "true"},
+	}, rows)
+
+	var summary models.AttendanceSummary
+	require.Equal(t, http.StatusOK, ac.do(http.MethodGet, coursePath+"/attendance"+semesterQuery, nil, &summary))
+	assert.Equal(t, alan.ID, summary.StudentID)
+	assert.Equal(t, 50.0, summary.Percentage)
+
+	// Only students who met the attendance minimum can be graded.
+	grade := func(studentID int) int {
+		return lc.do(http.MethodPost, coursePath+"/grades", map[string]any{
+			"semester_id": semester.ID, "student_id": studentID, "score": 70,
+		}, nil)
+	}
+	assert.Equal(t, http.StatusConflict, grade(barbara.ID))
+	assert.Equal(t, http.StatusConflict, grade(alan.ID))
+	assert.Equal(t, http.StatusOK, grade(grace.ID))
+}
+
+func TestAppeals(t *testing.T) {
+	c := testServer(t)
+	seedUser(t, "Admin", "admin@example.com", db.Admin)
+	lecturer := seedUser(t, "Ada", "ada@example.com", db.Lecturer)
+	seedUser(t, "Bob", "bob@example.com", db.Lecturer)
+	student := seedUser(t, "Alan", "alan@example.com", db.Student)
+
+	// The recent semester ended today, so its appeal window is open; the
+	// older one ended long before AppealWindow.
+	recent := seedSemesterStarting(t, time.Now().AddDate(0, -4, 0))
+	older := seedSemesterStarting(t, time.Now().AddDate(-1, 0, 0))
+	course, err := db.CreateCourse(t.Context(), "Compilers", 400, 3, lecturer.ID)
+	require.NoError(t, err)
+	seedPublishedGrade(t, student.ID, course.ID, recent.ID, 40)
+	seedPublishedGrade(t, student.ID, course.ID, older.ID, 35)
+
+	sc := c.as("alan@example.com", testPassword)
+	var grades []models.StudentGrade
+	require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/grades", nil, &grades))
+	gradeIn := map[int]int{}
+	for _, g := range grades {
+		gradeIn[g.SemesterID] = g.GradeID
+	}
+
+	fileAppeal := func(gradeID int, out any) int {
+		return sc.do(http.MethodPost, "/appeals", map[string]any{"grade_id": gradeID, "reason": "Question 3 was not marked"}, out)
+	}
+	assert.Equal(t, http.StatusConflict, fileAppeal(gradeIn[older.ID], nil))
+
+	var appeal models.Appeal
+	require.Equal(t, http.StatusCreated, fileAppeal(gradeIn[recent.ID], &appeal))
+	assert.Equal(t, http.StatusConflict, fileAppeal(gradeIn[recent.ID], nil), "one open appeal per grade")
+
+	admin := c.as("admin@example.com", testPassword)
+	lc := c.as("ada@example.com", testPassword)
+	bob := c.as("bob@example.com", testPassword)
+	review := "/appeals/" + strconv.Itoa(appeal.ID) + "/review"
+	decision := "/appeals/" + strconv.Itoa(appeal.ID) + "/decision"
+
+	assert.Equal(t, http.StatusConflict, admin.do(http.MethodPost, decision, map[string]any{"decision": "upheld", "score": 55}, nil),
+		"the admin decides only after the lecturer has reviewed")
+	assert.Equal(t, http.StatusForbidden, bob.do(http.MethodPost, review, map[string]any{"decision": "upheld", "score": 55}, nil))
+	assert.Equal(t, http.StatusBadRequest, lc.do(http.MethodPost, review, map[string]any{"decision": "upheld"}, nil))
+
+	require.Equal(t, http.StatusOK, lc.do(http.MethodPost, review, map[string]any{"decision": "upheld", "score": 55, "note": "Question 3 was skipped"}, &appeal))
+	assert.Equal(t, "under_review", appeal.Status)
+	assert.Equal(t, http.StatusConflict, lc.do(http.MethodPost, review, map[string]any{"decision": "rejected"}, nil))
+
+	require.Equal(t, http.StatusOK, admin.do(http.MethodPost, decision, map[string]any{"decision": "upheld"}, &appeal))
+	assert.Equal(t, "upheld", appeal.Status)
+	assert.Equal(t, http.StatusConflict, admin.do(http.MethodPost, decision, map[string]any{"decision": "rejected"}, nil))
+
+	// Upholding takes the lecturer's proposed score and records the change.
+	grade, err := db.FindStudentGrade(t.Context(), gradeIn[recent.ID])
+	require.NoError(t, err)
+	assert.Equal(t, 55.0, grade.Score)
+
+	var history []models.GradeChange
+	require.Equal(t, http.StatusOK, sc.do(http.MethodGet, "/grades/"+strconv.Itoa(gradeIn[recent.ID])+"/history", nil, &history))
+	require.Len(t, history, 1)
+	assert.Equal(t, 40.0, history[0].OldScore)
+	assert.Equal(t, 55.0, history[0].NewScore)
+	require.NotNil(t, history[0].AppealID)
+	assert.Equal(t, appeal.ID, *history[0].AppealID)
+}
@@ -0,0 +1,510 @@
+// Package server wires the HTTP routes of the API together.
+package server
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/handler"
+	"github.com/falasefemi2/gradesystem/internal/metrics"
+	"github.com/falasefemi2/gradesystem/internal/middleware"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/openapi"
+)
+
+// Route is a registered pattern together with the operations it serves.
+// The operations feed the OpenAPI document served at /openapi.json.
+type Route struct {
+	Pattern    string
+	Handler    http.HandlerFunc
+	Roles      []db.Role
+	Operations []openapi.Operation
+}
+
+var semesterQuery = []openapi.Param{{Name: "semester_id", Type: "integer", Required: true}}
+
+var dropQuery = []openapi.Param{
+	{Name: "semester_id", Type: "integer", Required: true},
+	{Name: "student_id", Type: "integer"},
+}
+
+type message map[string]string
+
+func Routes() []Route {
+	return []Route{
+		{
+			Pattern: "/healthz",
+			Handler: handler.Healthz,
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Liveness probe", Response: message{}},
+			},
+		},
+		{
+			Pattern: "/readyz",
+			Handler: handler.Readyz,
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Readiness probe, checks the database", Response: message{}},
+			},
+		},
+		{
+			Pattern: "/metrics",
+			Handler: metrics.Handler().ServeHTTP,
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Prometheus metrics", Response: "", ContentType: "text/plain"},
+			},
+		},
+		{
+			Pattern: "/openapi.json",
+			Handler: serveSpec,
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "This OpenAPI document", Response: map[string]any{}},
+			},
+		},
+		{
+			Pattern: "/events",
+			Handler: handler.Events,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{
+					Method:      http.MethodGet,
+					Summary:     "Server-sent events: grades_published, enrollment_opened, appeal_status_changed and waitlist_promoted",
+					Response:    "",
+					ContentType: "text/event-stream",
+				},
+			},
+		},
+		{
+			Pattern: "/graphql",
+			Handler: handler.GraphQL,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Read users, courses, semesters and grades with GraphQL", Request: handler.GraphQLRequest{}, Response: map[string]any{}},
+			},
+		},
+		{
+			Pattern: "/signup",
+			Handler: handler.SignUp,
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Create a student account", Request: handler.SignupRequest{}, Response: models.User{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/login",
+			Handler: handler.Login,
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Exchange credentials for a token", Request: handler.LoginRequest{}, Response: message{}},
+			},
+		},
+		{
+			Pattern: "/email/verify",
+			Handler: handler.VerifyEmail,
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Verify an email address with the emailed token", Request: handler.TokenRequest{}, Response: message{}},
+			},
+		},
+		{
+			Pattern: "/email/verify/resend",
+			Handler: handler.ResendVerification,
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Email a new verification token", Request: handler.EmailRequest{}, Response: message{}, Status: http.StatusAccepted},
+			},
+		},
+		{
+			Pattern: "/password/forgot",
+			Handler: handler.ForgotPassword,
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Email a single-use password reset token", Request: handler.EmailRequest{}, Response: message{}, Status: http.StatusAccepted},
+			},
+		},
+		{
+			Pattern: "/password/reset",
+			Handler: handler.ResetPassword,
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Set a new password with a reset token", Request: handler.ResetPasswordRequest{}, Response: message{}},
+			},
+		},
+		{
+			Pattern: "/admin/users",
+			Handler: handler.AdminUsersHandler,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "List users", Response: []models.User{}},
+				{Method: http.MethodPost, Summary: "Create an account with any role; the user must verify their email", Request: handler.CreateUserRequest{}, Response: models.User{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/admin/users/locked",
+			Handler: handler.GetLockedUsers,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "List accounts locked after failed logins", Response: []models.User{}},
+			},
+		},
+		{
+			Pattern: "/admin/users/{id}/unlock",
+			Handler: handler.UnlockUser,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Unlock an account", Response: message{}},
+			},
+		},
+		{
+			Pattern: "/admin/broadsheet",
+			Handler: handler.Broadsheet,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{
+					Method:  http.MethodGet,
+					Summary: "Semester result broadsheet of a level with GPA, CGPA and remarks",
+					Query: []openapi.Param{
+						{Name: "level", Type: "integer", Required: true},
+						{Name: "semester_id", Type: "integer", Required: true},
+						{Name: "format", Description: "csv (default) or xlsx"},
+					},
+					Response:    "",
+					ContentType: "text/csv",
+				},
+			},
+		},
+		{
+			Pattern: "/admin/evaluation-questions",
+			Handler: handler.AdminEvaluationQuestions,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "List evaluation questions, including retired ones", Response: []models.EvaluationQuestion{}},
+				{Method: http.MethodPost, Summary: "Add an evaluation question", Request: handler.CreateQuestionRequest{}, Response: models.EvaluationQuestion{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/admin/evaluation-questions/{id}",
+			Handler: handler.RetireEvaluationQuestion,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodDelete, Summary: "Stop asking an evaluation question", Response: message{}},
+			},
+		},
+		{
+			Pattern: "/admin/evaluations/rankings",
+			Handler: handler.EvaluationRankings,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Lecturers ranked by evaluation rating within each department", Query: semesterQuery, Response: []models.DepartmentRanking{}},
+			},
+		},
+		{
+			Pattern: "/departments",
+			Handler: handler.DepartmentsHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "List departments", Response: []models.Department{}},
+				{Method: http.MethodPost, Summary: "Create a department (admin only)", Request: handler.CreateDepartmentRequest{}, Response: models.Department{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/departments/{id}/courses",
+			Handler: handler.AssignDepartmentCourse,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Move a course into the department", Request: handler.AssignCourseRequest{}, Response: models.Course{}},
+			},
+		},
+		{
+			Pattern: "/departments/{id}/students",
+			Handler: handler.AssignDepartmentStudent,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Put a student on the department's programme", Request: handler.AssignStudentRequest{}, Response: models.User{}},
+			},
+		},
+		{
+			Pattern: "/departments/{id}/programme",
+			Handler: handler.ProgrammeHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Graduation requirements of the department's programme", Response: models.Programme{}},
+				{Method: http.MethodPut, Summary: "Set core courses, elective units per level and minimum CGPA (admin only)", Request: handler.ProgrammeRequest{}, Response: models.Programme{}},
+			},
+		},
+		{
+			Pattern: "/departments/{id}/graduation",
+			Handler: handler.CohortAudit,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Degree audit of every student on the programme, or of those enrolled in a semester", Query: []openapi.Param{{Name: "semester_id", Type: "integer"}}, Response: models.CohortAudit{}},
+			},
+		},
+		{
+			Pattern: "/students/{id}/audit",
+			Handler: handler.StudentAudit,
+			Roles:   []db.Role{db.Admin, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Satisfied and outstanding graduation requirements; students see only their own", Response: models.DegreeAudit{}},
+			},
+		},
+		{
+			Pattern: "/semesters",
+			Handler: handler.SemestersHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "List semesters", Response: []models.Semester{}},
+				{Method: http.MethodPost, Summary: "Create a semester (admin only)", Request: handler.CreateSemesterRequest{}, Response: models.Semester{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/semesters/{id}",
+			Handler: handler.SemesterByIDHandler,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Get a semester", Response: models.Semester{}},
+				{Method: http.MethodPut, Summary: "Update a semester", Request: handler.CreateSemesterRequest{}, Response: models.Semester{}},
+				{Method: http.MethodDelete, Summary: "Delete a semester", Status: http.StatusNoContent},
+			},
+		},
+		{
+			Pattern: "/semesters/{id}/enrollment",
+			Handler: handler.SemesterEnrollment,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Open or close enrollment; opening it notifies connected users", Request: handler.EnrollmentWindowRequest{}, Response: models.Semester{}},
+			},
+		},
+		{
+			Pattern: "/courses",
+			Handler: handler.CoursesHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{
+					Method:   http.MethodGet,
+					Summary:  "List courses; lecturers see their own, others may filter by level",
+					Query:    []openapi.Param{{Name: "level", Type: "integer"}},
+					Response: []models.Course{},
+				},
+				{Method: http.MethodPost, Summary: "Create a course (lecturer only)", Request: handler.CreateCourseRequest{}, Response: models.Course{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/courses/{id}",
+			Handler: handler.CourseByIDHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Get a course", Response: models.Course{}},
+				{Method: http.MethodPut, Summary: "Update a course (lecturer only)", Request: handler.CreateCourseRequest{}, Response: models.Course{}},
+				{Method: http.MethodDelete, Summary: "Delete a course (admin only)", Response: message{}},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/enrollments",
+			Handler: handler.EnrollmentsHandler,
+			Roles:   []db.Role{db.Admin, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Enroll in a course for a semester, or join its waitlist (202) when full; admins enroll a given student and may override capacity", Request: handler.EnrollRequest{}, Response: models.Enrollment{}, Status: http.StatusCreated},
+				{Method: http.MethodDelete, Summary: "Drop a course or leave its waitlist, promoting the next waiting student; admins name the student", Query: dropQuery, Response: message{}},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/capacity",
+			Handler: handler.OfferingCapacityHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Capacity, enrollment and waitlist counts of a course offering", Query: semesterQuery, Response: models.Offering{}},
+				{Method: http.MethodPut, Summary: "Set or lift the capacity of a course offering, promoting waiting students into new seats", Request: handler.CapacityRequest{}, Response: models.Offering{}},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/waitlist",
+			Handler: handler.CourseWaitlist,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Students waiting for a seat in a course offering, in promotion order", Query: semesterQuery, Response: []models.WaitlistEntry{}},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/sessions",
+			Handler: handler.ClassSessionsHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Open a class session", Request: handler.CreateSessionRequest{}, Response: models.ClassSession{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/sessions/{id}/attendance",
+			Handler: handler.MarkAttendance,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Mark attendance manually", Request: handler.MarkAttendanceRequest{}, Response: []models.Attendance{}},
+			},
+		},
+		{
+			Pattern: "/attendance/checkin",
+			Handler: handler.CheckIn,
+			Roles:   []db.Role{db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Check in with a session code", Request: handler.CheckInRequest{}, Response: models.Attendance{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/attendance",
+			Handler: handler.CourseAttendanceHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Attendance and exam eligibility; students see only their own", Query: semesterQuery, Response: []models.AttendanceSummary{}},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/attendance/report",
+			Handler: handler.AttendanceReport,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Attendance report as CSV", Query: semesterQuery, Response: "", ContentType: "text/csv"},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/grades",
+			Handler: handler.CourseGradesHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "List grades of a course offering", Query: semesterQuery, Response: []models.StudentGrade{}},
+				{Method: http.MethodPost, Summary: "Post or correct a score (course lecturer only)", Request: handler.PostGradeRequest{}, Response: models.Grade{}},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/grades/publish",
+			Handler: handler.PublishGrades,
+			Roles:   []db.Role{db.Admin, db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Publish the grades of a course offering", Request: handler.PublishGradesRequest{}, Response: map[string]int{}},
+			},
+		},
+		{
+			Pattern: "/grades",
+			Handler: handler.MyGrades,
+			Roles:   []db.Role{db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "List my published grades", Response: []models.StudentGrade{}},
+			},
+		},
+		{
+			Pattern: "/grades/{id}/history",
+			Handler: handler.GradeHistory,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Changes made to a grade after publication", Response: []models.GradeChange{}},
+			},
+		},
+		{
+			Pattern: "/evaluation-questions",
+			Handler: handler.EvaluationQuestions,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{Method: http.MethodGet, Summary: "Questions currently asked in course evaluations", Response: []models.EvaluationQuestion{}},
+			},
+		},
+		{
+			Pattern: "/courses/{id}/evaluations",
+			Handler: handler.CourseEvaluationsHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{
+					Method:   http.MethodGet,
+					Summary:  "Aggregated evaluation results, once the offering's grades are published",
+					Query:    semesterQuery,
+					Response: models.EvaluationReport{},
+				},
+				{Method: http.MethodPost, Summary: "Submit an anonymous course evaluation (student only)", Request: handler.SubmitEvaluationRequest{}, Response: message{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/appeals",
+			Handler: handler.AppealsHandler,
+			Roles:   []db.Role{db.Admin, db.Lecturer, db.Student},
+			Operations: []openapi.Operation{
+				{
+					Method:   http.MethodGet,
+					Summary:  "List appeals visible to the caller",
+					Query:    []openapi.Param{{Name: "status", Description: "pending, under_review, upheld or rejected"}},
+					Response: []models.Appeal{},
+				},
+				{Method: http.MethodPost, Summary: "Appeal a published grade (student only)", Request: handler.FileAppealRequest{}, Response: models.Appeal{}, Status: http.StatusCreated},
+			},
+		},
+		{
+			Pattern: "/appeals/{id}/review",
+			Handler: handler.ReviewAppeal,
+			Roles:   []db.Role{db.Lecturer},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Lecturer recommendation on an appeal", Request: handler.AppealDecisionRequest{}, Response: models.Appeal{}},
+			},
+		},
+		{
+			Pattern: "/appeals/{id}/decision",
+			Handler: handler.DecideAppeal,
+			Roles:   []db.Role{db.Admin},
+			Operations: []openapi.Operation{
+				{Method: http.MethodPost, Summary: "Admin decision on a reviewed appeal", Request: handler.AppealDecisionRequest{}, Response: models.Appeal{}},
+			},
+		},
+	}
+}
+
+func NewMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, route := range Routes() {
+		h := route.Handler
+		if len(route.Roles) > 0 {
+			h = middleware.RoleAuth(h, route.Roles...)
+		}
+		mux.HandleFunc(route.Pattern, h)
+	}
+	return mux
+}
+
+// NewHandler returns the mux wrapped in the standard middleware chain.
+func NewHandler(cfg *config.Config, logger *slog.Logger) http.Handler {
+	return middleware.Chain(NewMux(),
+		middleware.RequestID,
+		middleware.Logger(logger),
+		middleware.Recover(logger),
+		middleware.Timeout(cfg.RequestTimeout, "/events"),
+		middleware.Tenant(cfg.TenantDomain),
+		middleware.ETag("/events"),
+		metrics.Instrument,
+	)
+}
+
+// Spec builds the OpenAPI document describing Routes.
+func Spec() *openapi.Document {
+	var paths []openapi.Path
+	for _, route := range Routes() {
+		ops := make([]openapi.Operation, len(route.Operations))
+		for i, op := range route.Operations {
+			for _, role := range route.Roles {
+				op.Roles = append(op.Roles, string(role))
+			}
+			ops[i] = op
+		}
+		paths = append(paths, openapi.Path{Pattern: route.Pattern, Operations: ops})
+	}
+	return openapi.Build("Grade System API", "1.0.0", paths)
+}
+
+var (
+	specOnce sync.Once
+	specBody []byte
+	specErr  error
+)
+
+func serveSpec(w http.ResponseWriter, r *http.Request) {
+	specOnce.Do(func() {
+		specBody, specErr = json.MarshalIndent(Spec(), "", "  ")
+	})
+	if specErr != nil {
+		http.Error(w, specErr.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(specBody)
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var wildcard = regexp.MustCompile(`\{[^}]+\}`)
+
+// TestSpecMatchesRoutes fails when a route is registered without being
+// documented, or the document describes a path the mux does not serve.
+func TestSpecMatchesRoutes(t *testing.T) {
+	doc := Spec()
+	mux := NewMux()
+
+	registered := map[string]bool{}
+	for _, route := range Routes() {
+		registered[route.Pattern] = true
+		require.NotEmpty(t, route.Operations, "route %s has no documented operations", route.Pattern)
+
+		item, ok := doc.Paths[route.Pattern]
+		require.True(t, ok, "route %s missing from spec", route.Pattern)
+		for _, op := range route.Operations {
+			assert.Contains(t, item, strings.ToLower(op.Method), "%s %s missing from spec", op.Method, route.Pattern)
+		}
+	}
+
+	var paths []string
+	for path := range doc.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		assert.True(t, registered[path], "spec path %s is not registered", path)
+		for method := range doc.Paths[path] {
+			req := httptest.NewRequest(strings.ToUpper(method), wildcard.ReplaceAllString(path, "1"), nil)
+			_, pattern := mux.Handler(req)
+			assert.Equal(t, path, pattern, "%s %s", strings.ToUpper(method), path)
+		}
+	}
+}
+
+func TestServeSpec(t *testing.T) {
+	rec := httptest.NewRecorder()
+	NewMux().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Contains(t, rec.Body.String(), `"openapi": "3.0.3"`)
+	assert.Contains(t, rec.Body.String(), `"/courses/{id}/grades"`)
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/auth"
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/mail"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+var (
+	ErrEmailNotVerified = errors.New("email address not verified")
+	ErrPasswordReused   = errors.New("password was used recently, choose a different one")
+)
+
+var mailer mail.Sender = mail.LogSender{}
+
+// SetMailer installs the sender used for verification and reset emails.
+func SetMailer(m mail.Sender) {
+	mailer = m
+}
+
+// Register creates an account and emails a verification token; the user
+// can log in once the address is verified. A failed email is logged rather
+// than returned since the account exists and the email can be resent.
+func Register(ctx context.Context, name, email, password string, role db.Role) (*models.User, error) {
+	user, err := db.RegisterUser(ctx, name, email, password, role)
+	if err != nil {
+		return nil, err
+	}
+	if err := sendVerification(ctx, user); err != nil {
+		slog.ErrorContext(ctx, "sending verification email", slog.Int("user_id", user.ID), slog.Any("error", err))
+	}
+	return user, nil
+}
+
+// CreateAdmin creates an admin account for an operator, who is trusted
+// with the address, so it is marked verified straight away.
+func CreateAdmin(ctx context.Context, name, email, password string) (*models.User, error) {
+	user, err := db.RegisterUser(ctx, name, email, password, db.Admin)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := db.MarkEmailVerified(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+	user.EmailVerifiedAt = &now
+	return user, nil
+}
+
+// ResendVerification emails a fresh verification token if the address
+// belongs to an unverified account, and silently does nothing otherwise so
+// callers cannot probe which addresses are registered.
+func ResendVerification(ctx context.Context, email string) error {
+	user, err := db.GetUserByEmail(ctx, email)
+	if err != nil || user.EmailVerifiedAt != nil {
+		return nil
+	}
+	return sendVerification(ctx, user)
+}
+
+func VerifyEmail(ctx context.Context, token string) error {
+	t, err := findToken(ctx, db.TokenVerifyEmail, token)
+	if err != nil {
+		return err
+	}
+	return db.VerifyEmail(ctx, t, time.Now())
+}
+
+// RequestPasswordReset emails a reset token if the address is registered,
+// and silently does nothing otherwise.
+func RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+	ttl := config.Get().PasswordResetTTL
+	token, err := issueToken(ctx, user.ID, db.TokenPasswordReset, ttl)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this token to reset your password: %s\nIt expires in %s and can only be used once. "+
+			"If you did not ask for a reset, ignore this email.", token, ttl),
+	})
+}
+
+// ResetPassword sets a new password using a reset token. The password must
+// satisfy auth.ValidatePassword and not be one of the recent ones.
+func ResetPassword(ctx context.Context, token, password string) error {
+	t, err := findToken(ctx, db.TokenPasswordReset, token)
+	if err != nil {
+		return err
+	}
+	if err := auth.ValidatePassword(password); err != nil {
+		return err
+	}
+	if err := checkPasswordReuse(ctx, t.UserID, password); err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return db.ResetPassword(ctx, t, hash, time.Now())
+}
+
+// SetPassword is ResetPassword for an operator, who needs no token.
+func SetPassword(ctx context.Context, email, password string) error {
+	user, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if err := auth.ValidatePassword(password); err != nil {
+		return err
+	}
+	if err := checkPasswordReuse(ctx, user.ID, password); err != nil {
+		return err
+	}
+	hash, err := auth.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return db.SetPassword(ctx, user.ID, hash, time.Now())
+}
+
+func checkPasswordReuse(ctx context.Context, userID int, password string) error {
+	user, err := db.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	recent, err := db.RecentPasswordHashes(ctx, userID, config.Get().PasswordHistory)
+	if err != nil {
+		return err
+	}
+	for _, hash := range append(recent, user.Password) {
+		if auth.VerifyPassword(password, hash) == nil {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+func sendVerification(ctx context.Context, user *models.User) error {
+	ttl := config.Get().EmailVerificationTTL
+	token, err := issueToken(ctx, user.ID, db.TokenVerifyEmail, ttl)
+	if err != nil {
+		return err
+	}
+	return mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body:    fmt.Sprintf("Use this token to verify your email address: %s\nIt expires in %s.", token, ttl),
+	})
+}
+
+func issueToken(ctx context.Context, userID int, purpose db.TokenPurpose, ttl time.Duration) (string, error) {
+	token, err := auth.NewToken([]byte(config.Get().TokenSecret), string(purpose))
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if err := db.CreateUserToken(ctx, userID, purpose, auth.HashToken(token), now.Add(ttl), now); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findToken rejects tokens with a bad signature before touching the
+// database, then checks that the token is unused and unexpired.
+func findToken(ctx context.Context, purpose db.TokenPurpose, token string) (*db.UserToken, error) {
+	if !auth.VerifyTokenSignature([]byte(config.Get().TokenSecret), string(purpose), token) {
+		return nil, db.ErrTokenInvalid
+	}
+	return db.FindUsableToken(ctx, purpose, auth.HashToken(token), time.Now())
+}
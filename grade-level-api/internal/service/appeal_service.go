@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/events"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+)
+
+var (
+	ErrGradeNotPublished  = errors.New("grade has not been published yet")
+	ErrAppealWindowClosed = errors.New("the appeal window for this semester has closed")
+	ErrNotYourAppeal      = errors.New("you are not allowed to act on this appeal")
+
+	ErrAppealReasonRequired  = errors.New("reason is required")
+	ErrInvalidDecision       = errors.New("decision must be either upheld or rejected")
+	ErrScoreOutOfRange       = errors.New("score must be between 0 and 100")
+	ErrProposedScoreRequired = errors.New("proposed_score is required when recommending an appeal be upheld")
+	ErrScoreRequired         = errors.New("score is required to uphold an appeal")
+)
+
+// AppealDeadline is the last moment a grade from the semester can be
+// appealed: the configured window counted from the semester's end date.
+func AppealDeadline(semester *models.Semester) time.Time {
+	return semester.EndDate.Add(config.Get().AppealWindow)
+}
+
+func FileAppeal(ctx context.Context, studentID, gradeID int, reason string) (*models.Appeal, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrAppealReasonRequired
+	}
+
+	grade, err := db.FindStudentGrade(ctx, gradeID)
+	if err != nil {
+		return nil, err
+	}
+	if grade.StudentID != studentID {
+		return nil, ErrNotYourAppeal
+	}
+	if grade.PublishedAt == nil {
+		return nil, ErrGradeNotPublished
+	}
+
+	semester, err := db.FindSemesterByID(ctx, grade.SemesterID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(AppealDeadline(semester)) {
+		return nil, ErrAppealWindowClosed
+	}
+
+	return db.CreateAppeal(ctx, gradeID, studentID, reason)
+}
+
+// ReviewAppeal records the recommendation of the lecturer who teaches the
+// appealed course. Recommending that an appeal be upheld requires a score.
+func ReviewAppeal(ctx context.Context, lecturerID, appealID int, recommendation db.AppealStatus, proposedScore *float64, note string) (*models.Appeal, error) {
+	if err := validateDecision(recommendation, proposedScore); err != nil {
+		return nil, err
+	}
+	if recommendation == db.AppealUpheld && proposedScore == nil {
+		return nil, ErrProposedScoreRequired
+	}
+
+	appeal, err := db.FindAppealByID(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+	grade, err := db.FindStudentGrade(ctx, appeal.GradeID)
+	if err != nil {
+		return nil, err
+	}
+	course, err := db.FindCourseByID(ctx, grade.CourseID)
+	if err != nil {
+		return nil, err
+	}
+	if course.LecturerID != lecturerID {
+		return nil, ErrNotYourAppeal
+	}
+
+	if err := db.RecordLecturerReview(ctx, appealID, lecturerID, recommendation, proposedScore, note); err != nil {
+		return nil, err
+	}
+	return appealChanged(ctx, appealID)
+}
+
+// DecideAppeal records an admin's final decision. An upheld appeal adjusts
+// the grade to score, or to the lecturer's proposed score if none is given.
+func DecideAppeal(ctx context.Context, adminID, appealID int, decision db.AppealStatus, score *float64, note string) (*models.Appeal, error) {
+	if err := validateDecision(decision, score); err != nil {
+		return nil, err
+	}
+
+	appeal, err := db.FindAppealByID(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+
+	var newScore float64
+	if decision == db.AppealUpheld {
+		switch {
+		case score != nil:
+			newScore = *score
+		case appeal.ProposedScore != nil:
+			newScore = *appeal.ProposedScore
+		default:
+			return nil, ErrScoreRequired
+		}
+	}
+
+	if err := db.DecideAppeal(ctx, appealID, adminID, decision, newScore, note); err != nil {
+		return nil, err
+	}
+	return appealChanged(ctx, appealID)
+}
+
+// appealChanged reloads an appeal after a status change and tells the
+// student who filed it.
+func appealChanged(ctx context.Context, appealID int) (*models.Appeal, error) {
+	appeal, err := db.FindAppealByID(ctx, appealID)
+	if err != nil {
+		return nil, err
+	}
+	events.Publish(events.Event{
+		Type:          events.AppealStatusChanged,
+		Data:          appeal,
+		InstitutionID: tenant.FromContext(ctx),
+		UserIDs:       []int{appeal.StudentID},
+	})
+	return appeal, nil
+}
+
+func validateDecision(decision db.AppealStatus, score *float64) error {
+	if decision != db.AppealUpheld && decision != db.AppealRejected {
+		return ErrInvalidDecision
+	}
+	if score != nil && (*score < 0 || *score > 100) {
+		return ErrScoreOutOfRange
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package service
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"math"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+var ErrNotEnrolled = errors.New("student is not enrolled in this course")
+
+// codeAlphabet leaves out characters that are easy to misread on a projector.
+const codeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func NewSessionCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = codeAlphabet[int(b[i])%len(codeAlphabet)]
+	}
+	return string(b), nil
+}
+
+// AttendancePercentage is rounded to two decimals. An offering that has
+// not held a session yet counts as full attendance, so nobody is barred
+// from an exam before the first class.
+func AttendancePercentage(attended, total int) float64 {
+	if total == 0 {
+		return 100
+	}
+	return math.Round(float64(attended)/float64(total)*10000) / 100
+}
+
+func IsExamEligible(attended, total int) bool {
+	return AttendancePercentage(attended, total) >= config.Get().MinAttendancePercent
+}
+
+// AttendanceSummaries reports attendance for every student enrolled in a
+// course offering, including students who have not attended any session.
+func AttendanceSummaries(ctx context.Context, courseID, semesterID int) ([]models.AttendanceSummary, error) {
+	students, err := db.ListEnrolledStudents(ctx, courseID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	total, err := db.CountClassSessions(ctx, courseID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := db.CountAttendanceByStudent(ctx, courseID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]models.AttendanceSummary, 0, len(students))
+	for _, s := range students {
+		attended := counts[s.ID]
+		summaries = append(summaries, models.AttendanceSummary{
+			StudentID:    s.ID,
+			StudentName:  s.Name,
+			Attended:     attended,
+			Total:        total,
+			Percentage:   AttendancePercentage(attended, total),
+			ExamEligible: IsExamEligible(attended, total),
+		})
+	}
+	return summaries, nil
+}
+
+func StudentAttendanceSummary(ctx context.Context, studentID, courseID, semesterID int) (*models.AttendanceSummary, error) {
+	summaries, err := AttendanceSummaries(ctx, courseID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range summaries {
+		if s.StudentID == studentID {
+			return &s, nil
+		}
+	}
+	return nil, ErrNotEnrolled
+}
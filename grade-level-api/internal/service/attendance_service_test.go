@@ -0,0 +1,28 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttendancePercentage(t *testing.T) {
+	tests := []struct {
+		name            string
+		attended, total int
+		percentage      float64
+		eligible        bool
+	}{
+		{name: "no sessions held yet", attended: 0, total: 0, percentage: 100, eligible: true},
+		{name: "every session", attended: 4, total: 4, percentage: 100, eligible: true},
+		{name: "exactly the minimum", attended: 3, total: 4, percentage: 75, eligible: true},
+		{name: "rounded to two decimals", attended: 2, total: 3, percentage: 66.67, eligible: false},
+		{name: "none attended", attended: 0, total: 5, percentage: 0, eligible: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.percentage, AttendancePercentage(tt.attended, tt.total))
+			assert.Equal(t, tt.eligible, IsExamEligible(tt.attended, tt.total))
+		})
+	}
+}
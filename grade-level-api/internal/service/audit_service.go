@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+// The kinds of requirement a degree audit checks.
+const (
+	RequirementCoreCourse    = "core_course"
+	RequirementElectiveUnits = "elective_units"
+	RequirementMinCGPA       = "min_cgpa"
+)
+
+var ErrNoDepartment = errors.New("student is not on any department's programme")
+
+// SaveProgramme checks that the programme's courses exist and that each
+// level has at most one elective requirement, and saves it.
+func SaveProgramme(ctx context.Context, p *models.Programme) error {
+	if p.MinCGPA < 0 || p.MinCGPA > 5 {
+		return errors.New("min_cgpa must be between 0 and 5")
+	}
+	slices.Sort(p.CoreCourseIDs)
+	p.CoreCourseIDs = slices.Compact(p.CoreCourseIDs)
+	courses, err := db.FindCoursesByIDs(ctx, p.CoreCourseIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range p.CoreCourseIDs {
+		if courses[id] == nil {
+			return fmt.Errorf("course %d not found", id)
+		}
+	}
+
+	levels := map[int]bool{}
+	for _, e := range p.Electives {
+		if e.Level <= 0 || e.MinUnits < 0 {
+			return errors.New("electives need a positive level and non-negative min_units")
+		}
+		if levels[e.Level] {
+			return fmt.Errorf("level %d has more than one elective requirement", e.Level)
+		}
+		levels[e.Level] = true
+	}
+	return db.SaveProgramme(ctx, p)
+}
+
+// AuditStudent checks a student's published results against their
+// department's programme.
+func AuditStudent(ctx context.Context, studentID int) (*models.DegreeAudit, error) {
+	student, err := db.GetUserByID(ctx, studentID)
+	if err != nil {
+		return nil, err
+	}
+	if student.DepartmentID == nil {
+		return nil, ErrNoDepartment
+	}
+	programme, courses, err := loadProgramme(ctx, *student.DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := db.ListStudentResults(ctx, []int{student.ID})
+	if err != nil {
+		return nil, err
+	}
+	audit := Audit(student, programme, courses, results)
+	return &audit, nil
+}
+
+// AuditCohort audits every student on the department's programme, or with
+// a semesterID only those enrolled in that semester.
+func AuditCohort(ctx context.Context, departmentID, semesterID int) (*models.CohortAudit, error) {
+	programme, courses, err := loadProgramme(ctx, departmentID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := db.ListDepartmentStudents(ctx, departmentID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int, len(students))
+	for i, s := range students {
+		ids[i] = s.ID
+	}
+	results, err := db.ListStudentResults(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byStudent := map[int][]models.CourseResult{}
+	for _, r := range results {
+		byStudent[r.StudentID] = append(byStudent[r.StudentID], r)
+	}
+
+	cohort := &models.CohortAudit{
+		DepartmentID: departmentID,
+		SemesterID:   semesterID,
+		Students:     len(students),
+		Audits:       []models.DegreeAudit{},
+	}
+	for i := range students {
+		audit := Audit(&students[i], programme, courses, byStudent[students[i].ID])
+		if audit.Eligible {
+			cohort.Eligible++
+		}
+		cohort.Audits = append(cohort.Audits, audit)
+	}
+	return cohort, nil
+}
+
+func loadProgramme(ctx context.Context, departmentID int) (*models.Programme, map[int]*models.Course, error) {
+	programme, err := db.FindProgramme(ctx, departmentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	courses, err := db.FindCoursesByIDs(ctx, programme.CoreCourseIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return programme, courses, nil
+}
+
+// Audit measures a student's published results against a programme. A
+// course counts as passed with any passing attempt, and towards elective
+// units once however often it was taken; the CGPA counts every attempt,
+// as on the broadsheet. courses names the core courses in descriptions.
+func Audit(student *models.User, programme *models.Programme, courses map[int]*models.Course, results []models.CourseResult) models.DegreeAudit {
+	audit := models.DegreeAudit{
+		StudentID:    student.ID,
+		StudentName:  student.Name,
+		DepartmentID: programme.DepartmentID,
+		Satisfied:    []models.RequirementCheck{},
+		Outstanding:  []models.RequirementCheck{},
+	}
+	audit.CGPA, audit.Units = GPA(results)
+
+	best := map[int]models.CourseResult{}
+	for _, r := range results {
+		if b, ok := best[r.CourseID]; !ok || r.Score > b.Score {
+			best[r.CourseID] = r
+		}
+	}
+	passed := func(courseID int) bool {
+		r, ok := best[courseID]
+		if !ok {
+			return false
+		}
+		_, points := LetterGrade(r.Score)
+		return points > 0
+	}
+	record := func(check models.RequirementCheck) {
+		if check.Achieved >= check.Required {
+			audit.Satisfied = append(audit.Satisfied, check)
+		} else {
+			audit.Outstanding = append(audit.Outstanding, check)
+		}
+	}
+
+	core := map[int]bool{}
+	for _, id := range programme.CoreCourseIDs {
+		core[id] = true
+		name := fmt.Sprintf("course %d", id)
+		if c := courses[id]; c != nil {
+			name = c.Name
+		}
+		check := models.RequirementCheck{
+			Kind:        RequirementCoreCourse,
+			Description: "Pass " + name,
+			CourseID:    id,
+			Required:    1,
+		}
+		if r, ok := best[id]; ok {
+			check.Score = &r.Score
+		}
+		if passed(id) {
+			check.Achieved = 1
+		}
+		record(check)
+	}
+
+	for _, e := range programme.Electives {
+		var units int
+		for id, r := range best {
+			if !core[id] && r.Level == e.Level && passed(id) {
+				units += r.Units
+			}
+		}
+		record(models.RequirementCheck{
+			Kind:        RequirementElectiveUnits,
+			Description: fmt.Sprintf("Pass at least %d elective units at level %d", e.MinUnits, e.Level),
+			Level:       e.Level,
+			Required:    float64(e.MinUnits),
+			Achieved:    float64(units),
+		})
+	}
+
+	record(models.RequirementCheck{
+		Kind:        RequirementMinCGPA,
+		Description: fmt.Sprintf("Graduate with a CGPA of at least %.2f", programme.MinCGPA),
+		Required:    programme.MinCGPA,
+		Achieved:    audit.CGPA,
+	})
+
+	audit.Eligible = len(audit.Outstanding) == 0 && audit.Units > 0
+	return audit
+}
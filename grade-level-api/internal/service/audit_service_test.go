@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+func TestAudit(t *testing.T) {
+	student := &models.User{ID: 7, Name: "Grace"}
+	programme := &models.Programme{
+		DepartmentID:  1,
+		MinCGPA:       2.5,
+		CoreCourseIDs: []int{1, 2},
+		Electives:     []models.ElectiveRequirement{{Level: 300, MinUnits: 4}},
+	}
+	courses := map[int]*models.Course{1: {ID: 1, Name: "Compilers"}, 2: {ID: 2, Name: "Databases"}}
+	result := func(courseID, level, units int, score float64) models.CourseResult {
+		return models.CourseResult{StudentID: 7, CourseID: courseID, Level: level, Units: units, Score: score}
+	}
+
+	tests := []struct {
+		name        string
+		results     []models.CourseResult
+		eligible    bool
+		outstanding []string
+	}{
+		{
+			name:        "No Results",
+			outstanding: []string{RequirementCoreCourse, RequirementCoreCourse, RequirementElectiveUnits, RequirementMinCGPA},
+		},
+		{
+			name: "Everything Met",
+			results: []models.CourseResult{
+				result(1, 400, 3, 72), result(2, 300, 3, 65), result(10, 300, 2, 55), result(11, 300, 2, 48),
+			},
+			eligible: true,
+		},
+		{
+			name: "Failed Core Passed On Retake",
+			results: []models.CourseResult{
+				result(1, 400, 3, 30), result(1, 400, 3, 62), result(2, 300, 3, 65), result(10, 300, 4, 70),
+			},
+			eligible: true,
+		},
+		{
+			name: "Core Units Are Not Electives",
+			results: []models.CourseResult{
+				result(1, 400, 3, 72), result(2, 300, 3, 65), result(10, 300, 2, 55), result(11, 300, 2, 20), result(12, 200, 4, 70),
+			},
+			outstanding: []string{RequirementElectiveUnits},
+		},
+		{
+			name: "Low CGPA",
+			results: []models.CourseResult{
+				result(1, 400, 3, 41), result(2, 300, 3, 42), result(10, 300, 4, 44),
+			},
+			outstanding: []string{RequirementMinCGPA},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audit := Audit(student, programme, courses, tt.results)
+			assert.Equal(t, tt.eligible, audit.Eligible)
+			var kinds []string
+			for _, o := range audit.Outstanding {
+				kinds = append(kinds, o.Kind)
+			}
+			assert.Equal(t, tt.outstanding, kinds)
+			assert.Len(t, audit.Satisfied, 4-len(tt.outstanding))
+		})
+	}
+
+	audit := Audit(student, programme, courses, []models.CourseResult{result(1, 400, 3, 30), result(1, 400, 3, 38)})
+	assert.Equal(t, "Pass Compilers", audit.Outstanding[0].Description)
+	assert.Equal(t, 38.0, *audit.Outstanding[0].Score, "the best attempt is reported")
+}
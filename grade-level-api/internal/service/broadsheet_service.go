@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+const (
+	RemarkPass       = "pass"
+	RemarkProbation  = "probation"
+	RemarkWithdrawal = "withdrawal"
+	// RemarkNoResults marks students with nothing published yet.
+	RemarkNoResults = "no results"
+)
+
+// gradeScale is the five-point scale, from the highest band down.
+var gradeScale = []struct {
+	min    float64
+	letter string
+	points float64
+}{
+	{70, "A", 5},
+	{60, "B", 4},
+	{50, "C", 3},
+	{45, "D", 2},
+	{40, "E", 1},
+	{0, "F", 0},
+}
+
+// LetterGrade maps a score out of 100 to its letter and grade points.
+func LetterGrade(score float64) (string, float64) {
+	for _, band := range gradeScale {
+		if score >= band.min {
+			return band.letter, band.points
+		}
+	}
+	return "F", 0
+}
+
+// GPA is the unit-weighted average of grade points, rounded to two
+// decimals, with the total units it was computed over.
+func GPA(results []models.CourseResult) (float64, int) {
+	var points float64
+	var units int
+	for _, r := range results {
+		_, p := LetterGrade(r.Score)
+		points += p * float64(r.Units)
+		units += r.Units
+	}
+	if units == 0 {
+		return 0, 0
+	}
+	return math.Round(points/float64(units)*100) / 100, units
+}
+
+// Remark classifies a student's standing from their CGPA using the
+// configured probation and withdrawal thresholds.
+func Remark(cgpa float64, units int) string {
+	cfg := config.Get()
+	switch {
+	case units == 0:
+		return RemarkNoResults
+	case cgpa < cfg.WithdrawalCGPA:
+		return RemarkWithdrawal
+	case cgpa < cfg.ProbationCGPA:
+		return RemarkProbation
+	default:
+		return RemarkPass
+	}
+}
+
+// BuildBroadsheet assembles the result sheet for the students of a level in
+// a semester. Only published grades are counted; the semester GPA covers
+// every course a student took that semester, including other levels', and
+// the CGPA every semester up to and including it.
+func BuildBroadsheet(ctx context.Context, level, semesterID int) (*models.Broadsheet, error) {
+	semester, err := db.FindSemesterByID(ctx, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	courses, err := db.ListLevelCourses(ctx, level, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	students, err := db.ListLevelStudents(ctx, level, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := db.ListCohortResults(ctx, level, semesterID, semester.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	byStudent := map[int][]models.CourseResult{}
+	for _, r := range results {
+		byStudent[r.StudentID] = append(byStudent[r.StudentID], r)
+	}
+
+	sheet := &models.Broadsheet{
+		Level:        level,
+		SemesterID:   semester.ID,
+		SemesterName: semester.Name,
+		Courses:      courses,
+		Rows:         []models.BroadsheetRow{},
+	}
+	for _, student := range students {
+		all := byStudent[student.ID]
+
+		var current []models.CourseResult
+		scores := map[int]float64{}
+		for _, r := range all {
+			if r.SemesterID == semesterID {
+				current = append(current, r)
+				scores[r.CourseID] = r.Score
+			}
+		}
+
+		row := models.BroadsheetRow{StudentID: student.ID, StudentName: student.Name}
+		for _, c := range courses {
+			cell := models.BroadsheetCell{}
+			if score, ok := scores[c.ID]; ok {
+				cell.Score = &score
+				cell.Grade, cell.Points = LetterGrade(score)
+			}
+			row.Results = append(row.Results, cell)
+		}
+		row.GPA, row.Units = GPA(current)
+		var cumulativeUnits int
+		row.CGPA, cumulativeUnits = GPA(all)
+		row.Remark = Remark(row.CGPA, cumulativeUnits)
+		sheet.Rows = append(sheet.Rows, row)
+	}
+	return sheet, nil
+}
+
+// SemesterStandings computes the GPA, CGPA and remark of every student
+// enrolled in the semester who has a published result, across all levels,
+// ordered by name. Like the broadsheet it counts only published grades.
+func SemesterStandings(ctx context.Context, semesterID int) ([]models.Standing, error) {
+	semester, err := db.FindSemesterByID(ctx, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	results, err := db.ListSemesterResults(ctx, semesterID, semester.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	byStudent := map[int][]models.CourseResult{}
+	for _, r := range results {
+		byStudent[r.StudentID] = append(byStudent[r.StudentID], r)
+	}
+	ids := make([]int, 0, len(byStudent))
+	for id := range byStudent {
+		ids = append(ids, id)
+	}
+	users, err := db.GetUsersByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	standings := []models.Standing{}
+	for id, all := range byStudent {
+		var current []models.CourseResult
+		for _, r := range all {
+			if r.SemesterID == semesterID {
+				current = append(current, r)
+			}
+		}
+		s := models.Standing{StudentID: id}
+		if u := users[id]; u != nil {
+			s.StudentName = u.Name
+		}
+		s.GPA, s.Units = GPA(current)
+		var cumulativeUnits int
+		s.CGPA, cumulativeUnits = GPA(all)
+		s.Remark = Remark(s.CGPA, cumulativeUnits)
+		standings = append(standings, s)
+	}
+	slices.SortFunc(standings, func(a, b models.Standing) int {
+		if c := strings.Compare(a.StudentName, b.StudentName); c != 0 {
+			return c
+		}
+		return a.StudentID - b.StudentID
+	})
+	return standings, nil
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+func TestLetterGrade(t *testing.T) {
+	tests := []struct {
+		score  float64
+		letter string
+		points float64
+	}{
+		{100, "A", 5},
+		{70, "A", 5},
+		{69.99, "B", 4},
+		{50, "C", 3},
+		{45, "D", 2},
+		{40, "E", 1},
+		{39.5, "F", 0},
+		{0, "F", 0},
+	}
+	for _, tt := range tests {
+		letter, points := LetterGrade(tt.score)
+		assert.Equal(t, tt.letter, letter, "score %v", tt.score)
+		assert.Equal(t, tt.points, points, "score %v", tt.score)
+	}
+}
+
+func TestGPA(t *testing.T) {
+	gpa, units := GPA(nil)
+	assert.Zero(t, gpa)
+	assert.Zero(t, units)
+
+	// (5*3 + 3*2 + 0*1) / 6 = 3.5
+	gpa, units = GPA([]models.CourseResult{
+		{Units: 3, Score: 75},
+		{Units: 2, Score: 55},
+		{Units: 1, Score: 10},
+	})
+	assert.Equal(t, 3.5, gpa)
+	assert.Equal(t, 6, units)
+
+	// 13/3 rounds to two decimals.
+	gpa, _ = GPA([]models.CourseResult{{Units: 1, Score: 70}, {Units: 2, Score: 60}})
+	assert.Equal(t, 4.33, gpa)
+}
+
+func TestRemark(t *testing.T) {
+	cfg := config.Get()
+	defer func(p, w float64) { cfg.ProbationCGPA, cfg.WithdrawalCGPA = p, w }(cfg.ProbationCGPA, cfg.WithdrawalCGPA)
+	cfg.ProbationCGPA, cfg.WithdrawalCGPA = 2.0, 1.0
+
+	assert.Equal(t, RemarkNoResults, Remark(0, 0))
+	assert.Equal(t, RemarkWithdrawal, Remark(0.99, 3))
+	assert.Equal(t, RemarkProbation, Remark(1.0, 3))
+	assert.Equal(t, RemarkProbation, Remark(1.99, 3))
+	assert.Equal(t, RemarkPass, Remark(2.0, 3))
+}
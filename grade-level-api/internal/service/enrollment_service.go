@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/events"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+)
+
+// DropEnrollment takes a student out of an offering or off its waitlist
+// and notifies anyone promoted into the freed seat.
+func DropEnrollment(ctx context.Context, course *models.Course, studentID, semesterID int) error {
+	promoted, err := db.DropEnrollment(ctx, studentID, course.ID, semesterID)
+	if err != nil {
+		return err
+	}
+	notifyPromoted(ctx, course, semesterID, promoted)
+	return nil
+}
+
+// SetCapacity changes an offering's capacity, notifying anyone promoted
+// into the seats it opens, and returns the offering as it now stands.
+func SetCapacity(ctx context.Context, course *models.Course, semesterID int, capacity *int) (*models.Offering, error) {
+	promoted, err := db.SetCapacity(ctx, course.ID, semesterID, capacity)
+	if err != nil {
+		return nil, err
+	}
+	notifyPromoted(ctx, course, semesterID, promoted)
+	return db.FindOffering(ctx, course.ID, semesterID)
+}
+
+func notifyPromoted(ctx context.Context, course *models.Course, semesterID int, promoted []models.Enrollment) {
+	if len(promoted) == 0 {
+		return
+	}
+	studentIDs := make([]int, len(promoted))
+	for i, e := range promoted {
+		studentIDs[i] = e.StudentID
+	}
+	events.Publish(events.Event{
+		Type: events.WaitlistPromoted,
+		Data: map[string]any{
+			"course_id":   course.ID,
+			"course_name": course.Name,
+			"semester_id": semesterID,
+		},
+		InstitutionID: tenant.FromContext(ctx),
+		UserIDs:       studentIDs,
+	})
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+const (
+	MaxLikertRating      = 5
+	MaxCommentLength     = 2000
+	unassignedDepartment = "unassigned"
+)
+
+var (
+	ErrEvaluationClosed    = errors.New("course evaluations for this semester are not open")
+	ErrResultsNotPublished = errors.New("evaluation results are available once the course's grades are published")
+	ErrTooFewResponses     = errors.New("too few responses to report without identifying students")
+)
+
+// EvaluationOpen reports whether students may evaluate the semester's
+// courses at now.
+func EvaluationOpen(semester *models.Semester, now time.Time) bool {
+	cfg := config.Get()
+	opens := semester.EndDate.Add(-cfg.EvaluationLeadTime)
+	closes := semester.EndDate.Add(cfg.EvaluationWindow)
+	return !now.Before(opens) && !now.After(closes)
+}
+
+// SubmitEvaluation stores a student's anonymous evaluation of a course
+// offering they are enrolled in.
+func SubmitEvaluation(ctx context.Context, studentID, courseID, semesterID int, answers []models.EvaluationAnswer) error {
+	semester, err := db.FindSemesterByID(ctx, semesterID)
+	if err != nil {
+		return err
+	}
+	if !EvaluationOpen(semester, time.Now()) {
+		return ErrEvaluationClosed
+	}
+
+	questions, err := db.ListEvaluationQuestions(ctx, false)
+	if err != nil {
+		return err
+	}
+	answers, err = checkAnswers(questions, answers)
+	if err != nil {
+		return err
+	}
+
+	err = db.SubmitEvaluation(ctx, studentID, courseID, semesterID, answers)
+	if errors.Is(err, db.ErrEnrollmentNotFound) {
+		return ErrNotEnrolled
+	}
+	return err
+}
+
+// checkAnswers validates answers against the active questions: every
+// Likert question needs a rating, free-text answers are optional and
+// blank ones are dropped.
+func checkAnswers(questions []models.EvaluationQuestion, answers []models.EvaluationAnswer) ([]models.EvaluationAnswer, error) {
+	byID := make(map[int]models.EvaluationQuestion, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	answered := map[int]bool{}
+	var kept []models.EvaluationAnswer
+	for _, a := range answers {
+		q, ok := byID[a.QuestionID]
+		if !ok {
+			return nil, fmt.Errorf("question %d is not part of the evaluation", a.QuestionID)
+		}
+		if answered[a.QuestionID] {
+			return nil, fmt.Errorf("question %d is answered more than once", a.QuestionID)
+		}
+		answered[a.QuestionID] = true
+
+		switch db.QuestionKind(q.Kind) {
+		case db.QuestionLikert:
+			if a.Rating == nil || *a.Rating < 1 || *a.Rating > MaxLikertRating {
+				return nil, fmt.Errorf("question %d needs a rating from 1 to %d", q.ID, MaxLikertRating)
+			}
+			kept = append(kept, models.EvaluationAnswer{QuestionID: q.ID, Rating: a.Rating})
+		case db.QuestionText:
+			comment := strings.TrimSpace(a.Comment)
+			if len(comment) > MaxCommentLength {
+				return nil, fmt.Errorf("the answer to question %d is longer than %d characters", q.ID, MaxCommentLength)
+			}
+			if comment != "" {
+				kept = append(kept, models.EvaluationAnswer{QuestionID: q.ID, Comment: comment})
+			}
+		}
+	}
+
+	for _, q := range questions {
+		if db.QuestionKind(q.Kind) == db.QuestionLikert && !answered[q.ID] {
+			return nil, fmt.Errorf("question %d must be answered", q.ID)
+		}
+	}
+	return kept, nil
+}
+
+// CourseEvaluationReport aggregates a course offering's evaluations. It is
+// only available once the offering's grades are published and enough
+// students have responded for no answer to be traceable.
+func CourseEvaluationReport(ctx context.Context, courseID, semesterID int) (*models.EvaluationReport, error) {
+	published, err := db.GradesPublished(ctx, courseID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	if !published {
+		return nil, ErrResultsNotPublished
+	}
+
+	responses, err := db.CountEvaluationResponses(ctx, courseID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	if responses < config.Get().EvaluationMinResponses {
+		return nil, ErrTooFewResponses
+	}
+
+	answers, err := db.ListOfferingAnswers(ctx, courseID, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.EvaluationReport{
+		CourseID:   courseID,
+		SemesterID: semesterID,
+		Responses:  responses,
+		Questions:  summariseAnswers(answers),
+	}, nil
+}
+
+// summariseAnswers folds answers, already grouped by question, into one
+// result per question.
+func summariseAnswers(answers []db.OfferingAnswer) []models.QuestionResult {
+	results := []models.QuestionResult{}
+	var sum int
+	for _, a := range answers {
+		if len(results) == 0 || results[len(results)-1].QuestionID != a.Question.ID {
+			sum = 0
+			result := models.QuestionResult{QuestionID: a.Question.ID, Prompt: a.Question.Prompt, Kind: a.Question.Kind}
+			if db.QuestionKind(a.Question.Kind) == db.QuestionLikert {
+				result.Distribution = make([]int, MaxLikertRating)
+			}
+			results = append(results, result)
+		}
+		r := &results[len(results)-1]
+		r.Answers++
+
+		if a.Rating != nil && *a.Rating >= 1 && *a.Rating <= MaxLikertRating {
+			sum += *a.Rating
+			r.Distribution[*a.Rating-1]++
+			mean := math.Round(float64(sum)/float64(r.Answers)*100) / 100
+			r.MeanRating = &mean
+		}
+		if a.Comment != "" {
+			r.Comments = append(r.Comments, a.Comment)
+		}
+	}
+	return results
+}
+
+// EvaluationRankings ranks lecturers within each department by the mean
+// Likert rating of their courses in the semester. Lecturers with fewer
+// responses than the reporting minimum are left out.
+func EvaluationRankings(ctx context.Context, semesterID int) ([]models.DepartmentRanking, error) {
+	ratings, err := db.ListLecturerRatings(ctx, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	return rankLecturers(ratings, config.Get().EvaluationMinResponses), nil
+}
+
+func rankLecturers(ratings []models.LecturerRating, minResponses int) []models.DepartmentRanking {
+	rankings := []models.DepartmentRanking{}
+	// Keyed by department ID, with 0 for courses not in a department.
+	index := map[int]int{}
+	for _, lr := range ratings {
+		if lr.Responses < minResponses {
+			continue
+		}
+		lr.MeanRating = math.Round(lr.MeanRating*100) / 100
+		if lr.DepartmentID == nil {
+			lr.DepartmentName = unassignedDepartment
+		}
+
+		var key int
+		if lr.DepartmentID != nil {
+			key = *lr.DepartmentID
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(rankings)
+			index[key] = i
+			rankings = append(rankings, models.DepartmentRanking{DepartmentID: lr.DepartmentID, DepartmentName: lr.DepartmentName})
+		}
+		rankings[i].Lecturers = append(rankings[i].Lecturers, lr)
+	}
+
+	for i := range rankings {
+		lecturers := rankings[i].Lecturers
+		slices.SortFunc(lecturers, func(a, b models.LecturerRating) int {
+			return cmp.Or(
+				cmp.Compare(b.MeanRating, a.MeanRating),
+				cmp.Compare(b.Responses, a.Responses),
+				cmp.Compare(a.LecturerName, b.LecturerName),
+			)
+		})
+		// Equal means share a rank, and the next mean skips past them.
+		for j := range lecturers {
+			lecturers[j].Rank = j + 1
+			if j > 0 && lecturers[j].MeanRating == lecturers[j-1].MeanRating {
+				lecturers[j].Rank = lecturers[j-1].Rank
+			}
+		}
+	}
+	slices.SortFunc(rankings, func(a, b models.DepartmentRanking) int {
+		// The unassigned group goes last.
+		return cmp.Or(
+			cmp.Compare(boolInt(a.DepartmentID == nil), boolInt(b.DepartmentID == nil)),
+			cmp.Compare(a.DepartmentName, b.DepartmentName),
+		)
+	})
+	return rankings
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
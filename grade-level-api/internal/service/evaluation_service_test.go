@@ -0,0 +1,67 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+func TestCheckAnswers(t *testing.T) {
+	questions := []models.EvaluationQuestion{
+		{ID: 1, Kind: "likert"},
+		{ID: 2, Kind: "text"},
+	}
+	rating := func(n int) *int { return &n }
+
+	tests := []struct {
+		name    string
+		answers []models.EvaluationAnswer
+		kept    int
+		wantErr bool
+	}{
+		{"rating and comment", []models.EvaluationAnswer{{QuestionID: 1, Rating: rating(4)}, {QuestionID: 2, Comment: "good"}}, 2, false},
+		{"blank comment is dropped", []models.EvaluationAnswer{{QuestionID: 1, Rating: rating(1)}, {QuestionID: 2, Comment: "  "}}, 1, false},
+		{"likert unanswered", []models.EvaluationAnswer{{QuestionID: 2, Comment: "good"}}, 0, true},
+		{"rating out of range", []models.EvaluationAnswer{{QuestionID: 1, Rating: rating(6)}}, 0, true},
+		{"unknown question", []models.EvaluationAnswer{{QuestionID: 1, Rating: rating(3)}, {QuestionID: 9, Comment: "x"}}, 0, true},
+		{"answered twice", []models.EvaluationAnswer{{QuestionID: 1, Rating: rating(3)}, {QuestionID: 1, Rating: rating(2)}}, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, err := checkAnswers(questions, tt.answers)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Len(t, kept, tt.kept)
+		})
+	}
+}
+
+func TestRankLecturers(t *testing.T) {
+	cs, maths := 1, 2
+	rankings := rankLecturers([]models.LecturerRating{
+		{DepartmentID: &cs, DepartmentName: "Computer Science", LecturerID: 1, LecturerName: "Ada", Responses: 10, MeanRating: 4.2},
+		{DepartmentID: &cs, DepartmentName: "Computer Science", LecturerID: 2, LecturerName: "Alan", Responses: 8, MeanRating: 4.6},
+		{DepartmentID: &cs, DepartmentName: "Computer Science", LecturerID: 3, LecturerName: "Grace", Responses: 4, MeanRating: 4.2},
+		{DepartmentID: &cs, DepartmentName: "Computer Science", LecturerID: 4, LecturerName: "Linus", Responses: 2, MeanRating: 5},
+		{DepartmentID: nil, LecturerID: 5, LecturerName: "Edsger", Responses: 5, MeanRating: 3},
+		{DepartmentID: &maths, DepartmentName: "Mathematics", LecturerID: 6, LecturerName: "Emmy", Responses: 6, MeanRating: 4.9},
+	}, 3)
+
+	require.Len(t, rankings, 3)
+	assert.Equal(t, "Computer Science", rankings[0].DepartmentName)
+	assert.Equal(t, "Mathematics", rankings[1].DepartmentName)
+	assert.Equal(t, unassignedDepartment, rankings[2].DepartmentName)
+
+	// Linus has too few responses to be reported; Ada and Grace tie.
+	var got [][2]int
+	for _, lr := range rankings[0].Lecturers {
+		got = append(got, [2]int{lr.LecturerID, lr.Rank})
+	}
+	assert.Equal(t, [][2]int{{2, 1}, {1, 2}, {3, 2}}, got)
+}
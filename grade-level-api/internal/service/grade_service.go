@@ -1 +1,32 @@
 package service
+
+import (
+	"context"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/events"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+)
+
+// PublishGrades publishes a course offering's pending grades and notifies
+// the students whose grades were published. It returns how many were.
+func PublishGrades(ctx context.Context, course *models.Course, semesterID int) (int, error) {
+	studentIDs, err := db.PublishGrades(ctx, course.ID, semesterID)
+	if err != nil {
+		return 0, err
+	}
+	if len(studentIDs) > 0 {
+		events.Publish(events.Event{
+			Type: events.GradesPublished,
+			Data: map[string]any{
+				"course_id":   course.ID,
+				"course_name": course.Name,
+				"semester_id": semesterID,
+			},
+			InstitutionID: tenant.FromContext(ctx),
+			UserIDs:       studentIDs,
+		})
+	}
+	return len(studentIDs), nil
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/events"
+	"github.com/falasefemi2/gradesystem/internal/models"
+	"github.com/falasefemi2/gradesystem/internal/tenant"
+)
+
+// SetEnrollmentOpen opens or closes enrollment for a semester. Opening it
+// notifies everyone connected.
+func SetEnrollmentOpen(ctx context.Context, semesterID int, open bool) (*models.Semester, error) {
+	changed, err := db.SetEnrollmentOpen(ctx, semesterID, open)
+	if err != nil {
+		return nil, err
+	}
+	semester, err := db.FindSemesterByID(ctx, semesterID)
+	if err != nil {
+		return nil, err
+	}
+	if changed && open {
+		events.Publish(events.Event{
+			Type: events.EnrollmentOpened,
+			Data: map[string]any{
+				"semester_id":   semester.ID,
+				"semester_name": semester.Name,
+			},
+			InstitutionID: tenant.FromContext(ctx),
+		})
+	}
+	return semester, nil
+}
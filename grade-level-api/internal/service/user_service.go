@@ -1 +1,77 @@
 package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/falasefemi2/gradesystem/internal/auth"
+	"github.com/falasefemi2/gradesystem/internal/config"
+	"github.com/falasefemi2/gradesystem/internal/db"
+	"github.com/falasefemi2/gradesystem/internal/models"
+)
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("account locked until %s", e.Until.UTC().Format(time.RFC3339))
+}
+
+// LockoutDuration is how long an account is locked after the given number
+// of consecutive failures. The first lock lasts LockoutBase and every
+// further failure doubles it, up to LockoutMax.
+func LockoutDuration(failures int) time.Duration {
+	cfg := config.Get()
+	if failures < cfg.LockoutThreshold {
+		return 0
+	}
+	d := cfg.LockoutBase
+	for i := cfg.LockoutThreshold; i < failures && d < cfg.LockoutMax; i++ {
+		d *= 2
+	}
+	return min(d, cfg.LockoutMax)
+}
+
+// Authenticate checks a user's credentials, keeping track of consecutive
+// failures and locking the account once there are too many.
+func Authenticate(ctx context.Context, email, password string) (*models.User, error) {
+	user, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	now := time.Now()
+	if user.LockedUntil != nil && user.LockedUntil.After(now) {
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+
+	if err := auth.VerifyPassword(password, user.Password); err != nil {
+		failures, err := db.RecordFailedLogin(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if d := LockoutDuration(failures); d > 0 {
+			until := now.Add(d)
+			if err := db.LockUser(ctx, user.ID, until); err != nil {
+				return nil, err
+			}
+			return nil, &AccountLockedError{Until: until}
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := db.ResetLoginFailures(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	if user.EmailVerifiedAt == nil {
+		return nil, ErrEmailNotVerified
+	}
+	return user, nil
+}
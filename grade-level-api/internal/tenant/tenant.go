@@ -0,0 +1,26 @@
+// Package tenant carries the institution a request acts for. Everything
+// in the database belongs to one institution, and the db package scopes
+// every query to the institution in the context.
+package tenant
+
+import "context"
+
+// Default is the institution created by the migrations. Work outside of a
+// request, and requests that name no institution, act for it, so a
+// single-school deployment needs no configuration.
+const Default = 1
+
+type contextKey struct{}
+
+// With returns a copy of ctx acting for the institution.
+func With(ctx context.Context, institutionID int) context.Context {
+	return context.WithValue(ctx, contextKey{}, institutionID)
+}
+
+// FromContext returns the institution ctx acts for, or Default.
+func FromContext(ctx context.Context) int {
+	if id, ok := ctx.Value(contextKey{}).(int); ok {
+		return id
+	}
+	return Default
+}
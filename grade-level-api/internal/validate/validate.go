@@ -0,0 +1,185 @@
+// Package validate checks request structs against their `validate` tags.
+//
+// Supported rules, comma separated:
+//
+//	required     the field must not be its zero value
+//	email        a bare email address
+//	min=N/max=N  bounds on numbers, or on the length of strings and slices
+//	oneof=a b c  one of the space separated values
+//	date         a YYYY-MM-DD date
+//	datetime     an RFC 3339 timestamp
+//
+// Rules other than required are skipped for fields left empty.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type Rule struct {
+	Name  string
+	Param string
+}
+
+// Rules parses a `validate` struct tag.
+func Rules(tag string) []Rule {
+	if tag == "" {
+		return nil
+	}
+	var rules []Rule
+	for _, part := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(strings.TrimSpace(part), "=")
+		rules = append(rules, Rule{Name: name, Param: param})
+	}
+	return rules
+}
+
+// FieldName is the name a struct field has in JSON.
+func FieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" {
+		return f.Name
+	}
+	return name
+}
+
+// Struct validates v, which must be a struct or a pointer to one. It
+// returns nil when every field is valid.
+func Struct(v any) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	rt := rv.Type()
+
+	var errs Errors
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		rules := Rules(field.Tag.Get("validate"))
+		if len(rules) == 0 {
+			continue
+		}
+		if msg := checkField(rv.Field(i), rules); msg != "" {
+			errs = append(errs, FieldError{Field: FieldName(field), Message: msg})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func checkField(v reflect.Value, rules []Rule) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			for _, r := range rules {
+				if r.Name == "required" {
+					return "is required"
+				}
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	empty := v.IsZero() || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "")
+	for _, r := range rules {
+		if r.Name == "required" && empty {
+			return "is required"
+		}
+	}
+	if empty {
+		return ""
+	}
+
+	for _, r := range rules {
+		if msg := checkRule(v, r); msg != "" {
+			return msg
+		}
+	}
+	return ""
+}
+
+func checkRule(v reflect.Value, r Rule) string {
+	switch r.Name {
+	case "required":
+		return ""
+	case "email":
+		addr, err := mail.ParseAddress(v.String())
+		if err != nil || addr.Address != v.String() {
+			return "must be a valid email address"
+		}
+	case "min", "max":
+		limit, err := strconv.ParseFloat(r.Param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: bad %s parameter %q", r.Name, r.Param))
+		}
+		n, unit := measure(v)
+		if r.Name == "min" && n < limit {
+			if unit == "" {
+				return "must be at least " + r.Param
+			}
+			return fmt.Sprintf("must be at least %s %s", r.Param, unit)
+		}
+		if r.Name == "max" && n > limit {
+			if unit == "" {
+				return "must be at most " + r.Param
+			}
+			return fmt.Sprintf("must be at most %s %s", r.Param, unit)
+		}
+	case "oneof":
+		options := strings.Fields(r.Param)
+		for _, o := range options {
+			if v.String() == o {
+				return ""
+			}
+		}
+		return "must be one of: " + strings.Join(options, ", ")
+	case "date":
+		if _, err := time.Parse(time.DateOnly, v.String()); err != nil {
+			return "must be a date in YYYY-MM-DD format"
+		}
+	case "datetime":
+		if _, err := time.Parse(time.RFC3339, v.String()); err != nil {
+			return "must be an RFC 3339 timestamp"
+		}
+	default:
+		panic("validate: unknown rule " + r.Name)
+	}
+	return ""
+}
+
+// measure returns what min and max compare against: the value of a number
+// or the length of a string or slice, with the unit used in messages.
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String()))), "characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), "items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	panic("validate: min/max not supported on " + v.Kind().String())
+}
@@ -0,0 +1,52 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sample struct {
+	Email string   `json:"email" validate:"required,email"`
+	Role  string   `json:"role" validate:"oneof=student lecturer"`
+	Name  string   `json:"name" validate:"max=5"`
+	Score *float64 `json:"score" validate:"min=0,max=100"`
+	Day   string   `json:"day" validate:"date"`
+	IDs   []int    `json:"ids" validate:"min=1"`
+}
+
+func TestStruct(t *testing.T) {
+	high := 101.0
+	tests := []struct {
+		name   string
+		input  sample
+		fields []string
+	}{
+		{"valid", sample{Email: "a@b.com", Role: "student", IDs: []int{1}}, nil},
+		{"missing required", sample{IDs: []int{1}}, []string{"email"}},
+		{"bad email", sample{Email: "nope", IDs: []int{1}}, []string{"email"}},
+		{"not one of", sample{Email: "a@b.com", Role: "admin", IDs: []int{1}}, []string{"role"}},
+		{"too long", sample{Email: "a@b.com", Name: "abcdef", IDs: []int{1}}, []string{"name"}},
+		{"out of range", sample{Email: "a@b.com", Score: &high, IDs: []int{1}}, []string{"score"}},
+		{"bad date", sample{Email: "a@b.com", Day: "2024-13-01", IDs: []int{1}}, []string{"day"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Struct(&tt.input)
+			if tt.fields == nil {
+				assert.NoError(t, err)
+				return
+			}
+			errs, ok := err.(Errors)
+			if !assert.True(t, ok, "expected Errors, got %v", err) {
+				return
+			}
+			var fields []string
+			for _, fe := range errs {
+				fields = append(fields, fe.Field)
+			}
+			assert.Equal(t, tt.fields, fields)
+		})
+	}
+}
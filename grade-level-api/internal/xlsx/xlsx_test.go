@@ -0,0 +1,53 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumn(t *testing.T) {
+	for i, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 51: "AZ", 52: "BA", 701: "ZZ", 702: "AAA"} {
+		assert.Equal(t, want, Column(i), "index %d", i)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	var buf bytes.Buffer
+	err := Write(&buf, "Level 400: 2025/26", [][]any{
+		{"name", "score"},
+		{"Ada & <Grace>", 71.5},
+		{"Alan", nil, 3},
+	})
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		b, err := io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+		files[f.Name] = string(b)
+	}
+
+	require.Contains(t, files, "[Content_Types].xml")
+	assert.Contains(t, files["xl/workbook.xml"], `name="Level 400 202526"`)
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	assert.Contains(t, sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">Ada &amp; &lt;Grace&gt;</t></is></c>`)
+	assert.Contains(t, sheet, `<c r="B2"><v>71.5</v></c>`)
+	assert.Contains(t, sheet, `<c r="C3"><v>3</v></c>`)
+	assert.NotContains(t, sheet, `r="B3"`)
+}
+
+func TestWriteRejectsUnknownCells(t *testing.T) {
+	assert.Error(t, Write(io.Discard, "x", [][]any{{true}}))
+}
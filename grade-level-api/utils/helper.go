@@ -16,3 +16,12 @@ func WriteError(w http.ResponseWriter, status int, msg string) {
 		"error": msg,
 	})
 }
+
+// WriteErrorDetails writes an error in the same shape as WriteError with
+// extra detail, such as per-field validation failures, under "details".
+func WriteErrorDetails(w http.ResponseWriter, status int, msg string, details any) {
+	WriteJSON(w, status, map[string]any{
+		"error":   msg,
+		"details": details,
+	})
+}
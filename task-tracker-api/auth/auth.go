@@ -11,8 +11,10 @@ import (
 
 var jwtSecret = []byte("supersecretkey")
 
+// Claims identify the user by ID so a token stays valid when the user
+// changes their email.
 type Claims struct {
-	Email string `json:"email"`
+	UserID int `json:"user_id"`
 	jwt.RegisteredClaims
 }
 
@@ -35,10 +37,10 @@ func ValidatePassword(password string) error {
 	return nil
 }
 
-func GenerateJWT(email string) (string, error) {
+func GenerateJWT(userID int) (string, error) {
 	expirationTime := time.Now().Add(24 * time.Hour)
 	claims := &Claims{
-		Email: email,
+		UserID: userID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 		},
@@ -57,7 +59,7 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if !token.Valid {
+	if !token.Valid || claims.UserID == 0 {
 		return nil, errors.New("invalid token")
 	}
 
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestJWTCarriesUserID(t *testing.T) {
+	token, err := GenerateJWT(42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("ValidateJWT: %v", err)
+	}
+	if claims.UserID != 42 {
+		t.Errorf("UserID = %d, want 42", claims.UserID)
+	}
+}
+
+func TestValidateJWTRejects(t *testing.T) {
+	sign := func(claims jwt.Claims, key []byte) string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		// Tokens issued before claims carried the user ID only had an email.
+		{"no user id", sign(jwt.MapClaims{"email": "ada@example.com"}, jwtSecret)},
+		{"other key", sign(&Claims{UserID: 42}, []byte("not-the-secret"))},
+		{"malformed", "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ValidateJWT(tt.token); err == nil {
+				t.Error("ValidateJWT accepted the token")
+			}
+		})
+	}
+}
@@ -2,33 +2,67 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
+
+	"github.com/falasefemi2/ask-tracker-api/httpjson"
+	"github.com/falasefemi2/ask-tracker-api/models"
 )
 
-func JWTMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
-			return
-		}
-
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			http.Error(w, "Could not find bearer token in Authorization header", http.StatusUnauthorized)
-			return
-		}
-
-		claims, err := ValidateJWT(tokenString)
-		if err != nil {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
-
-		ctx := context.WithValue(r.Context(), "user", claims)
-		r = r.WithContext(ctx)
-
-		next.ServeHTTP(w, r)
-	})
+type contextKey struct{}
+
+// ErrUserNotFound is what a UserLookup returns when the token's user no
+// longer exists.
+var ErrUserNotFound = errors.New("user not found")
+
+// UserLookup resolves the user a token was issued to. It is passed in rather
+// than imported because the db package already depends on auth.
+type UserLookup func(id int) (*models.User, error)
+
+func JWTMiddleware(lookup UserLookup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				httpjson.Error(w, http.StatusUnauthorized, "Authorization header required")
+				return
+			}
+
+			tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+			if tokenString == authHeader {
+				httpjson.Error(w, http.StatusUnauthorized, "Could not find bearer token in Authorization header")
+				return
+			}
+
+			claims, err := ValidateJWT(tokenString)
+			if err != nil {
+				httpjson.Error(w, http.StatusUnauthorized, "Invalid token")
+				return
+			}
+
+			user, err := lookup(claims.UserID)
+			if errors.Is(err, ErrUserNotFound) {
+				httpjson.Error(w, http.StatusUnauthorized, "User not found")
+				return
+			}
+			if err != nil {
+				log.Printf("looking up user %d: %v", claims.UserID, err)
+				httpjson.Error(w, http.StatusInternalServerError, "Could not load user")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), contextKey{}, user)
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserFromContext returns the user JWTMiddleware resolved for the request.
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(contextKey{}).(*models.User)
+	return user, ok
 }
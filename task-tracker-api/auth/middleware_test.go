@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/falasefemi2/ask-tracker-api/models"
+)
+
+func TestJWTMiddleware(t *testing.T) {
+	ada := &models.User{ID: 42, Email: "ada@example.com"}
+	token, err := GenerateJWT(ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		header string
+		lookup UserLookup
+		want   int
+	}{
+		{"valid token", "Bearer " + token, func(int) (*models.User, error) { return ada, nil }, http.StatusOK},
+		{"no header", "", nil, http.StatusUnauthorized},
+		{"not a bearer token", token, nil, http.StatusUnauthorized},
+		{"invalid token", "Bearer nonsense", nil, http.StatusUnauthorized},
+		{"deleted user", "Bearer " + token, func(int) (*models.User, error) { return nil, ErrUserNotFound }, http.StatusUnauthorized},
+		{"lookup failure", "Bearer " + token, func(int) (*models.User, error) { return nil, errors.New("connection refused") }, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var looked int
+			lookup := func(id int) (*models.User, error) {
+				looked = id
+				return tt.lookup(id)
+			}
+			var got *models.User
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = UserFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			JWTMiddleware(lookup)(next).ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.lookup != nil && looked != ada.ID {
+				t.Errorf("looked up user %d, want %d", looked, ada.ID)
+			}
+			if tt.want == http.StatusOK && got != ada {
+				t.Errorf("user in context = %v, want %v", got, ada)
+			}
+			if tt.want == http.StatusOK {
+				return
+			}
+			if got != nil {
+				t.Error("next handler ran")
+			}
+			var body struct {
+				Error string `json:"error"`
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error == "" {
+				t.Errorf("body = %q, want a JSON error", rec.Body)
+			}
+		})
+	}
+}
+
+func TestUserFromContextWithoutMiddleware(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/tasks", nil)
+	if user, ok := UserFromContext(req.Context()); ok || user != nil {
+		t.Errorf("UserFromContext = %v, %v; want nil, false", user, ok)
+	}
+}
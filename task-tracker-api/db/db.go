@@ -4,7 +4,6 @@ package db
 
 import (
 	"database/sql"
-	"fmt"
 	"log"
 
 	_ "github.com/go-sql-driver/mysql"
@@ -12,6 +11,12 @@ import (
 
 var DB *sql.DB
 
+// ValidationError is an error caused by the caller's input rather than by
+// the database; handlers answer it with 400 Bad Request.
+type ValidationError string
+
+func (e ValidationError) Error() string { return string(e) }
+
 func Init() {
 	var err error
 	dsn := "root:admin@tcp(localhost:3306)/tasktracker"
@@ -23,5 +28,8 @@ func Init() {
 	if err != nil {
 		log.Fatal("Error connecting to database", err)
 	}
-	fmt.Println("Database connected successfully!")
+	if err := Migrate(); err != nil {
+		log.Fatal("Error migrating database", err)
+	}
+	log.Println("Database connected successfully!")
 }
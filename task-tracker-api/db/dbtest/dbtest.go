@@ -0,0 +1,128 @@
+// Package dbtest stands in for MySQL in tests. Every statement the db
+// package runs is handed to a function the test supplies, which answers
+// it the way the database would.
+package dbtest
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/falasefemi2/ask-tracker-api/db"
+)
+
+// Result is the answer to one statement: Columns and Rows for a query,
+// RowsAffected and LastInsertID for anything else.
+type Result struct {
+	Columns      []string
+	Rows         [][]driver.Value
+	RowsAffected int64
+	LastInsertID int64
+}
+
+// Func answers a statement. query has its whitespace collapsed, so tests
+// can match on fragments such as "UPDATE tasks SET".
+type Func func(query string, args []driver.Value) (Result, error)
+
+var (
+	register sync.Once
+	funcs    sync.Map
+	next     atomic.Int64
+)
+
+// Open points db.DB at a database answered by f until the test ends.
+func Open(t *testing.T, f Func) {
+	t.Helper()
+	register.Do(func() { sql.Register("dbtest", fakeDriver{}) })
+
+	name := strconv.FormatInt(next.Add(1), 10)
+	funcs.Store(name, f)
+	conn, err := sql.Open("dbtest", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prev := db.DB
+	db.DB = conn
+	t.Cleanup(func() {
+		db.DB = prev
+		conn.Close()
+		funcs.Delete(name)
+	})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	f, _ := funcs.Load(name)
+	return &conn{answer: f.(Func)}, nil
+}
+
+type conn struct {
+	answer Func
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: strings.Join(strings.Fields(query), " ")}, nil
+}
+
+func (c *conn) Close() error              { return nil }
+func (c *conn) Begin() (driver.Tx, error) { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.conn.answer(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return result{lastInsertID: res.LastInsertID, rowsAffected: res.RowsAffected}, nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	res, err := s.conn.answer(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: res.Columns, values: res.Rows}, nil
+}
+
+type result struct {
+	lastInsertID, rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r result) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string { return r.columns }
+func (r *rows) Close() error      { return nil }
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
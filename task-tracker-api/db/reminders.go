@@ -0,0 +1,81 @@
+package db
+
+import (
+	"time"
+
+	"github.com/falasefemi2/ask-tracker-api/models"
+)
+
+// TaskReminder is an open task coming due, with the address of its owner.
+type TaskReminder struct {
+	Task  models.Task
+	Email string
+}
+
+// TasksToRemind returns open tasks due after since and up to until that
+// have not had a reminder for their current due date. since may be in the
+// past, to catch tasks that came due while no scheduler was running.
+func TasksToRemind(since, until time.Time) ([]TaskReminder, error) {
+	rows, err := DB.Query(`
+		SELECT t.id, t.user_id, t.title, t.description, t.status, t.priority, t.due_at,
+			t.time_zone, t.completed_at, t.created_at, t.updated_at, u.email
+		FROM tasks t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.due_at > ? AND t.due_at <= ? AND t.reminded_at IS NULL AND t.status IN (?, ?)
+		ORDER BY t.due_at
+	`, since, until, models.StatusTodo, models.StatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var reminders []TaskReminder
+	for rows.Next() {
+		var email string
+		task, err := scanTask(withTrailing(rows, &email))
+		if err != nil {
+			return nil, err
+		}
+		reminders = append(reminders, TaskReminder{Task: *task, Email: email})
+	}
+	return reminders, rows.Err()
+}
+
+// ClaimReminder marks a task's reminder as sent. It reports false if another
+// scheduler got there first, so each reminder goes out once.
+func ClaimReminder(taskID int) (bool, error) {
+	result, err := DB.Exec(
+		"UPDATE tasks SET reminded_at = ? WHERE id = ? AND reminded_at IS NULL",
+		time.Now(), taskID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// ReleaseReminder undoes a claim after the reminder failed to send, so the
+// next run tries again.
+func ReleaseReminder(taskID int) error {
+	_, err := DB.Exec("UPDATE tasks SET reminded_at = NULL WHERE id = ?", taskID)
+	return err
+}
+
+// trailingScanner lets scanTask read a row that has extra columns after
+// the task's own.
+type trailingScanner struct {
+	row   rowScanner
+	extra []any
+}
+
+func withTrailing(row rowScanner, extra ...any) rowScanner {
+	return trailingScanner{row: row, extra: extra}
+}
+
+func (s trailingScanner) Scan(dest ...any) error {
+	return s.row.Scan(append(dest, s.extra...)...)
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/falasefemi2/ask-tracker-api/models"
+)
+
+type migration struct {
+	version    int
+	name       string
+	statements []string
+	// run, when set, is called before statements, for steps that need Go
+	// logic rather than plain SQL.
+	run func() error
+}
+
+// migrations are applied in order and recorded in schema_migrations, so a
+// released migration must never be edited; add a new one instead.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "base tables",
+		statements: []string{
+			`CREATE TABLE IF NOT EXISTS users (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				email VARCHAR(255) NOT NULL UNIQUE,
+				password_hash VARCHAR(255) NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS tasks (
+				id INT AUTO_INCREMENT PRIMARY KEY,
+				user_id INT NOT NULL,
+				title VARCHAR(255) NOT NULL,
+				description TEXT NOT NULL,
+				status VARCHAR(20) NOT NULL DEFAULT 'todo',
+				created_at DATETIME NOT NULL,
+				updated_at DATETIME NOT NULL,
+				FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+			)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "task search",
+		statements: []string{
+			`ALTER TABLE tasks ADD FULLTEXT INDEX ft_tasks_search (title, description)`,
+		},
+	},
+	{
+		version: 3,
+		name:    "task status",
+		run:     normalizeTaskStatuses,
+		statements: []string{
+			`ALTER TABLE tasks
+				MODIFY status VARCHAR(20) NOT NULL DEFAULT 'todo',
+				ADD CONSTRAINT chk_tasks_status CHECK (status IN ('todo', 'in_progress', 'done', 'archived')),
+				ADD COLUMN completed_at DATETIME NULL AFTER status`,
+			`UPDATE tasks SET completed_at = updated_at WHERE status = 'done'`,
+		},
+	},
+	{
+		version: 4,
+		name:    "due dates and priorities",
+		statements: []string{
+			`ALTER TABLE tasks
+				ADD COLUMN priority VARCHAR(10) NOT NULL DEFAULT 'medium' AFTER status,
+				ADD COLUMN due_at DATETIME NULL AFTER priority,
+				ADD COLUMN time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC' AFTER due_at,
+				ADD COLUMN reminded_at DATETIME NULL AFTER time_zone,
+				ADD CONSTRAINT chk_tasks_priority CHECK (priority IN ('low', 'medium', 'high', 'urgent')),
+				ADD INDEX idx_tasks_due (due_at)`,
+		},
+	},
+}
+
+// legacyStatuses are spellings clients used while any status string was
+// accepted, keyed after lowercasing and turning spaces and hyphens into
+// underscores.
+var legacyStatuses = map[string]string{
+	"pending":    models.StatusTodo,
+	"open":       models.StatusTodo,
+	"new":        models.StatusTodo,
+	"to_do":      models.StatusTodo,
+	"inprogress": models.StatusInProgress,
+	"started":    models.StatusInProgress,
+	"doing":      models.StatusInProgress,
+	"complete":   models.StatusDone,
+	"completed":  models.StatusDone,
+	"finished":   models.StatusDone,
+	"archive":    models.StatusArchived,
+}
+
+// canonicalStatus maps a stored status to one of the allowed statuses,
+// reporting false when it cannot tell which one was meant.
+func canonicalStatus(raw string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	key = strings.NewReplacer(" ", "_", "-", "_").Replace(key)
+	if models.ValidStatus(key) {
+		return key, true
+	}
+	status, ok := legacyStatuses[key]
+	return status, ok
+}
+
+// normalizeTaskStatuses rewrites legacy statuses to the allowed ones. It
+// fails, leaving the migration to be retried, if any status cannot be
+// mapped, rather than guess and lose whether work was finished.
+func normalizeTaskStatuses() error {
+	rows, err := DB.Query(`SELECT BINARY status, COUNT(*) FROM tasks GROUP BY BINARY status`)
+	if err != nil {
+		return err
+	}
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			rows.Close()
+			return err
+		}
+		counts[status] = n
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var unknown []string
+	for raw, n := range counts {
+		status, ok := canonicalStatus(raw)
+		if !ok {
+			unknown = append(unknown, fmt.Sprintf("%q (%d)", raw, n))
+			continue
+		}
+		if status == raw {
+			continue
+		}
+		if _, err := DB.Exec(`UPDATE tasks SET status = ? WHERE BINARY status = ?`, status, raw); err != nil {
+			return err
+		}
+		log.Printf("migration: %d task(s) with status %q set to %q", n, raw, status)
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("tasks have statuses that cannot be mapped to todo, in_progress, done or archived: %s; update them and restart",
+			strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func Migrate() error {
+	if _, err := DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INT PRIMARY KEY,
+		name VARCHAR(255) NOT NULL
+	)`); err != nil {
+		return err
+	}
+
+	applied := map[int]bool{}
+	rows, err := DB.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return err
+		}
+		applied[v] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+		if m.run != nil {
+			if err := m.run(); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+		}
+		for _, stmt := range m.statements {
+			if _, err := DB.Exec(stmt); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+			}
+		}
+		if _, err := DB.Exec(
+			`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`,
+			m.version, m.name,
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package db
+
+import "testing"
+
+func TestCanonicalStatus(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+		ok   bool
+	}{
+		{"todo", "todo", true},
+		{"in_progress", "in_progress", true},
+		{"done", "done", true},
+		{"archived", "archived", true},
+		{"Done", "done", true},
+		{" TODO ", "todo", true},
+		{"in-progress", "in_progress", true},
+		{"In Progress", "in_progress", true},
+		{"inprogress", "in_progress", true},
+		{"completed", "done", true},
+		{"Complete", "done", true},
+		{"finished", "done", true},
+		{"pending", "todo", true},
+		{"to-do", "todo", true},
+		{"archive", "archived", true},
+		{"blocked", "", false},
+		{"", "", false},
+		{"cancelled", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := canonicalStatus(tt.raw)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("canonicalStatus(%q) = %q, %v; want %q, %v", tt.raw, got, ok, tt.want, tt.ok)
+		}
+	}
+}
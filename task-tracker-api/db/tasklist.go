@@ -0,0 +1,300 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/falasefemi2/ask-tracker-api/models"
+)
+
+var ErrInvalidTaskFilter error = ValidationError("invalid task filter")
+
+func invalidFilter(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidTaskFilter, reason)
+}
+
+const (
+	DefaultTaskLimit = 20
+	MaxTaskLimit     = 100
+)
+
+// taskSortColumns are the fields a task list can be sorted by.
+var taskSortColumns = map[string]bool{
+	"created_at": true,
+	"updated_at": true,
+	"title":      true,
+	"status":     true,
+}
+
+// TaskFilter narrows and orders a user's task list. Zero values mean no
+// filter; the After bounds are inclusive and the Before bounds exclusive.
+// Overdue keeps open tasks whose due date has passed.
+type TaskFilter struct {
+	Status        string
+	Priority      string
+	Overdue       bool
+	Search        string
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	UpdatedAfter  time.Time
+	UpdatedBefore time.Time
+	Sort          string
+	Order         string
+	Cursor        string
+	Limit         int
+}
+
+type TaskPage struct {
+	Tasks      []models.Task `json:"tasks"`
+	Total      int           `json:"total"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// taskCursor marks the last task of a page by its sort value and ID, which
+// together are unique, so the next page starts right after it even when
+// tasks are added in between.
+type taskCursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+func encodeCursor(c taskCursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor reads a cursor the client sent back and checks it was
+// issued for the same sort, since its value is compared to that column.
+func decodeCursor(s, sort, order string) (*taskCursor, error) {
+	var c taskCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, invalidFilter("malformed cursor")
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID <= 0 {
+		return nil, invalidFilter("malformed cursor")
+	}
+	if c.Sort != sort || c.Order != order {
+		return nil, invalidFilter("cursor was issued for a different sort order")
+	}
+	switch c.Sort {
+	case "created_at", "updated_at":
+		if _, err := time.Parse("2006-01-02 15:04:05", c.Value); err != nil {
+			return nil, invalidFilter("malformed cursor")
+		}
+	case "status":
+		if !models.ValidStatus(c.Value) {
+			return nil, invalidFilter("malformed cursor")
+		}
+	}
+	return &c, nil
+}
+
+// sortValue is the task's value for the sort column, formatted the way
+// MySQL compares it.
+func sortValue(task *models.Task, column string) string {
+	switch column {
+	case "updated_at":
+		return task.UpdatedAt.Format("2006-01-02 15:04:05")
+	case "title":
+		return task.Title
+	case "status":
+		return task.Status
+	}
+	return task.CreatedAt.Format("2006-01-02 15:04:05")
+}
+
+// ftMinTokenSize is InnoDB's default innodb_ft_min_token_size; shorter
+// words are not indexed, so a FULLTEXT search for them finds nothing.
+const ftMinTokenSize = 3
+
+// booleanSearch turns free text into a MySQL boolean-mode query that
+// requires every indexed word, matching it as a prefix. Anything that is
+// not a letter, digit or underscore separates words, as it does for the
+// FULLTEXT parser, so no operator reaches MySQL. It returns "" when no word
+// is long enough to be indexed.
+func booleanSearch(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	var terms []string
+	for _, word := range words {
+		if utf8.RuneCountInString(word) < ftMinTokenSize {
+			continue
+		}
+		terms = append(terms, "+"+word+"*")
+	}
+	return strings.Join(terms, " ")
+}
+
+// likePattern matches text anywhere in a column, with LIKE's wildcards in
+// text taken literally.
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(text)
+	return "%" + escaped + "%"
+}
+
+// normalize validates the filter, fills in defaults and decodes its
+// cursor, which is nil on the first page.
+func (f *TaskFilter) normalize() (*taskCursor, error) {
+	if f.Status != "" && !models.ValidStatus(f.Status) {
+		return nil, invalidFilter("unknown status " + f.Status)
+	}
+	if f.Priority != "" && !models.ValidPriority(f.Priority) {
+		return nil, invalidFilter("unknown priority " + f.Priority)
+	}
+	if f.Sort == "" {
+		f.Sort = "created_at"
+	}
+	if !taskSortColumns[f.Sort] {
+		return nil, invalidFilter("cannot sort by " + f.Sort)
+	}
+	if f.Order == "" {
+		f.Order = "desc"
+	}
+	if f.Order != "asc" && f.Order != "desc" {
+		return nil, invalidFilter("order must be asc or desc")
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultTaskLimit
+	}
+	if f.Limit > MaxTaskLimit {
+		f.Limit = MaxTaskLimit
+	}
+	if f.Cursor == "" {
+		return nil, nil
+	}
+	return decodeCursor(f.Cursor, f.Sort, f.Order)
+}
+
+func ListTasks(userID int, filter TaskFilter) (*TaskPage, error) {
+	cursor, err := filter.normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	where := []string{"user_id = ?"}
+	args := []any{userID}
+	if filter.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, filter.Status)
+	}
+	if filter.Priority != "" {
+		where = append(where, "priority = ?")
+		args = append(args, filter.Priority)
+	}
+	if filter.Overdue {
+		where = append(where, "due_at < ? AND status IN (?, ?)")
+		args = append(args, time.Now(), models.StatusTodo, models.StatusInProgress)
+	}
+	// Text made only of words too short for the FULLTEXT index is matched
+	// with LIKE instead, which cannot use an index but still finds them.
+	if search := booleanSearch(filter.Search); search != "" {
+		where = append(where, "MATCH (title, description) AGAINST (? IN BOOLEAN MODE)")
+		args = append(args, search)
+	} else if text := strings.TrimSpace(filter.Search); text != "" {
+		where = append(where, "(title LIKE ? OR description LIKE ?)")
+		args = append(args, likePattern(text), likePattern(text))
+	}
+	bounds := []struct {
+		clause string
+		value  time.Time
+	}{
+		{"created_at >= ?", filter.CreatedAfter},
+		{"created_at < ?", filter.CreatedBefore},
+		{"updated_at >= ?", filter.UpdatedAfter},
+		{"updated_at < ?", filter.UpdatedBefore},
+	}
+	for _, b := range bounds {
+		if !b.value.IsZero() {
+			where = append(where, b.clause)
+			args = append(args, b.value)
+		}
+	}
+
+	page := &TaskPage{Tasks: []models.Task{}}
+	err = DB.QueryRow(
+		"SELECT COUNT(*) FROM tasks WHERE "+strings.Join(where, " AND "), args...,
+	).Scan(&page.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	cmp := "<"
+	if filter.Order == "asc" {
+		cmp = ">"
+	}
+	if cursor != nil {
+		where = append(where, "("+filter.Sort+" "+cmp+" ? OR ("+filter.Sort+" = ? AND id "+cmp+" ?))")
+		args = append(args, cursor.Value, cursor.Value, cursor.ID)
+	}
+
+	query := `
+		SELECT ` + taskColumns + `
+		FROM tasks
+		WHERE ` + strings.Join(where, " AND ") + `
+		ORDER BY ` + filter.Sort + ` ` + filter.Order + `, id ` + filter.Order + `
+		LIMIT ?
+	`
+	// One extra row tells whether there is a next page.
+	args = append(args, filter.Limit+1)
+	rows, err := DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		page.Tasks = append(page.Tasks, *task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(page.Tasks) > filter.Limit {
+		page.Tasks = page.Tasks[:filter.Limit]
+		last := &page.Tasks[len(page.Tasks)-1]
+		page.NextCursor = encodeCursor(taskCursor{
+			Sort:  filter.Sort,
+			Order: filter.Order,
+			Value: sortValue(last, filter.Sort),
+			ID:    last.ID,
+		})
+	}
+	return page, nil
+}
+
+// DueTasks returns the user's open tasks due in [from, to), soonest first.
+func DueTasks(userID int, from, to time.Time) ([]models.Task, error) {
+	rows, err := DB.Query(`
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE user_id = ? AND due_at >= ? AND due_at < ? AND status IN (?, ?)
+		ORDER BY due_at, id
+	`, userID, from, to, models.StatusTodo, models.StatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := []models.Task{}
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, *task)
+	}
+	return tasks, rows.Err()
+}
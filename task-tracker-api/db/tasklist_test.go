@@ -0,0 +1,149 @@
+package db
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	want := taskCursor{Sort: "created_at", Order: "desc", Value: "2025-03-01 09:30:00", ID: 42}
+	got, err := decodeCursor(encodeCursor(want), "created_at", "desc")
+	if err != nil {
+		t.Fatalf("decodeCursor: %v", err)
+	}
+	if *got != want {
+		t.Errorf("got %+v, want %+v", *got, want)
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	raw := func(s string) string { return base64.RawURLEncoding.EncodeToString([]byte(s)) }
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		order  string
+		ok     bool
+	}{
+		{"valid title", encodeCursor(taskCursor{"title", "asc", "Buy milk", 3}), "title", "asc", true},
+		{"valid status", encodeCursor(taskCursor{"status", "desc", "done", 3}), "status", "desc", true},
+		{"not base64", "!!!", "created_at", "desc", false},
+		{"padded base64", base64.URLEncoding.EncodeToString([]byte(`{"s":"title"}`)), "title", "desc", false},
+		{"not json", raw("hello"), "created_at", "desc", false},
+		{"wrong json type", raw(`{"s":"title","o":"asc","v":"x","id":"3"}`), "title", "asc", false},
+		{"missing id", raw(`{"s":"title","o":"asc","v":"x"}`), "title", "asc", false},
+		{"negative id", encodeCursor(taskCursor{"title", "asc", "x", -1}), "title", "asc", false},
+		{"other sort", encodeCursor(taskCursor{"title", "desc", "x", 3}), "created_at", "desc", false},
+		{"other order", encodeCursor(taskCursor{"title", "asc", "x", 3}), "title", "desc", false},
+		{"tampered time", encodeCursor(taskCursor{"updated_at", "desc", "0 OR 1=1", 3}), "updated_at", "desc", false},
+		{"tampered status", encodeCursor(taskCursor{"status", "asc", "deleted", 3}), "status", "asc", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeCursor(tt.cursor, tt.sort, tt.order)
+			if tt.ok {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidTaskFilter) {
+				t.Errorf("got %v, want ErrInvalidTaskFilter", err)
+			}
+		})
+	}
+}
+
+func TestTaskFilterNormalize(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter TaskFilter
+		ok     bool
+	}{
+		{"empty", TaskFilter{}, true},
+		{"all valid", TaskFilter{Status: "in_progress", Priority: "urgent", Sort: "title", Order: "asc"}, true},
+		{"unknown status", TaskFilter{Status: "completed"}, false},
+		{"unknown priority", TaskFilter{Priority: "critical"}, false},
+		{"unknown sort", TaskFilter{Sort: "id; DROP TABLE tasks"}, false},
+		{"unknown order", TaskFilter{Order: "sideways"}, false},
+		{"bad cursor", TaskFilter{Cursor: "nope"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.filter.normalize()
+			if tt.ok {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			var validation ValidationError
+			if !errors.Is(err, ErrInvalidTaskFilter) || !errors.As(err, &validation) {
+				t.Errorf("got %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestTaskFilterDefaults(t *testing.T) {
+	tests := []struct {
+		limit, want int
+	}{
+		{0, DefaultTaskLimit},
+		{-5, DefaultTaskLimit},
+		{7, 7},
+		{MaxTaskLimit + 1, MaxTaskLimit},
+	}
+	for _, tt := range tests {
+		f := TaskFilter{Limit: tt.limit}
+		if _, err := f.normalize(); err != nil {
+			t.Fatalf("normalize: %v", err)
+		}
+		if f.Limit != tt.want {
+			t.Errorf("limit %d: got %d, want %d", tt.limit, f.Limit, tt.want)
+		}
+		if f.Sort != "created_at" || f.Order != "desc" {
+			t.Errorf("got sort %q order %q, want created_at desc", f.Sort, f.Order)
+		}
+	}
+}
+
+func TestListTasksRejectsInvalidFilter(t *testing.T) {
+	// Validation happens before any query, so no database is needed.
+	_, err := ListTasks(1, TaskFilter{Status: "bogus"})
+	if !errors.Is(err, ErrInvalidTaskFilter) {
+		t.Errorf("got %v, want ErrInvalidTaskFilter", err)
+	}
+}
+
+func TestBooleanSearch(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"", ""},
+		{"groceries", "+groceries*"},
+		{"  buy   milk ", "+buy* +milk*"},
+		{"follow-up", "+follow*"},
+		{"re-index database", "+index* +database*"},
+		{"foo-bar", "+foo* +bar*"},
+		{`+must -not "phrase" (group) ~less <lt >gt @2 star*`, "+must* +not* +phrase* +group* +less* +star*"},
+		{"a to do", ""},
+		{"go to the gym", "+the* +gym*"},
+		{"snake_case", "+snake_case*"},
+		{"café déjà", "+café* +déjà*"},
+	}
+	for _, tt := range tests {
+		if got := booleanSearch(tt.text); got != tt.want {
+			t.Errorf("booleanSearch(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	if got, want := likePattern(`50%_off\`), `%50\%\_off\\%`; got != want {
+		t.Errorf("likePattern = %q, want %q", got, want)
+	}
+}
@@ -11,18 +11,135 @@ import (
 	"github.com/falasefemi2/ask-tracker-api/models"
 )
 
-func CreateTask(userID int, title, description string) (*models.Task, error) {
+var (
+	ErrTaskNotFound            = errors.New("task not found")
+	ErrInvalidTransition       = models.ErrInvalidTransition
+	ErrTaskArchived            = errors.New("archived tasks cannot be edited; move the task back to todo first")
+	ErrInvalidStatus     error = ValidationError("status must be one of todo, in_progress, done or archived")
+	ErrInvalidPriority   error = ValidationError("priority must be one of low, medium, high or urgent")
+	ErrEmptyTitle        error = ValidationError("title cannot be empty")
+	ErrNothingToUpdate   error = ValidationError("title, description, status, priority or due date must be provided")
+)
+
+const taskColumns = "id, user_id, title, description, status, priority, due_at, time_zone, completed_at, created_at, updated_at"
+
+type NewTask struct {
+	Title       string
+	Description string
+	Priority    string
+	DueAt       *time.Time
+	TimeZone    string
+}
+
+// TaskUpdate holds the fields to change on a task; nil fields are left as
+// they are. ClearDueAt removes the due date.
+type TaskUpdate struct {
+	Title       *string
+	Description *string
+	Status      *string
+	Priority    *string
+	DueAt       *time.Time
+	ClearDueAt  bool
+	TimeZone    *string
+}
+
+// editsFields reports whether the update changes anything besides status.
+func (u TaskUpdate) editsFields() bool {
+	return u.Title != nil || u.Description != nil || u.Priority != nil ||
+		u.DueAt != nil || u.ClearDueAt || u.TimeZone != nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTask(row rowScanner) (*models.Task, error) {
+	var task models.Task
+	var dueAtStr sql.NullString
+	var completedAtStr sql.NullString
+	var createdAtStr string
+	var updatedAtStr string
+
+	err := row.Scan(
+		&task.ID,
+		&task.UserID,
+		&task.Title,
+		&task.Description,
+		&task.Status,
+		&task.Priority,
+		&dueAtStr,
+		&task.TimeZone,
+		&completedAtStr,
+		&createdAtStr,
+		&updatedAtStr,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if dueAtStr.Valid {
+		dueAt, err := time.Parse("2006-01-02 15:04:05", dueAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+		if loc, err := time.LoadLocation(task.TimeZone); err == nil {
+			dueAt = dueAt.In(loc)
+		}
+		task.DueAt = &dueAt
+	}
+	if completedAtStr.Valid {
+		completedAt, err := time.Parse("2006-01-02 15:04:05", completedAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+		task.CompletedAt = &completedAt
+	}
+
+	task.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	task.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
+	if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func CreateTask(userID int, input NewTask) (*models.Task, error) {
+	if input.Title == "" {
+		return nil, ErrEmptyTitle
+	}
+	if input.Priority == "" {
+		input.Priority = models.PriorityMedium
+	}
+	if !models.ValidPriority(input.Priority) {
+		return nil, ErrInvalidPriority
+	}
+	if input.TimeZone == "" {
+		input.TimeZone = "UTC"
+	}
 	now := time.Now()
 	task := &models.Task{
 		UserID:      userID,
-		Title:       title,
-		Description: description,
+		Title:       input.Title,
+		Description: input.Description,
+		Status:      models.StatusTodo,
+		Priority:    input.Priority,
+		DueAt:       input.DueAt,
+		TimeZone:    input.TimeZone,
 		CreatedAt:   now,
 		UpdatedAt:   now,
 	}
 
-	query := "INSERT INTO tasks (user_id, title, description, created_at, updated_at) VALUES (?, ?, ?, ?, ?)"
-	res, err := DB.Exec(query, userID, title, description, now, now)
+	query := `
+		INSERT INTO tasks (user_id, title, description, status, priority, due_at, time_zone, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := DB.Exec(query,
+		userID, task.Title, task.Description, task.Status, task.Priority,
+		task.DueAt, task.TimeZone, now, now,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -35,25 +152,91 @@ func CreateTask(userID int, title, description string) (*models.Task, error) {
 	return task, nil
 }
 
-func UpdateTask(id, userID int, title, description, status string) (*models.Task, error) {
-	if title == "" && description == "" && status == "" {
-		return nil, errors.New("title, description, or status must be provided")
+func GetTask(id, userID int) (*models.Task, error) {
+	task, err := scanTask(DB.QueryRow(`
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE id = ? AND user_id = ?
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// UpdateTask applies an update, moving the task's status with
+// Task.MoveTo so only allowed transitions happen and completed_at follows.
+func UpdateTask(id, userID int, update TaskUpdate) (*models.Task, error) {
+	if update.Status == nil && !update.editsFields() {
+		return nil, ErrNothingToUpdate
+	}
+	if update.Title != nil && *update.Title == "" {
+		return nil, ErrEmptyTitle
+	}
+	if update.Status != nil && !models.ValidStatus(*update.Status) {
+		return nil, ErrInvalidStatus
+	}
+	if update.Priority != nil && !models.ValidPriority(*update.Priority) {
+		return nil, ErrInvalidPriority
+	}
+
+	tx, err := DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	task, err := scanTask(tx.QueryRow(`
+		SELECT `+taskColumns+`
+		FROM tasks
+		WHERE id = ? AND user_id = ?
+		FOR UPDATE
+	`, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, ErrTaskNotFound
+	}
+	if err != nil {
+		return nil, err
 	}
+
+	now := time.Now()
 	setClauses := []string{}
-	args := []interface{}{}
-	if title != "" {
+	args := []any{}
+	if update.Status != nil && *update.Status != task.Status {
+		if err := task.MoveTo(*update.Status, now); err != nil {
+			return nil, err
+		}
+		setClauses = append(setClauses, "status = ?", "completed_at = ?")
+		args = append(args, task.Status, task.CompletedAt)
+	} else if task.Status == models.StatusArchived && update.editsFields() {
+		return nil, ErrTaskArchived
+	}
+	if update.Title != nil {
 		setClauses = append(setClauses, "title = ?")
-		args = append(args, title)
+		args = append(args, *update.Title)
 	}
-	if description != "" {
+	if update.Description != nil {
 		setClauses = append(setClauses, "description = ?")
-		args = append(args, description)
+		args = append(args, *update.Description)
 	}
-	if status != "" {
-		setClauses = append(setClauses, "status = ?")
-		args = append(args, status)
+	if update.Priority != nil {
+		setClauses = append(setClauses, "priority = ?")
+		args = append(args, *update.Priority)
+	}
+	// A new due date gets a new reminder.
+	if update.DueAt != nil {
+		setClauses = append(setClauses, "due_at = ?", "reminded_at = NULL")
+		args = append(args, *update.DueAt)
+	} else if update.ClearDueAt {
+		setClauses = append(setClauses, "due_at = NULL", "reminded_at = NULL")
+	}
+	if update.TimeZone != nil {
+		setClauses = append(setClauses, "time_zone = ?")
+		args = append(args, *update.TimeZone)
 	}
-	now := time.Now()
 	setClauses = append(setClauses, "updated_at = ?")
 	args = append(args, now)
 	query := `
@@ -62,51 +245,14 @@ func UpdateTask(id, userID int, title, description, status string) (*models.Task
 		WHERE id = ? AND user_id = ?
 	`
 	args = append(args, id, userID)
-	result, err := DB.Exec(query, args...)
-	if err != nil {
+	if _, err := tx.Exec(query, args...); err != nil {
 		log.Printf("Database error: %v", err)
 		return nil, err
 	}
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	if rowsAffected == 0 {
-		return nil, errors.New("task not found or not authorized")
-	}
-	var task models.Task
-	var createdAtStr string
-	var updatedAtStr string
-
-	err = DB.QueryRow(`
-		SELECT id, user_id, title, description, status, created_at, updated_at
-		FROM tasks
-		WHERE id = ? AND user_id = ?
-	`, id, userID).Scan(
-		&task.ID,
-		&task.UserID,
-		&task.Title,
-		&task.Description,
-		&task.Status,
-		&createdAtStr,
-		&updatedAtStr,
-	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("task not found after update")
-		}
-		return nil, err
-	}
-
-	task.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
-	if err != nil {
-		return nil, err
-	}
-	task.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
-	if err != nil {
-		return nil, err
-	}
-	return &task, nil
+	return GetTask(id, userID)
 }
 
 func DeleteTask(id, userID int) error {
@@ -123,58 +269,8 @@ where id = ? AND user_id = ?
 	if err != nil {
 		return fmt.Errorf("could not get rows affected: %v", err)
 	}
-
-	fmt.Printf("%d row(s) deleted\n", rowsAffected)
-	return nil
-}
-
-func GetUserTasks(userID int) ([]models.Task, error) {
-	query := `
-		SELECT id, user_id, title, description, status, created_at, updated_at
-		FROM tasks
-		WHERE user_id = ?
-		ORDER BY created_at DESC
-	`
-	rows, err := DB.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var tasks []models.Task
-	for rows.Next() {
-		var task models.Task
-		var createdAtStr string
-		var updatedAtStr string
-
-		err := rows.Scan(
-			&task.ID,
-			&task.UserID,
-			&task.Title,
-			&task.Description,
-			&task.Status,
-			&createdAtStr,
-			&updatedAtStr,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		task.CreatedAt, err = time.Parse("2006-01-02 15:04:05", createdAtStr)
-		if err != nil {
-			return nil, err
-		}
-		task.UpdatedAt, err = time.Parse("2006-01-02 15:04:05", updatedAtStr)
-		if err != nil {
-			return nil, err
-		}
-
-		tasks = append(tasks, task)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
+	if rowsAffected == 0 {
+		return ErrTaskNotFound
 	}
-
-	return tasks, nil
+	return nil
 }
@@ -3,14 +3,40 @@ package db
 import (
 	"database/sql"
 	"errors"
+	"net/mail"
 
 	"github.com/falasefemi2/ask-tracker-api/auth"
 	"github.com/falasefemi2/ask-tracker-api/models"
+	"github.com/go-sql-driver/mysql"
 )
 
+var (
+	// ErrUserNotFound is auth's sentinel, so JWTMiddleware can tell a
+	// deleted user from a failed lookup.
+	ErrUserNotFound          = auth.ErrUserNotFound
+	ErrInvalidEmail    error = ValidationError("email must be a valid address like name@example.com")
+	ErrEmailTaken            = errors.New("email is already in use")
+	ErrInvalidPassword       = errors.New("invalid password")
+)
+
+const mysqlDuplicateEntry = 1062
+
+func isDuplicateEntry(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+func validEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
 func CreateUser(email, password string) (*models.User, error) {
+	if !validEmail(email) {
+		return nil, ErrInvalidEmail
+	}
 	if err := auth.ValidatePassword(password); err != nil {
-		return nil, err
+		return nil, ValidationError(err.Error())
 	}
 	hash, err := auth.HashPassword(password)
 	if err != nil {
@@ -18,7 +44,10 @@ func CreateUser(email, password string) (*models.User, error) {
 	}
 	result, err := DB.Exec("INSERT INTO users (email, password_hash) VALUES (?, ?)", email, hash)
 	if err != nil {
-		return nil, errors.New("email already exists or database error")
+		if isDuplicateEntry(err) {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
@@ -37,7 +66,7 @@ func GetUserByEmail(email string) (*models.User, error) {
 		Scan(&user.ID, &user.Email, &user.PasswordHash)
 
 	if err == sql.ErrNoRows {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, err
@@ -46,6 +75,44 @@ func GetUserByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
+func GetUserByID(id int) (*models.User, error) {
+	user := &models.User{}
+	err := DB.QueryRow("SELECT id, email, password_hash FROM users WHERE id = ?", id).
+		Scan(&user.ID, &user.Email, &user.PasswordHash)
+
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func UpdateUserEmail(id int, email string) error {
+	if !validEmail(email) {
+		return ErrInvalidEmail
+	}
+	result, err := DB.Exec("UPDATE users SET email = ? WHERE id = ?", email, id)
+	if isDuplicateEntry(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		// MySQL also reports zero rows when the email is unchanged.
+		_, err := GetUserByID(id)
+		return err
+	}
+	return nil
+}
+
 func VerifyUser(email, password string) (*models.User, error) {
 	user, err := GetUserByEmail(email)
 	if err != nil {
@@ -53,7 +120,7 @@ func VerifyUser(email, password string) (*models.User, error) {
 	}
 
 	if err := auth.VerifyPassword(password, user.PasswordHash); err != nil {
-		return nil, errors.New("invalid password")
+		return nil, ErrInvalidPassword
 	}
 
 	return user, nil
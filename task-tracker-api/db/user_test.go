@@ -0,0 +1,53 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+func TestValidEmail(t *testing.T) {
+	tests := []struct {
+		email string
+		want  bool
+	}{
+		{"ada@example.com", true},
+		{"ada.lovelace+tasks@example.co.uk", true},
+		{"", false},
+		{"ada", false},
+		{"ada@", false},
+		{"Ada <ada@example.com>", false},
+		{" ada@example.com", false},
+	}
+	for _, tt := range tests {
+		if got := validEmail(tt.email); got != tt.want {
+			t.Errorf("validEmail(%q) = %v, want %v", tt.email, got, tt.want)
+		}
+	}
+}
+
+func TestCreateUserRejectsInvalidEmail(t *testing.T) {
+	// The address is checked before the database is touched.
+	_, err := CreateUser("not-an-email", "correct-horse")
+	if !errors.Is(err, ErrInvalidEmail) {
+		t.Errorf("CreateUser error = %v, want %v", err, ErrInvalidEmail)
+	}
+}
+
+func TestIsDuplicateEntry(t *testing.T) {
+	dup := &mysql.MySQLError{Number: mysqlDuplicateEntry, Message: "Duplicate entry 'ada@example.com' for key 'email'"}
+	if !isDuplicateEntry(dup) {
+		t.Error("duplicate entry not recognised")
+	}
+	if !isDuplicateEntry(fmt.Errorf("updating email: %w", dup)) {
+		t.Error("wrapped duplicate entry not recognised")
+	}
+	if isDuplicateEntry(&mysql.MySQLError{Number: 1045, Message: "Access denied"}) {
+		t.Error("access denied taken for a duplicate entry")
+	}
+	if isDuplicateEntry(errors.New("connection refused")) {
+		t.Error("plain error taken for a duplicate entry")
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/falasefemi2/ask-tracker-api/auth"
@@ -18,19 +19,20 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type ChangeEmailRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
 func SignupHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	var req SignupRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 	user, err := db.CreateUser(req.Email, req.Password)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDBError(w, err, "Failed to create user")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -42,24 +44,24 @@ func SignupHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
 	user, err := db.VerifyUser(req.Email, req.Password)
+	if errors.Is(err, db.ErrUserNotFound) || errors.Is(err, db.ErrInvalidPassword) {
+		writeError(w, http.StatusUnauthorized, "Invalid email or password")
+		return
+	}
 	if err != nil {
-		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		writeDBError(w, err, "Failed to log in")
 		return
 	}
 
-	token, err := auth.GenerateJWT(user.Email)
+	token, err := auth.GenerateJWT(user.ID)
 	if err != nil {
-		http.Error(w, "Failed to generate token", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Failed to generate token")
 		return
 	}
 
@@ -70,3 +72,31 @@ func LoginHandler(w http.ResponseWriter, r *http.Request) {
 		"token": token,
 	})
 }
+
+// ChangeEmailHandler asks for the current password so a stolen token alone
+// cannot take over the account.
+func ChangeEmailHandler(w http.ResponseWriter, r *http.Request) {
+	var req ChangeEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request")
+		return
+	}
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
+		return
+	}
+	if err := auth.VerifyPassword(req.Password, user.PasswordHash); err != nil {
+		writeError(w, http.StatusUnauthorized, "Invalid password")
+		return
+	}
+	if err := db.UpdateUserEmail(user.ID, req.Email); err != nil {
+		writeDBError(w, err, "Failed to change email")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":    user.ID,
+		"email": req.Email,
+	})
+}
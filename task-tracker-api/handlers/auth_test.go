@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/falasefemi2/ask-tracker-api/auth"
+	"github.com/falasefemi2/ask-tracker-api/db/dbtest"
+	"github.com/falasefemi2/ask-tracker-api/models"
+	"github.com/go-sql-driver/mysql"
+)
+
+// serveAs runs h behind JWTMiddleware with a token for user.
+func serveAs(t *testing.T, user *models.User, h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	token, err := auth.GenerateJWT(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	lookup := func(int) (*models.User, error) { return user, nil }
+
+	rec := httptest.NewRecorder()
+	auth.JWTMiddleware(lookup)(h).ServeHTTP(rec, req)
+	return rec
+}
+
+// errorBody decodes a {"error": ...} response.
+func errorBody(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("decoding error body: %v", err)
+	}
+	return body.Error
+}
+
+func TestChangeEmail(t *testing.T) {
+	hash, err := auth.HashPassword("correct-horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ada := &models.User{ID: 42, Email: "ada@example.com", PasswordHash: hash}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		// update answers the UPDATE of the address, when it is reached.
+		update    func() (dbtest.Result, error)
+		want      int
+		wantError string
+	}{
+		{
+			name: "changed", email: "lovelace@example.com", password: "correct-horse",
+			update: func() (dbtest.Result, error) { return dbtest.Result{RowsAffected: 1}, nil },
+			want:   http.StatusOK,
+		},
+		{
+			name: "wrong password", email: "lovelace@example.com", password: "wrong-horse",
+			want: http.StatusUnauthorized, wantError: "Invalid password",
+		},
+		{
+			name: "invalid email", email: "lovelace", password: "correct-horse",
+			want: http.StatusBadRequest, wantError: "email must be a valid address like name@example.com",
+		},
+		{
+			name: "email taken", email: "grace@example.com", password: "correct-horse",
+			update: func() (dbtest.Result, error) {
+				return dbtest.Result{}, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+			},
+			want: http.StatusConflict, wantError: "email is already in use",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var updated []driver.Value
+			dbtest.Open(t, func(query string, args []driver.Value) (dbtest.Result, error) {
+				if tt.update == nil || !strings.HasPrefix(query, "UPDATE users SET email") {
+					t.Fatalf("unexpected query %q", query)
+				}
+				updated = args
+				return tt.update()
+			})
+
+			body := `{"email": "` + tt.email + `", "password": "` + tt.password + `"}`
+			req := httptest.NewRequest(http.MethodPut, "/me/email", strings.NewReader(body))
+			rec := serveAs(t, ada, http.HandlerFunc(ChangeEmailHandler), req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.wantError != "" {
+				if got := errorBody(t, rec); got != tt.wantError {
+					t.Errorf("error = %q, want %q", got, tt.wantError)
+				}
+				return
+			}
+			if len(updated) != 2 || updated[0] != tt.email || updated[1] != int64(ada.ID) {
+				t.Errorf("UPDATE args = %v, want [%s %d]", updated, tt.email, ada.ID)
+			}
+			var got models.User
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.ID != ada.ID || got.Email != tt.email {
+				t.Errorf("response = %+v, want id %d and email %s", got, ada.ID, tt.email)
+			}
+		})
+	}
+}
@@ -1,18 +1,68 @@
 package handlers
 
-import "net/http"
+import (
+	"errors"
+	"log"
+	"net/http"
 
-func TasksHandler(w http.ResponseWriter, r *http.Request) {
-	switch r.Method {
-	case http.MethodPost:
-		CreateTaskHandler(w, r)
-	case http.MethodPut:
-		UpdateTaskHandler(w, r)
-	case http.MethodDelete:
-		DeleteTaskHandler(w, r)
-	case http.MethodGet:
-		GetUserTasksHandler(w, r)
+	"github.com/falasefemi2/ask-tracker-api/db"
+	"github.com/falasefemi2/ask-tracker-api/httpjson"
+)
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	httpjson.Error(w, status, message)
+}
+
+// writeDBError answers an error from the db package. The caller's mistakes
+// get a 4xx carrying the error's message; anything else is logged and
+// reported as a 500 with message.
+func writeDBError(w http.ResponseWriter, err error, message string) {
+	var validation db.ValidationError
+	switch {
+	case errors.As(err, &validation):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, db.ErrTaskNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, db.ErrUserNotFound):
+		writeError(w, http.StatusUnauthorized, err.Error())
+	case errors.Is(err, db.ErrInvalidTransition),
+		errors.Is(err, db.ErrTaskArchived),
+		errors.Is(err, db.ErrEmailTaken):
+		writeError(w, http.StatusConflict, err.Error())
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		log.Printf("%s: %v", message, err)
+		writeError(w, http.StatusInternalServerError, message)
 	}
 }
+
+// JSONErrors answers requests the mux has no route for with a JSON body
+// instead of the mux's plain-text 404 and 405 responses.
+func JSONErrors(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		rec := &statusRecorder{header: http.Header{}, status: http.StatusNotFound}
+		mux.ServeHTTP(rec, r)
+		if allow := rec.header.Get("Allow"); allow != "" {
+			w.Header().Set("Allow", allow)
+		}
+		if rec.status == http.StatusMethodNotAllowed {
+			writeError(w, rec.status, "method not allowed")
+			return
+		}
+		writeError(w, http.StatusNotFound, "not found")
+	})
+}
+
+// statusRecorder captures the status and headers of the mux's own error
+// response so they can be rewritten as JSON.
+type statusRecorder struct {
+	header http.Header
+	status int
+}
+
+func (s *statusRecorder) Header() http.Header         { return s.header }
+func (s *statusRecorder) Write(b []byte) (int, error) { return len(b), nil }
+func (s *statusRecorder) WriteHeader(status int)      { s.status = status }
@@ -2,48 +2,114 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/falasefemi2/ask-tracker-api/auth"
 	"github.com/falasefemi2/ask-tracker-api/db"
+	"github.com/falasefemi2/ask-tracker-api/models"
 )
 
+// Due dates are RFC 3339 timestamps, or local times such as
+// "2025-03-01T17:00" read in time_zone, an IANA zone name that defaults to
+// UTC.
 type CreateTaskRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
+	Priority    string `json:"priority"`
+	DueAt       string `json:"due_at"`
+	TimeZone    string `json:"time_zone"`
 }
 
-type UpdateTaskRequest struct {
+// ReplaceTaskRequest is the body of a PUT, which sets every field.
+type ReplaceTaskRequest struct {
 	Title       string `json:"title"`
 	Description string `json:"description"`
 	Status      string `json:"status"`
+	Priority    string `json:"priority"`
+	DueAt       string `json:"due_at"`
+	TimeZone    string `json:"time_zone"`
 }
 
-func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
+// UpdateTaskRequest is the body of a PATCH; omitted fields are unchanged
+// and an empty due_at removes the due date.
+type UpdateTaskRequest struct {
+	Title       *string `json:"title"`
+	Description *string `json:"description"`
+	Status      *string `json:"status"`
+	Priority    *string `json:"priority"`
+	DueAt       *string `json:"due_at"`
+	TimeZone    *string `json:"time_zone"`
+}
+
+func loadZone(name string) (*time.Location, error) {
+	if name == "" {
+		name = "UTC"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.New("unknown time zone " + name)
+	}
+	return loc, nil
+}
+
+func parseDue(value string, loc *time.Location) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
 	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, errors.New("due_at must be an RFC 3339 timestamp or a local time like 2006-01-02T15:04")
+}
+
+func taskIDFromPath(w http.ResponseWriter, r *http.Request) (int, bool) {
+	taskID, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Task ID must be a number")
+		return 0, false
+	}
+	return taskID, true
+}
+
+func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var req CreateTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request")
 		return
 	}
-	claims, ok := r.Context().Value("user").(*auth.Claims)
+	user, ok := auth.UserFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
 		return
 	}
-	user, err := db.GetUserByEmail(claims.Email)
+	loc, err := loadZone(req.TimeZone)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	task, err := db.CreateTask(user.ID, req.Title, req.Description)
+	dueAt, err := parseDue(req.DueAt, loc)
 	if err != nil {
-		http.Error(w, "Failed to create task", http.StatusInternalServerError)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	task, err := db.CreateTask(user.ID, db.NewTask{
+		Title:       req.Title,
+		Description: req.Description,
+		Priority:    req.Priority,
+		DueAt:       dueAt,
+		TimeZone:    loc.String(),
+	})
+	if err != nil {
+		writeDBError(w, err, "Failed to create task")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -51,79 +117,148 @@ func CreateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(task)
 }
 
-func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPut {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func GetTaskHandler(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
 		return
 	}
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 3 {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
 		return
 	}
-	taskID, err := strconv.Atoi(parts[2])
+	task, err := db.GetTask(taskID, user.ID)
 	if err != nil {
-		http.Error(w, "Task ID must be a number", http.StatusBadRequest)
+		writeDBError(w, err, "Failed to load task")
 		return
 	}
-	var req UpdateTaskRequest
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+func ReplaceTaskHandler(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req ReplaceTaskRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	claims, ok := r.Context().Value("user").(*auth.Claims)
-	if !ok {
-		http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
+	if req.Status == "" {
+		writeError(w, http.StatusBadRequest, "status is required")
 		return
 	}
-	user, err := db.GetUserByEmail(claims.Email)
+	if req.Priority == "" {
+		req.Priority = models.PriorityMedium
+	}
+	loc, err := loadZone(req.TimeZone)
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	task, err := db.UpdateTask(
-		taskID,
-		user.ID,
-		req.Title,
-		req.Description,
-		req.Status,
-	)
+	dueAt, err := parseDue(req.DueAt, loc)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(task)
+	zone := loc.String()
+	updateTask(w, r, taskID, db.TaskUpdate{
+		Title:       &req.Title,
+		Description: &req.Description,
+		Status:      &req.Status,
+		Priority:    &req.Priority,
+		DueAt:       dueAt,
+		ClearDueAt:  dueAt == nil,
+		TimeZone:    &zone,
+	})
 }
 
-func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodDelete {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+func UpdateTaskHandler(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := taskIDFromPath(w, r)
+	if !ok {
 		return
 	}
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 3 {
-		http.Error(w, "Invalid task ID", http.StatusBadRequest)
+	var req UpdateTaskRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
-	taskID, err := strconv.Atoi(parts[2])
+	update := db.TaskUpdate{
+		Title:       req.Title,
+		Description: req.Description,
+		Status:      req.Status,
+		Priority:    req.Priority,
+	}
+	if req.TimeZone != nil {
+		loc, err := loadZone(*req.TimeZone)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		zone := loc.String()
+		update.TimeZone = &zone
+	}
+	if req.DueAt != nil {
+		// A local due time without a new zone is read in the task's zone.
+		zone := update.TimeZone
+		if zone == nil {
+			user, ok := auth.UserFromContext(r.Context())
+			if !ok {
+				writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
+				return
+			}
+			task, err := db.GetTask(taskID, user.ID)
+			if err != nil {
+				writeDBError(w, err, "Failed to load task")
+				return
+			}
+			zone = &task.TimeZone
+		}
+		loc, err := loadZone(*zone)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		update.DueAt, err = parseDue(*req.DueAt, loc)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		update.ClearDueAt = update.DueAt == nil
+	}
+	updateTask(w, r, taskID, update)
+}
+
+func updateTask(w http.ResponseWriter, r *http.Request, taskID int, update db.TaskUpdate) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
+		return
+	}
+	task, err := db.UpdateTask(taskID, user.ID, update)
 	if err != nil {
-		http.Error(w, "Task ID must be a number", http.StatusBadRequest)
+		writeDBError(w, err, "Failed to update task")
 		return
 	}
-	claims, ok := r.Context().Value("user").(*auth.Claims)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(task)
+}
+
+func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	taskID, ok := taskIDFromPath(w, r)
 	if !ok {
-		http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
 		return
 	}
-	user, err := db.GetUserByEmail(claims.Email)
-	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
 		return
 	}
-	err = db.DeleteTask(taskID, user.ID)
+	err := db.DeleteTask(taskID, user.ID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeDBError(w, err, "Failed to delete task")
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -131,26 +266,129 @@ func DeleteTaskHandler(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"message": "task deleted successfully"})
 }
 
+// parseTime accepts an RFC 3339 timestamp or a plain date, read as
+// midnight UTC.
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func parseTaskFilter(r *http.Request) (db.TaskFilter, error) {
+	q := r.URL.Query()
+	filter := db.TaskFilter{
+		Status:   q.Get("status"),
+		Priority: q.Get("priority"),
+		Search:   q.Get("q"),
+		Sort:     q.Get("sort"),
+		Order:    strings.ToLower(q.Get("order")),
+		Cursor:   q.Get("cursor"),
+	}
+	if overdue := q.Get("overdue"); overdue != "" {
+		v, err := strconv.ParseBool(overdue)
+		if err != nil {
+			return filter, errors.New("overdue must be true or false")
+		}
+		filter.Overdue = v
+	}
+	if limit := q.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return filter, errors.New("limit must be a positive number")
+		}
+		filter.Limit = n
+	}
+	dates := []struct {
+		param string
+		dest  *time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+		{"updated_after", &filter.UpdatedAfter},
+		{"updated_before", &filter.UpdatedBefore},
+	}
+	for _, d := range dates {
+		value := q.Get(d.param)
+		if value == "" {
+			continue
+		}
+		t, err := parseTime(value)
+		if err != nil {
+			return filter, errors.New(d.param + " must be a date (2006-01-02) or RFC 3339 timestamp")
+		}
+		*d.dest = t
+	}
+	return filter, nil
+}
+
+// GetUserTasksHandler lists the user's tasks a page at a time. Query
+// parameters: status, priority, overdue, q (search), created_after,
+// created_before, updated_after, updated_before, sort, order, cursor and
+// limit.
 func GetUserTasksHandler(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
+		return
+	}
+	filter, err := parseTaskFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	claims, ok := r.Context().Value("user").(*auth.Claims)
+	page, err := db.ListTasks(user.ID, filter)
+	if err != nil {
+		writeDBError(w, err, "Failed to list tasks")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+// dueWindows returns the bounds of the day and of the Monday-to-Sunday
+// week containing now in loc. Days are counted on the calendar, so a day
+// across a daylight saving change is 23 or 25 hours long.
+func dueWindows(now time.Time, loc *time.Location) (today, tomorrow, weekStart, weekEnd time.Time) {
+	now = now.In(loc)
+	today = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	tomorrow = today.AddDate(0, 0, 1)
+	daysSinceMonday := (int(today.Weekday()) + 6) % 7
+	weekStart = today.AddDate(0, 0, -daysSinceMonday)
+	weekEnd = weekStart.AddDate(0, 0, 7)
+	return today, tomorrow, weekStart, weekEnd
+}
+
+// DueTasksHandler lists open tasks due today and this week (Monday to
+// Sunday), in the time zone given by the tz query parameter.
+func DueTasksHandler(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
 	if !ok {
-		http.Error(w, "Could not retrieve user from context", http.StatusInternalServerError)
+		writeError(w, http.StatusInternalServerError, "Could not retrieve user from context")
 		return
 	}
-	user, err := db.GetUserByEmail(claims.Email)
+	loc, err := loadZone(r.URL.Query().Get("tz"))
 	if err != nil {
-		http.Error(w, "User not found", http.StatusNotFound)
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	tasks, err := db.GetUserTasks(user.ID)
+	today, tomorrow, weekStart, weekEnd := dueWindows(time.Now(), loc)
+
+	week, err := db.DueTasks(user.ID, weekStart, weekEnd)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		writeDBError(w, err, "Failed to list due tasks")
 		return
 	}
+	dueToday := []models.Task{}
+	for _, task := range week {
+		if !task.DueAt.Before(today) && task.DueAt.Before(tomorrow) {
+			dueToday = append(dueToday, task)
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(tasks)
+	json.NewEncoder(w).Encode(map[string]any{
+		"time_zone": loc.String(),
+		"today":     dueToday,
+		"this_week": week,
+	})
 }
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+	// The zone tests must not depend on the machine having zoneinfo.
+	_ "time/tzdata"
+
+	"github.com/falasefemi2/ask-tracker-api/db"
+)
+
+func TestParseTaskFilter(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  db.TaskFilter
+		ok    bool
+	}{
+		{"empty", "", db.TaskFilter{}, true},
+		{
+			"all fields",
+			"status=done&priority=high&overdue=true&q=milk&sort=title&order=ASC&cursor=abc&limit=5",
+			db.TaskFilter{
+				Status: "done", Priority: "high", Overdue: true, Search: "milk",
+				Sort: "title", Order: "asc", Cursor: "abc", Limit: 5,
+			},
+			true,
+		},
+		{
+			"dates",
+			"created_after=2025-03-01&created_before=2025-03-02T10:00:00%2B01:00&updated_after=2025-01-01T00:00:00Z",
+			db.TaskFilter{
+				CreatedAfter:  time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+				CreatedBefore: time.Date(2025, 3, 2, 9, 0, 0, 0, time.UTC),
+				UpdatedAfter:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+			true,
+		},
+		{"zero limit", "limit=0", db.TaskFilter{}, false},
+		{"text limit", "limit=ten", db.TaskFilter{}, false},
+		{"bad overdue", "overdue=maybe", db.TaskFilter{}, false},
+		{"bad date", "updated_before=yesterday", db.TaskFilter{}, false},
+		{"impossible date", "created_after=2025-02-30", db.TaskFilter{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/tasks?"+tt.query, nil)
+			got, err := parseTaskFilter(r)
+			if !tt.ok {
+				if err == nil {
+					t.Errorf("expected an error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.CreatedAfter.Equal(tt.want.CreatedAfter) ||
+				!got.CreatedBefore.Equal(tt.want.CreatedBefore) ||
+				!got.UpdatedAfter.Equal(tt.want.UpdatedAfter) ||
+				!got.UpdatedBefore.Equal(tt.want.UpdatedBefore) {
+				t.Errorf("dates: got %+v, want %+v", got, tt.want)
+			}
+			got.CreatedAfter, got.CreatedBefore = tt.want.CreatedAfter, tt.want.CreatedBefore
+			got.UpdatedAfter, got.UpdatedBefore = tt.want.UpdatedAfter, tt.want.UpdatedBefore
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestLoadZone(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"", "UTC", true},
+		{"UTC", "UTC", true},
+		{"Europe/Lisbon", "Europe/Lisbon", true},
+		{"Mars/Olympus_Mons", "", false},
+		{"+01:00", "", false},
+	}
+	for _, tt := range tests {
+		loc, err := loadZone(tt.name)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("loadZone(%q): expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil || loc.String() != tt.want {
+			t.Errorf("loadZone(%q) = %v, %v; want %s", tt.name, loc, err, tt.want)
+		}
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	lagos := mustZone(t, "Africa/Lagos")
+	tests := []struct {
+		value string
+		want  time.Time
+		ok    bool
+	}{
+		{"2025-03-01T17:00:00Z", time.Date(2025, 3, 1, 17, 0, 0, 0, time.UTC), true},
+		{"2025-03-01T17:00:00+02:00", time.Date(2025, 3, 1, 15, 0, 0, 0, time.UTC), true},
+		// Local times are read in the task's zone; Lagos is UTC+1.
+		{"2025-03-01T17:00", time.Date(2025, 3, 1, 16, 0, 0, 0, time.UTC), true},
+		{"2025-03-01T17:00:30", time.Date(2025, 3, 1, 16, 0, 30, 0, time.UTC), true},
+		{"2025-03-01", time.Time{}, false},
+		{"tomorrow", time.Time{}, false},
+		{"2025-02-30T10:00", time.Time{}, false},
+	}
+	for _, tt := range tests {
+		got, err := parseDue(tt.value, lagos)
+		if !tt.ok {
+			if err == nil {
+				t.Errorf("parseDue(%q): expected an error, got %v", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseDue(%q): %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("parseDue(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	if got, err := parseDue("", lagos); got != nil || err != nil {
+		t.Errorf("parseDue(\"\") = %v, %v; want no due date", got, err)
+	}
+}
+
+func TestDueWindows(t *testing.T) {
+	tokyo := mustZone(t, "Asia/Tokyo")
+	newYork := mustZone(t, "America/New_York")
+	date := func(y int, m time.Month, d int, loc *time.Location) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		loc       *time.Location
+		today     time.Time
+		weekStart time.Time
+	}{
+		{
+			"midweek",
+			time.Date(2025, 3, 5, 15, 0, 0, 0, time.UTC), time.UTC,
+			date(2025, 3, 5, time.UTC), date(2025, 3, 3, time.UTC),
+		},
+		{
+			"sunday belongs to the week before",
+			time.Date(2025, 3, 9, 23, 59, 59, 0, time.UTC), time.UTC,
+			date(2025, 3, 9, time.UTC), date(2025, 3, 3, time.UTC),
+		},
+		{
+			"monday midnight starts a week",
+			time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), time.UTC,
+			date(2025, 3, 10, time.UTC), date(2025, 3, 10, time.UTC),
+		},
+		{
+			"sunday in UTC is monday in Tokyo",
+			time.Date(2025, 3, 9, 23, 30, 0, 0, time.UTC), tokyo,
+			date(2025, 3, 10, tokyo), date(2025, 3, 10, tokyo),
+		},
+		{
+			"week across a month and year end",
+			time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC), time.UTC,
+			date(2025, 1, 1, time.UTC), date(2024, 12, 30, time.UTC),
+		},
+		{
+			"daylight saving starts",
+			time.Date(2025, 3, 9, 12, 0, 0, 0, newYork), newYork,
+			date(2025, 3, 9, newYork), date(2025, 3, 3, newYork),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			today, tomorrow, weekStart, weekEnd := dueWindows(tt.now, tt.loc)
+			if !today.Equal(tt.today) {
+				t.Errorf("today = %v, want %v", today, tt.today)
+			}
+			if want := tt.today.AddDate(0, 0, 1); !tomorrow.Equal(want) {
+				t.Errorf("tomorrow = %v, want %v", tomorrow, want)
+			}
+			if !weekStart.Equal(tt.weekStart) {
+				t.Errorf("week start = %v, want %v", weekStart, tt.weekStart)
+			}
+			if weekStart.Weekday() != time.Monday {
+				t.Errorf("week starts on %v", weekStart.Weekday())
+			}
+			if want := tt.weekStart.AddDate(0, 0, 7); !weekEnd.Equal(want) {
+				t.Errorf("week end = %v, want %v", weekEnd, want)
+			}
+			if tt.now.Before(today) || !tt.now.Before(tomorrow) {
+				t.Errorf("now %v is outside today [%v, %v)", tt.now, today, tomorrow)
+			}
+			if tt.now.Before(weekStart) || !tt.now.Before(weekEnd) {
+				t.Errorf("now %v is outside the week [%v, %v)", tt.now, weekStart, weekEnd)
+			}
+		})
+	}
+
+	// The day daylight saving starts in New York is 23 hours long.
+	today, tomorrow, _, _ := dueWindows(time.Date(2025, 3, 9, 12, 0, 0, 0, newYork), newYork)
+	if got := tomorrow.Sub(today); got != 23*time.Hour {
+		t.Errorf("DST day lasts %v, want 23h", got)
+	}
+}
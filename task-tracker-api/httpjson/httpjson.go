@@ -0,0 +1,15 @@
+// Package httpjson writes the API's JSON error responses, so handlers and
+// middleware answer in the same shape.
+package httpjson
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Error writes {"error": message} with status.
+func Error(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
@@ -1,23 +1,104 @@
 package main
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/falasefemi2/ask-tracker-api/auth"
 	"github.com/falasefemi2/ask-tracker-api/db"
 	"github.com/falasefemi2/ask-tracker-api/handlers"
+	"github.com/falasefemi2/ask-tracker-api/reminders"
 )
 
+// durationEnv reads a positive duration such as 15m from the environment.
+func durationEnv(name string, fallback time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("%s must be a positive duration such as 15m", name)
+	}
+	return d
+}
+
+// reminderScheduler posts reminders to REMINDER_WEBHOOK_URL when it is set
+// and logs them otherwise. REMINDER_LEAD is how long before the due time
+// they go out, and REMINDER_GRACE how late one may still go out after the
+// server was down.
+func reminderScheduler() *reminders.Scheduler {
+	var notifier reminders.Notifier = reminders.LogNotifier{}
+	if url := os.Getenv("REMINDER_WEBHOOK_URL"); url != "" {
+		notifier = reminders.NewWebhookNotifier(url)
+	}
+	return &reminders.Scheduler{
+		Notifier: notifier,
+		Lead:     durationEnv("REMINDER_LEAD", 15*time.Minute),
+		Grace:    durationEnv("REMINDER_GRACE", time.Hour),
+		Interval: time.Minute,
+	}
+}
+
+// routes maps the API onto its handlers. Tasks and account changes need a
+// token for a user that lookup can still find.
+func routes(lookup auth.UserLookup) http.Handler {
+	requireUser := auth.JWTMiddleware(lookup)
+	protected := func(h http.HandlerFunc) http.Handler {
+		return requireUser(h)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /signup", handlers.SignupHandler)
+	mux.HandleFunc("POST /login", handlers.LoginHandler)
+	mux.Handle("PUT /me/email", protected(handlers.ChangeEmailHandler))
+
+	mux.Handle("GET /tasks", protected(handlers.GetUserTasksHandler))
+	mux.Handle("POST /tasks", protected(handlers.CreateTaskHandler))
+	mux.Handle("GET /tasks/due", protected(handlers.DueTasksHandler))
+	mux.Handle("GET /tasks/{id}", protected(handlers.GetTaskHandler))
+	mux.Handle("PUT /tasks/{id}", protected(handlers.ReplaceTaskHandler))
+	mux.Handle("PATCH /tasks/{id}", protected(handlers.UpdateTaskHandler))
+	mux.Handle("DELETE /tasks/{id}", protected(handlers.DeleteTaskHandler))
+	return handlers.JSONErrors(mux)
+}
+
 func main() {
-	db.Init()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	http.HandleFunc("/signup", handlers.SignupHandler)
-	http.HandleFunc("/login", handlers.LoginHandler)
+	db.Init()
+	schedulerDone := make(chan struct{})
+	go func() {
+		defer close(schedulerDone)
+		reminderScheduler().Run(ctx)
+	}()
 
-	http.Handle(
-		"/tasks/",
-		auth.JWTMiddleware(http.HandlerFunc(handlers.TasksHandler)),
-	)
+	server := &http.Server{Addr: ":8080", Handler: routes(db.GetUserByID)}
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
 
-	http.ListenAndServe(":8080", nil)
+	select {
+	case err := <-serverErr:
+		// Shutdown has not been called, so this is a real failure such as
+		// the port being taken.
+		stop()
+		<-schedulerDone
+		log.Fatalf("server: %v", err)
+	case <-ctx.Done():
+		log.Println("Shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("server shutdown: %v", err)
+		}
+	}
+	<-schedulerDone
 }
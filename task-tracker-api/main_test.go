@@ -0,0 +1,124 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/falasefemi2/ask-tracker-api/auth"
+	"github.com/falasefemi2/ask-tracker-api/db/dbtest"
+	"github.com/falasefemi2/ask-tracker-api/models"
+)
+
+var taskColumns = []string{"id", "user_id", "title", "description", "status", "priority", "due_at", "time_zone", "completed_at", "created_at", "updated_at"}
+
+// fakeTasks answers the task queries for a single task 7 owned by user 42.
+type fakeTasks struct {
+	t      *testing.T
+	status string
+}
+
+func (f *fakeTasks) answer(query string, args []driver.Value) (dbtest.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "SELECT "+strings.Join(taskColumns, ", ")+" FROM tasks WHERE id = ? AND user_id = ?"):
+		res := dbtest.Result{Columns: taskColumns}
+		if args[0] == int64(7) && args[1] == int64(42) {
+			res.Rows = [][]driver.Value{{
+				int64(7), int64(42), "Write report", "", f.status, "medium",
+				"2025-03-01 17:00:00", "UTC", nil, "2025-02-01 09:00:00", "2025-02-01 09:00:00",
+			}}
+		}
+		return res, nil
+	case strings.HasPrefix(query, "UPDATE tasks SET status = ?"):
+		f.status = args[0].(string)
+		return dbtest.Result{RowsAffected: 1}, nil
+	}
+	f.t.Fatalf("unexpected query %q", query)
+	return dbtest.Result{}, nil
+}
+
+func TestRoutes(t *testing.T) {
+	tasks := &fakeTasks{t: t, status: models.StatusTodo}
+	dbtest.Open(t, tasks.answer)
+
+	ada := &models.User{ID: 42, Email: "ada@example.com"}
+	handler := routes(func(id int) (*models.User, error) {
+		if id != ada.ID {
+			return nil, auth.ErrUserNotFound
+		}
+		return ada, nil
+	})
+	adaToken, err := auth.GenerateJWT(ada.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deletedToken, err := auth.GenerateJWT(99)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		body       string
+		want       int
+		wantAllow  string
+		wantError  string
+		wantStatus string
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/nope", want: http.StatusNotFound, wantError: "not found"},
+		{name: "wrong method", method: http.MethodDelete, path: "/tasks", token: adaToken, want: http.StatusMethodNotAllowed, wantAllow: "GET, HEAD, POST", wantError: "method not allowed"},
+		{name: "no token", method: http.MethodGet, path: "/tasks/7", want: http.StatusUnauthorized, wantError: "Authorization header required"},
+		{name: "deleted user", method: http.MethodGet, path: "/tasks/7", token: deletedToken, want: http.StatusUnauthorized, wantError: "User not found"},
+		{name: "get task", method: http.MethodGet, path: "/tasks/7", token: adaToken, want: http.StatusOK, wantStatus: models.StatusTodo},
+		{name: "get someone else's task", method: http.MethodGet, path: "/tasks/8", token: adaToken, want: http.StatusNotFound, wantError: "task not found"},
+		{name: "bad task id", method: http.MethodGet, path: "/tasks/seven", token: adaToken, want: http.StatusBadRequest},
+		{name: "patch status", method: http.MethodPatch, path: "/tasks/7", token: adaToken, body: `{"status": "in_progress"}`, want: http.StatusOK, wantStatus: models.StatusInProgress},
+		{name: "patch nothing", method: http.MethodPatch, path: "/tasks/7", token: adaToken, body: `{}`, want: http.StatusBadRequest},
+		{name: "patch invalid status", method: http.MethodPatch, path: "/tasks/7", token: adaToken, body: `{"status": "blocked"}`, want: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tasks.t = t
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, want application/json", ct)
+			}
+			if allow := rec.Header().Get("Allow"); allow != tt.wantAllow {
+				t.Errorf("Allow = %q, want %q", allow, tt.wantAllow)
+			}
+
+			var body struct {
+				Error  string `json:"error"`
+				ID     int    `json:"id"`
+				Status string `json:"status"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatalf("response is not JSON: %v", err)
+			}
+			if rec.Code >= 400 && body.Error == "" {
+				t.Error("error response has no error message")
+			}
+			if tt.wantError != "" && body.Error != tt.wantError {
+				t.Errorf("error = %q, want %q", body.Error, tt.wantError)
+			}
+			if tt.wantStatus != "" && (body.ID != 7 || body.Status != tt.wantStatus) {
+				t.Errorf("task = %d %q, want 7 %q", body.ID, body.Status, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -1,14 +1,95 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
+const (
+	StatusTodo       = "todo"
+	StatusInProgress = "in_progress"
+	StatusDone       = "done"
+	StatusArchived   = "archived"
+)
+
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+func ValidPriority(priority string) bool {
+	switch priority {
+	case PriorityLow, PriorityMedium, PriorityHigh, PriorityUrgent:
+		return true
+	}
+	return false
+}
+
+// statusTransitions lists where a task may move from each status. A done
+// task is reopened as in progress, and an archived one comes back as todo.
+var statusTransitions = map[string][]string{
+	StatusTodo:       {StatusInProgress, StatusDone, StatusArchived},
+	StatusInProgress: {StatusTodo, StatusDone, StatusArchived},
+	StatusDone:       {StatusInProgress, StatusArchived},
+	StatusArchived:   {StatusTodo},
+}
+
+func ValidStatus(status string) bool {
+	_, ok := statusTransitions[status]
+	return ok
+}
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// CanTransition reports whether a task may move from one status to
+// another. Keeping the same status is always allowed.
+func CanTransition(from, to string) bool {
+	if from == to {
+		return ValidStatus(to)
+	}
+	for _, next := range statusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// A Task's DueAt is stored in UTC and shown in TimeZone, the IANA zone the
+// due date was set in.
 type Task struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          int        `json:"id"`
+	UserID      int        `json:"user_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	DueAt       *time.Time `json:"due_at"`
+	TimeZone    string     `json:"time_zone"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
+// MoveTo changes the task's status if the transition is allowed. A task
+// gets a completion time when it becomes done and loses it when reopened;
+// archiving keeps it.
+func (t *Task) MoveTo(status string, now time.Time) error {
+	if status == t.Status {
+		return nil
+	}
+	if !CanTransition(t.Status, status) {
+		return fmt.Errorf("%w: a %s task cannot be moved to %s", ErrInvalidTransition, t.Status, status)
+	}
+	t.Status = status
+	switch status {
+	case StatusDone:
+		t.CompletedAt = &now
+	case StatusTodo, StatusInProgress:
+		t.CompletedAt = nil
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	// allowed[from][to]; every pair not listed is forbidden.
+	allowed := map[string]map[string]bool{
+		StatusTodo:       {StatusTodo: true, StatusInProgress: true, StatusDone: true, StatusArchived: true},
+		StatusInProgress: {StatusTodo: true, StatusInProgress: true, StatusDone: true, StatusArchived: true},
+		StatusDone:       {StatusInProgress: true, StatusDone: true, StatusArchived: true},
+		StatusArchived:   {StatusTodo: true, StatusArchived: true},
+	}
+	statuses := []string{StatusTodo, StatusInProgress, StatusDone, StatusArchived, "completed", ""}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			want := allowed[from][to]
+			if got := CanTransition(from, to); got != want {
+				t.Errorf("CanTransition(%q, %q) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestMoveTo(t *testing.T) {
+	earlier := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	now := time.Date(2025, 3, 5, 17, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		from, to      string
+		completedAt   *time.Time
+		wantErr       bool
+		wantCompleted *time.Time
+	}{
+		{StatusTodo, StatusInProgress, nil, false, nil},
+		{StatusTodo, StatusDone, nil, false, &now},
+		{StatusTodo, StatusArchived, nil, false, nil},
+		{StatusInProgress, StatusTodo, nil, false, nil},
+		{StatusInProgress, StatusDone, nil, false, &now},
+		{StatusInProgress, StatusArchived, nil, false, nil},
+		{StatusDone, StatusInProgress, &earlier, false, nil},
+		{StatusDone, StatusArchived, &earlier, false, &earlier},
+		{StatusDone, StatusTodo, &earlier, true, &earlier},
+		{StatusDone, StatusDone, &earlier, false, &earlier},
+		{StatusArchived, StatusTodo, &earlier, false, nil},
+		{StatusArchived, StatusArchived, &earlier, false, &earlier},
+		{StatusArchived, StatusInProgress, nil, true, nil},
+		{StatusArchived, StatusDone, &earlier, true, &earlier},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			task := Task{Status: tt.from, CompletedAt: tt.completedAt}
+			err := task.MoveTo(tt.to, now)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidTransition) {
+					t.Fatalf("got %v, want ErrInvalidTransition", err)
+				}
+				if task.Status != tt.from {
+					t.Errorf("status changed to %q on a rejected move", task.Status)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if task.Status != tt.to {
+					t.Errorf("status = %q, want %q", task.Status, tt.to)
+				}
+			}
+			switch {
+			case tt.wantCompleted == nil && task.CompletedAt != nil:
+				t.Errorf("completed_at = %v, want none", *task.CompletedAt)
+			case tt.wantCompleted != nil && task.CompletedAt == nil:
+				t.Errorf("completed_at not set, want %v", *tt.wantCompleted)
+			case tt.wantCompleted != nil && !task.CompletedAt.Equal(*tt.wantCompleted):
+				t.Errorf("completed_at = %v, want %v", *task.CompletedAt, *tt.wantCompleted)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Package reminders sends reminders for tasks that are about to come due.
+package reminders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+type Reminder struct {
+	TaskID   int       `json:"task_id"`
+	UserID   int       `json:"user_id"`
+	Email    string    `json:"email"`
+	Title    string    `json:"title"`
+	Priority string    `json:"priority"`
+	DueAt    time.Time `json:"due_at"`
+}
+
+// Notifier delivers a reminder to the task's owner.
+type Notifier interface {
+	Notify(ctx context.Context, r Reminder) error
+}
+
+// LogNotifier writes reminders to the log, for development.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(ctx context.Context, r Reminder) error {
+	log.Printf("reminder: task %d %q for %s is due at %s",
+		r.TaskID, r.Title, r.Email, r.DueAt.Format(time.RFC1123))
+	return nil
+}
+
+// WebhookNotifier posts each reminder as JSON to URL.
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{URL: url, Client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, r Reminder) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package reminders
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/falasefemi2/ask-tracker-api/db"
+)
+
+// Scheduler checks every Interval for open tasks due within Lead and sends
+// each one reminder through Notifier. A task that came due while the
+// server was down or a check ran long is still reminded if it is at most
+// Grace overdue; older ones are dropped, as a reminder that late is noise.
+type Scheduler struct {
+	Notifier Notifier
+	Lead     time.Duration
+	Grace    time.Duration
+	Interval time.Duration
+}
+
+// Run checks for reminders until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.Interval)
+	defer ticker.Stop()
+	for {
+		s.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) check(ctx context.Context) {
+	now := time.Now()
+	due, err := db.TasksToRemind(now.Add(-s.Grace), now.Add(s.Lead))
+	if err != nil {
+		log.Printf("reminders: %v", err)
+		return
+	}
+	for _, d := range due {
+		claimed, err := db.ClaimReminder(d.Task.ID)
+		if err != nil {
+			log.Printf("reminders: task %d: %v", d.Task.ID, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		err = s.Notifier.Notify(ctx, Reminder{
+			TaskID:   d.Task.ID,
+			UserID:   d.Task.UserID,
+			Email:    d.Email,
+			Title:    d.Task.Title,
+			Priority: d.Task.Priority,
+			DueAt:    *d.Task.DueAt,
+		})
+		if err != nil {
+			log.Printf("reminders: task %d: %v", d.Task.ID, err)
+			if err := db.ReleaseReminder(d.Task.ID); err != nil {
+				log.Printf("reminders: task %d: %v", d.Task.ID, err)
+			}
+		}
+	}
+}
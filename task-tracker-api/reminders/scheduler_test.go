@@ -0,0 +1,154 @@
+package reminders
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/falasefemi2/ask-tracker-api/db/dbtest"
+)
+
+// fakeTask answers the scheduler's queries for one open task, keeping its
+// reminded_at the way the database would.
+type fakeTask struct {
+	t        *testing.T
+	due      time.Time
+	reminded bool
+	claims   int
+	// claimedElsewhere makes another scheduler claim the task between
+	// this one listing it and claiming it.
+	claimedElsewhere bool
+	since, until     time.Time
+}
+
+func (f *fakeTask) answer(query string, args []driver.Value) (dbtest.Result, error) {
+	switch {
+	case strings.HasPrefix(query, "SELECT t.id"):
+		f.since, f.until = args[0].(time.Time), args[1].(time.Time)
+		res := dbtest.Result{Columns: []string{
+			"id", "user_id", "title", "description", "status", "priority", "due_at",
+			"time_zone", "completed_at", "created_at", "updated_at", "email",
+		}}
+		if !f.reminded && f.due.After(f.since) && !f.due.After(f.until) {
+			res.Rows = [][]driver.Value{{
+				int64(7), int64(42), "Write report", "", "todo", "high",
+				f.due.UTC().Format(time.DateTime), "UTC", nil, "2025-02-01 09:00:00", "2025-02-01 09:00:00",
+				"ada@example.com",
+			}}
+		}
+		if f.claimedElsewhere {
+			f.reminded = true
+		}
+		return res, nil
+	case strings.HasPrefix(query, "UPDATE tasks SET reminded_at = ? WHERE id = ? AND reminded_at IS NULL"):
+		if f.reminded {
+			return dbtest.Result{RowsAffected: 0}, nil
+		}
+		f.reminded = true
+		f.claims++
+		return dbtest.Result{RowsAffected: 1}, nil
+	case strings.HasPrefix(query, "UPDATE tasks SET reminded_at = NULL WHERE id = ?"):
+		f.reminded = false
+		return dbtest.Result{RowsAffected: 1}, nil
+	}
+	f.t.Fatalf("unexpected query %q", query)
+	return dbtest.Result{}, nil
+}
+
+type recordingNotifier struct {
+	sent []Reminder
+	err  error
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, r Reminder) error {
+	n.sent = append(n.sent, r)
+	return n.err
+}
+
+func TestSchedulerRemindsOnce(t *testing.T) {
+	due := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+	task := &fakeTask{t: t, due: due}
+	dbtest.Open(t, task.answer)
+
+	notifier := &recordingNotifier{}
+	s := &Scheduler{Notifier: notifier, Lead: 15 * time.Minute, Grace: time.Hour}
+	before := time.Now()
+	s.check(t.Context())
+	after := time.Now()
+	if task.since.Before(before.Add(-s.Grace)) || task.since.After(after.Add(-s.Grace)) ||
+		task.until.Before(before.Add(s.Lead)) || task.until.After(after.Add(s.Lead)) {
+		t.Errorf("window = %v to %v, want from Grace ago to Lead ahead", task.since, task.until)
+	}
+	s.check(t.Context())
+
+	if task.claims != 1 {
+		t.Errorf("claimed %d times, want once", task.claims)
+	}
+	if len(notifier.sent) != 1 {
+		t.Fatalf("sent %d reminders, want 1", len(notifier.sent))
+	}
+	want := Reminder{TaskID: 7, UserID: 42, Email: "ada@example.com", Title: "Write report", Priority: "high", DueAt: due}
+	if got := notifier.sent[0]; got.TaskID != want.TaskID || got.UserID != want.UserID || got.Email != want.Email ||
+		got.Title != want.Title || got.Priority != want.Priority || !got.DueAt.Equal(want.DueAt) {
+		t.Errorf("reminder = %+v, want %+v", got, want)
+	}
+}
+
+func TestSchedulerSkipsTaskClaimedElsewhere(t *testing.T) {
+	task := &fakeTask{t: t, due: time.Now().Add(5 * time.Minute), claimedElsewhere: true}
+	dbtest.Open(t, task.answer)
+
+	notifier := &recordingNotifier{}
+	s := &Scheduler{Notifier: notifier, Lead: 15 * time.Minute, Grace: time.Hour}
+	s.check(t.Context())
+
+	if len(notifier.sent) != 0 {
+		t.Errorf("sent %d reminders for a task another scheduler claimed", len(notifier.sent))
+	}
+}
+
+func TestSchedulerRetriesFailedWebhook(t *testing.T) {
+	task := &fakeTask{t: t, due: time.Now().Add(5 * time.Minute)}
+	dbtest.Open(t, task.answer)
+
+	var calls int
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer webhook.Close()
+
+	notifier := NewWebhookNotifier(webhook.URL)
+	s := &Scheduler{Notifier: notifier, Lead: 15 * time.Minute, Grace: time.Hour}
+
+	s.check(t.Context())
+	if task.reminded {
+		t.Fatal("a failed reminder is still claimed, so it would never be retried")
+	}
+	s.check(t.Context())
+	if calls != 2 || !task.reminded {
+		t.Fatalf("after a retry: %d webhook calls, reminded %v; want 2 and true", calls, task.reminded)
+	}
+	s.check(t.Context())
+	if calls != 2 {
+		t.Errorf("webhook called %d times, want no call once the reminder went out", calls)
+	}
+}
+
+func TestWebhookNotifierRejectsNon2xx(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer webhook.Close()
+
+	err := NewWebhookNotifier(webhook.URL).Notify(t.Context(), Reminder{TaskID: 7})
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Notify error = %v, want the 503 reported", err)
+	}
+}